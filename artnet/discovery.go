@@ -0,0 +1,96 @@
+package artnet
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// discoveryMutex serializes discoveries, as each one has to bind the Art-Net port.
+var discoveryMutex sync.Mutex
+
+// Discover broadcasts an ArtPoll on all IPv4 networks and collects the
+// ArtPollReply packets that arrive within the timeout.
+func Discover(timeout time.Duration) ([]Node, error) {
+	discoveryMutex.Lock()
+	defer discoveryMutex.Unlock()
+
+	// Nodes send their replies to the Art-Net port, so we have to listen on it
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: Port})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on Art-Net port %d: %w", Port, err)
+	}
+	defer conn.Close()
+
+	poll := BuildPoll()
+	sent := false
+	for _, addr := range broadcastAddresses() {
+		if _, err := conn.WriteToUDP(poll, &net.UDPAddr{IP: addr, Port: Port}); err == nil {
+			sent = true
+		}
+	}
+	if !sent {
+		return nil, fmt.Errorf("failed to send ArtPoll on any network")
+	}
+
+	nodes := make(map[string]Node)
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 1024)
+	for {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+		node, err := ParsePollReply(buf[:n])
+		if err != nil {
+			continue // Our own ArtPoll, ArtDmx from other controllers, ...
+		}
+		nodes[fmt.Sprintf("%s/%d", node.IP, node.BindIndex)] = *node
+	}
+
+	result := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, node)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].IP != result[j].IP {
+			return result[i].IP < result[j].IP
+		}
+		return result[i].BindIndex < result[j].BindIndex
+	})
+	return result, nil
+}
+
+// broadcastAddresses returns the limited broadcast address plus the directed
+// broadcast address of every IPv4 network the host is attached to.
+func broadcastAddresses() []net.IP {
+	addresses := []net.IP{net.IPv4bcast}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return addresses
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		ip := ipNet.IP.To4()
+		if ip == nil || len(ipNet.Mask) != net.IPv4len {
+			continue
+		}
+		broadcast := make(net.IP, net.IPv4len)
+		for i := range ip {
+			broadcast[i] = ip[i] | ^ipNet.Mask[i]
+		}
+		addresses = append(addresses, broadcast)
+	}
+	return addresses
+}
@@ -0,0 +1,160 @@
+package artnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	// Port is the UDP port used for all Art-Net traffic.
+	Port = 6454
+
+	// Art-Net 4 op codes (transmitted little endian).
+	OpPoll      = 0x2000
+	OpPollReply = 0x2100
+	OpDmx       = 0x5000
+	OpSync      = 0x5200
+
+	// ProtocolVersion is the Art-Net protocol revision implemented by this package.
+	ProtocolVersion = 14
+
+	// MaxPortAddress is the highest 15 bit port-address (net, sub-net and universe).
+	MaxPortAddress = 0x7fff
+
+	headerLen       = 12 // ID, OpCode and ProtVer
+	dmxHeaderLen    = 18
+	pollLen         = 14
+	syncLen         = 14
+	pollReplyMinLen = 207 // Up to and including the MAC address
+)
+
+// packetID is the fixed identifier at the start of every Art-Net packet.
+var packetID = []byte("Art-Net\x00")
+
+// writeHeader writes the packet ID, the op code and the protocol version.
+func writeHeader(packet []byte, opCode uint16) {
+	copy(packet[0:8], packetID)
+	binary.LittleEndian.PutUint16(packet[8:10], opCode)
+	binary.BigEndian.PutUint16(packet[10:12], ProtocolVersion)
+}
+
+// BuildDmx builds an ArtDmx packet for a port-address. A sequence of 0 disables
+// re-ordering on the receiver, otherwise it should count from 1 to 255.
+func BuildDmx(portAddress uint16, sequence byte, data []byte) ([]byte, error) {
+	if portAddress > MaxPortAddress {
+		return nil, fmt.Errorf("port-address %d out of range", portAddress)
+	}
+	length := len(data)
+	if length < 2 || length > 512 {
+		return nil, fmt.Errorf("invalid DMX data length %d, must be between 2 and 512", length)
+	}
+	if length%2 != 0 {
+		// The length has to be even, pad with a zero channel
+		length++
+	}
+
+	packet := make([]byte, dmxHeaderLen+length)
+	writeHeader(packet, OpDmx)
+	packet[12] = sequence
+	packet[13] = 0                        // Physical input port, informational only
+	packet[14] = byte(portAddress & 0xff) // SubUni: sub-net and universe
+	packet[15] = byte(portAddress >> 8)   // Net
+	binary.BigEndian.PutUint16(packet[16:18], uint16(length))
+	copy(packet[dmxHeaderLen:], data)
+	return packet, nil
+}
+
+// BuildPoll builds an ArtPoll packet asking all nodes to reply.
+func BuildPoll() []byte {
+	packet := make([]byte, pollLen)
+	writeHeader(packet, OpPoll)
+	packet[12] = 0x00 // Flags: only reply to this poll
+	packet[13] = 0x10 // DiagPriority: low, diagnostics are disabled anyway
+	return packet
+}
+
+// BuildSync builds an ArtSync packet, telling nodes to output the ArtDmx data
+// they received since the last sync.
+func BuildSync() []byte {
+	packet := make([]byte, syncLen)
+	writeHeader(packet, OpSync)
+	// Aux1 and Aux2 are transmitted as zero
+	return packet
+}
+
+// OpCode returns the op code of an Art-Net packet or an error if it isn't one.
+func OpCode(packet []byte) (uint16, error) {
+	if len(packet) < headerLen || !bytes.Equal(packet[0:8], packetID) {
+		return 0, fmt.Errorf("not an Art-Net packet")
+	}
+	return binary.LittleEndian.Uint16(packet[8:10]), nil
+}
+
+// Node describes an Art-Net node discovered through an ArtPollReply.
+type Node struct {
+	IP            string   `json:"ip"`
+	Port          int      `json:"port"`
+	ShortName     string   `json:"short_name"`
+	LongName      string   `json:"long_name"`
+	NodeReport    string   `json:"node_report"`
+	Firmware      uint16   `json:"firmware"`
+	OEM           uint16   `json:"oem"`
+	ESTA          uint16   `json:"esta"`
+	MAC           string   `json:"mac"`
+	BindIndex     int      `json:"bind_index"`
+	PortAddresses []uint16 `json:"port_addresses"` // Port-addresses of the node's output ports
+}
+
+// ParsePollReply decodes an ArtPollReply packet.
+func ParsePollReply(packet []byte) (*Node, error) {
+	opCode, err := OpCode(packet)
+	if err != nil {
+		return nil, err
+	}
+	if opCode != OpPollReply {
+		return nil, fmt.Errorf("unexpected op code 0x%04x", opCode)
+	}
+	if len(packet) < pollReplyMinLen {
+		return nil, fmt.Errorf("ArtPollReply too short (%d bytes)", len(packet))
+	}
+
+	node := &Node{
+		IP:         net.IP(packet[10:14]).String(),
+		Port:       int(binary.LittleEndian.Uint16(packet[14:16])),
+		Firmware:   binary.BigEndian.Uint16(packet[16:18]),
+		OEM:        binary.BigEndian.Uint16(packet[20:22]),
+		ESTA:       binary.LittleEndian.Uint16(packet[24:26]),
+		ShortName:  cString(packet[26:44]),
+		LongName:   cString(packet[44:108]),
+		NodeReport: cString(packet[108:172]),
+		MAC:        net.HardwareAddr(packet[201:207]).String(),
+	}
+	if len(packet) > 211 {
+		node.BindIndex = int(packet[211])
+	}
+
+	netSwitch := uint16(packet[18] & 0x7f)
+	subSwitch := uint16(packet[19] & 0x0f)
+	numPorts := int(binary.BigEndian.Uint16(packet[172:174]))
+	if numPorts > 4 {
+		numPorts = 4
+	}
+	for i := 0; i < numPorts; i++ {
+		if packet[174+i]&0x80 == 0 {
+			continue // Port can't output DMX
+		}
+		swOut := uint16(packet[190+i] & 0x0f)
+		node.PortAddresses = append(node.PortAddresses, netSwitch<<8|subSwitch<<4|swOut)
+	}
+	return node, nil
+}
+
+// cString converts a null terminated byte field into a string.
+func cString(field []byte) string {
+	if i := bytes.IndexByte(field, 0); i >= 0 {
+		field = field[:i]
+	}
+	return string(field)
+}
@@ -0,0 +1,167 @@
+package artnet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// header is the expected start of a packet with an op code, low byte first.
+func header(opLow, opHigh byte) []byte {
+	return []byte{'A', 'r', 't', '-', 'N', 'e', 't', 0, opLow, opHigh, 0, 14}
+}
+
+func TestBuildDmx(t *testing.T) {
+	tests := []struct {
+		name        string
+		portAddress uint16
+		sequence    byte
+		data        []byte
+		want        []byte // Bytes after the header
+		wantErr     bool
+	}{
+		{
+			name:        "even length",
+			portAddress: 0,
+			sequence:    1,
+			data:        []byte{10, 20},
+			want:        []byte{1, 0, 0x00, 0x00, 0, 2, 10, 20},
+		},
+		{
+			name:        "odd length padded",
+			portAddress: 0,
+			sequence:    255,
+			data:        []byte{10, 20, 30},
+			want:        []byte{255, 0, 0x00, 0x00, 0, 4, 10, 20, 30, 0},
+		},
+		{
+			name:        "sub-net and universe",
+			portAddress: 0x0023, // Sub-net 2, universe 3
+			data:        []byte{1, 2},
+			want:        []byte{0, 0, 0x23, 0x00, 0, 2, 1, 2},
+		},
+		{
+			name:        "net",
+			portAddress: 0x7f45, // Net 127, sub-net 4, universe 5
+			data:        []byte{1, 2},
+			want:        []byte{0, 0, 0x45, 0x7f, 0, 2, 1, 2},
+		},
+		{
+			name:        "full universe",
+			portAddress: 1,
+			data:        make([]byte, 512),
+			want:        append([]byte{0, 0, 0x01, 0x00, 0x02, 0x00}, make([]byte, 512)...),
+		},
+		{name: "more than 512 channels", data: make([]byte, 513), wantErr: true},
+		{name: "less than 2 channels", data: []byte{1}, wantErr: true},
+		{name: "port-address too high", portAddress: 0x8000, data: []byte{1, 2}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			packet, err := BuildDmx(test.portAddress, test.sequence, test.data)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got % x", packet)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := append(header(0x00, 0x50), test.want...)
+			if !bytes.Equal(packet, want) {
+				t.Errorf("got  % x\nwant % x", packet, want)
+			}
+		})
+	}
+}
+
+func TestBuildPollAndSync(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   []byte
+	}{
+		{name: "poll", packet: BuildPoll(), want: append(header(0x00, 0x20), 0x00, 0x10)},
+		{name: "sync", packet: BuildSync(), want: append(header(0x00, 0x52), 0x00, 0x00)},
+	}
+	for _, test := range tests {
+		if !bytes.Equal(test.packet, test.want) {
+			t.Errorf("%s: got % x, want % x", test.name, test.packet, test.want)
+		}
+	}
+}
+
+// pollReplyFixture returns an ArtPollReply of a two port node at 192.168.1.50.
+func pollReplyFixture() []byte {
+	packet := make([]byte, 239)
+	copy(packet, header(0x00, 0x21)[:10])          // ArtPollReply has no protocol version
+	copy(packet[10:14], []byte{192, 168, 1, 50})   // IP address
+	copy(packet[14:16], []byte{0x36, 0x19})        // Port 6454, low byte first
+	copy(packet[16:18], []byte{0x01, 0x02})        // Firmware version
+	packet[18], packet[19] = 0x81, 0x12            // Net 1 (high bit ignored), sub-net 2 (high nibble ignored)
+	copy(packet[20:22], []byte{0x04, 0x30})        // OEM code
+	copy(packet[24:26], []byte{0x50, 0x41})        // ESTA code "AP", low byte first
+	copy(packet[26:44], "Stage Left\x00garbage")   // Short name
+	copy(packet[44:108], "Stage Left Node")        // Long name
+	copy(packet[108:172], "#0001 [0042] Power On") // Node report
+	copy(packet[172:174], []byte{0x00, 0x02})      // Two ports
+	packet[174], packet[175] = 0x80, 0x40          // Port 1 outputs DMX, port 2 is an input
+	packet[190], packet[191] = 0x05, 0x06          // SwOut of the ports
+	copy(packet[201:207], []byte{0, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e})
+	packet[211] = 2 // Bind index
+	return packet
+}
+
+func TestParsePollReply(t *testing.T) {
+	node, err := ParsePollReply(pollReplyFixture())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Node{
+		IP:            "192.168.1.50",
+		Port:          6454,
+		ShortName:     "Stage Left",
+		LongName:      "Stage Left Node",
+		NodeReport:    "#0001 [0042] Power On",
+		Firmware:      0x0102,
+		OEM:           0x0430,
+		ESTA:          0x4150,
+		MAC:           "00:1a:2b:3c:4d:5e",
+		BindIndex:     2,
+		PortAddresses: []uint16{0x0125},
+	}
+	if !reflect.DeepEqual(node, want) {
+		t.Errorf("got  %+v\nwant %+v", node, want)
+	}
+
+	// Art-Net 3 replies end after the MAC address, without a bind index
+	node, err = ParsePollReply(pollReplyFixture()[:pollReplyMinLen])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.BindIndex != 0 || node.MAC != want.MAC {
+		t.Errorf("short reply: bind index %d, MAC %s", node.BindIndex, node.MAC)
+	}
+}
+
+func TestParsePollReplyInvalid(t *testing.T) {
+	wrongOpCode := pollReplyFixture()
+	wrongOpCode[9] = 0x50 // ArtDmx
+	notArtNet := pollReplyFixture()
+	notArtNet[0] = 'X'
+
+	tests := map[string][]byte{
+		"too short":     pollReplyFixture()[:pollReplyMinLen-1],
+		"header only":   header(0x00, 0x21)[:10],
+		"wrong op code": wrongOpCode,
+		"poll":          BuildPoll(),
+		"not Art-Net":   notArtNet,
+		"empty":         nil,
+	}
+	for name, packet := range tests {
+		if node, err := ParsePollReply(packet); err == nil {
+			t.Errorf("%s: expected an error, got %+v", name, node)
+		}
+	}
+}
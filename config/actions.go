@@ -0,0 +1,24 @@
+package config
+
+import "strings"
+
+// delayUnits are the units an action's delay can be given in.
+var delayUnits = []string{"seconds", "beats", "bars"}
+
+// quantizeModes are the beat positions an action can be quantized to.
+var quantizeModes = []string{"beat", "bar", "phrase"}
+
+// ValidateTiming checks the delay and quantization of an action at path.
+func (a *ActionConfig) ValidateTiming(path string) Issues {
+	var issues Issues
+	if a.Delay < 0 {
+		issues = append(issues, ErrorIssue(path+".delay", "must not be negative"))
+	}
+	if a.DelayUnit != "" && !contains(delayUnits, a.DelayUnit) {
+		issues = append(issues, ErrorIssue(path+".delay_unit", "unknown delay unit '%s', must be one of %s", a.DelayUnit, strings.Join(delayUnits, ", ")))
+	}
+	if a.Quantize != "" && !contains(quantizeModes, a.Quantize) {
+		issues = append(issues, ErrorIssue(path+".quantize", "unknown quantization '%s', must be one of %s", a.Quantize, strings.Join(quantizeModes, ", ")))
+	}
+	return issues
+}
@@ -10,10 +10,13 @@ import (
 
 // ActionConfig represents a single action to be performed by an event.
 type ActionConfig struct {
-	Type     string                 	`json:"type"`
-	ChainID  string                 	`json:"chain_id,omitempty"`
-	EffectID string                 	`json:"effect_id,omitempty"`
-	Params   map[string]interface{} 	`json:"params,omitempty"`
+	Type      string                 	`json:"type"`
+	ChainID   string                 	`json:"chain_id,omitempty"`
+	EffectID  string                 	`json:"effect_id,omitempty"`
+	Params    map[string]interface{} 	`json:"params,omitempty"`
+	Delay     float64                	`json:"delay,omitempty"`      // Time after the event (or the last wait) the action executes
+	DelayUnit string                 	`json:"delay_unit,omitempty"` // Unit of Delay: "seconds" (default), "beats" or "bars"
+	Quantize  string                 	`json:"quantize,omitempty"`   // Executes on the next "beat", "bar" or "phrase", after the delay
 }
 
 // MidiTriggerConfig represents a single MIDI message that triggers an event.
@@ -26,11 +29,88 @@ type MidiTriggerConfig struct {
 
 // Config represents the overall application configuration.
 type Config struct {
+	SchemaURL    string                   	`json:"$schema,omitempty"` // JSON Schema editors validate the file against, see -schema
 	Globals      GlobalsConfig            	`json:"globals"`
 	Chains       []ChainConfig            	`json:"chains"`
+	SharedOutputs []SharedOutputConfig     	`json:"shared_outputs,omitempty"` // Outputs that several chains merge onto
 	Actions      map[string][]ActionConfig 	`json:"actions"` // Renamed from Events
 	Triggers     []MidiTriggerConfig      	`json:"triggers"` // Renamed from MidiTriggers
 	MidiPortName string                 	`json:"midi_port_name,omitempty"`
+	MidiMaster   *MidiMasterConfig        	`json:"midi_master,omitempty"`
+	MidiMappings []MidiMappingConfig      	`json:"midi_mappings,omitempty"` // Continuous MIDI controls mapped onto a target
+	OSCPort      int                      	`json:"osc_port,omitempty"` // UDP port OSC messages for osc modulators are received on, 0 to not listen
+	Modulators   map[string]ModulatorConfig 	`json:"modulators,omitempty"` // Named modulators effect args can be bound to
+	Scenes       map[string]SceneConfig     	`json:"scenes,omitempty"` // Saved looks, see save_scene and recall_scene
+	CueLists     map[string]CueListConfig   	`json:"cue_lists,omitempty"` // Sequences of cues played with cue_go and cue_back
+}
+
+// CueListConfig is an ordered list of cues. GO fires the next cue, cues can also
+// advance on their own after a time or a number of beats or bars.
+type CueListConfig struct {
+	Cues []CueConfig 	`json:"cues"`
+	Loop bool        	`json:"loop,omitempty"` // GO after the last cue starts over with the first
+}
+
+// CueConfig is a step of a cue list. Firing it triggers its event and executes its actions.
+type CueConfig struct {
+	Name     string         	`json:"name,omitempty"`     // Name to jump to the cue with
+	Event    string         	`json:"event,omitempty"`    // Event triggered when the cue fires
+	Actions  []ActionConfig 	`json:"actions,omitempty"`  // Actions executed when the cue fires, after the event
+	Advance  string         	`json:"advance,omitempty"`  // When the next cue fires: "manual" (default, on GO), "time", "beats" or "bars"
+	Duration float64        	`json:"duration,omitempty"` // Seconds, beats or bars after this cue fired, unless advance is manual
+}
+
+// SceneConfig is a saved look: the effects of the chains and the globals. Recalling
+// it replaces the effects of the chains it lists, other chains keep theirs.
+type SceneConfig struct {
+	Globals *GlobalsConfig            	`json:"globals,omitempty"` // Globals to restore, kept as they are if not set
+	Chains  map[string][]EffectConfig 	`json:"chains"`            // Effects by chain ID
+}
+
+// ModulatorConfig describes a modulator, a value from 0 to 1 that changes over time.
+// Effect args bound to it follow it on every tick.
+type ModulatorConfig struct {
+	Type        string   	`json:"type"`                   // "lfo", "envelope", "midi" or "osc"
+	Shape       string   	`json:"shape,omitempty"`        // LFO waveform: "sine" (default), "triangle", "saw", "square" or "random"
+	Beats       float64  	`json:"beats,omitempty"`        // LFO period in beats, defaults to 1
+	Phase       float64  	`json:"phase,omitempty"`        // LFO offset as a fraction of the period (0-1)
+	Event       string   	`json:"event,omitempty"`        // Event that triggers the envelope
+	Attack      float64  	`json:"attack,omitempty"`       // Envelope attack time in seconds
+	Decay       float64  	`json:"decay,omitempty"`        // Envelope decay time in seconds
+	Sustain     *float64 	`json:"sustain,omitempty"`      // Envelope sustain level (0-1), defaults to 1
+	Hold        float64  	`json:"hold,omitempty"`         // Envelope time at the sustain level in seconds
+	Release     float64  	`json:"release,omitempty"`      // Envelope release time in seconds
+	MessageType string   	`json:"message_type,omitempty"` // MIDI input: "cc", "note_on" (velocity) or "pitch_bend"
+	Number      int      	`json:"number,omitempty"`       // MIDI input: CC or note number, unused for pitch_bend
+	Address     string   	`json:"address,omitempty"`      // OSC input: address of the messages, e.g. "/fader1"
+}
+
+// ModulatorBinding binds an effect arg to a modulator. It is given in place of the
+// arg's value, e.g. "percentage": {"modulator": "wobble", "min": 0.2, "max": 0.8}.
+type ModulatorBinding struct {
+	Modulator string   	`json:"modulator"`
+	Min       *float64 	`json:"min,omitempty"` // Arg value at 0, defaults to the parameter's minimum or 0
+	Max       *float64 	`json:"max,omitempty"` // Arg value at 1, defaults to the parameter's maximum or 1
+}
+
+// MidiMasterConfig binds MIDI controls to the grand master.
+type MidiMasterConfig struct {
+	IntensityCC  *int 	`json:"intensity_cc,omitempty"`  // CC number controlling the master intensity
+	BlackoutNote *int 	`json:"blackout_note,omitempty"` // Note number toggling blackout on note on
+}
+
+// MidiMappingConfig maps a continuous MIDI control onto a target value.
+type MidiMappingConfig struct {
+	MessageType string   	`json:"message_type"`        // "cc", "note_on" (velocity) or "pitch_bend"
+	Number      int      	`json:"number"`              // CC or note number, unused for pitch_bend
+	Target      string   	`json:"target"`              // "effect_param", "bpm", "intensity", "color1_hue" or "color2_hue"
+	ChainID     string   	`json:"chain_id,omitempty"`  // Chain of the effect, for effect_param
+	EffectID    string   	`json:"effect_id,omitempty"` // ID of the effect, for effect_param
+	Param       string   	`json:"param,omitempty"`     // Name of the effect arg, for effect_param
+	Min         *float64 	`json:"min,omitempty"`       // Value at the lowest control position, defaults depend on the target
+	Max         *float64 	`json:"max,omitempty"`       // Value at the highest control position, defaults depend on the target
+	Curve       string   	`json:"curve,omitempty"`     // "linear" (default), "exponential" or "logarithmic"
+	Pickup      bool     	`json:"pickup,omitempty"`    // Soft takeover, the control only takes over once it reaches the current value
 }
 
 // ChainConfig represents the configuration for a single chain.
@@ -41,6 +121,17 @@ type ChainConfig struct {
 	NumLamps int            	`json:"numLamps"`
 	Effects  []EffectConfig 	`json:"effects"`
 	Output   OutputConfig   	`json:"output"`
+	OutputID string         	`json:"output_id,omitempty"` // ID of a shared output to use instead of Output
+	Segments map[string]LampRange 	`json:"segments,omitempty"` // Named lamp ranges effects can target, e.g. "truss_left"
+	Enabled  *bool                	`json:"enabled,omitempty"`  // A disabled chain sends black, enabled if not set
+}
+
+// SharedOutputConfig represents an output that several chains send to. The frames
+// of all chains using it are merged per channel according to MergeMode.
+type SharedOutputConfig struct {
+	ID        string       	`json:"id"`
+	MergeMode string       	`json:"merge_mode"` // "htp" (default), "ltp" or "priority"
+	Output    OutputConfig 	`json:"output"`
 }
 
 // EffectConfig represents the configuration for an effect.
@@ -50,6 +141,19 @@ type EffectConfig struct {
 	Args    map[string]interface{} 	`json:"args"`
 	Enabled *bool                  	`json:"enabled,omitempty"`
 	Group   string                 	`json:"group,omitempty"`
+	Lamps   *LampRange             	`json:"lamps,omitempty"` // Lamps of the chain the effect works on, all if not set
+	Blend   string                 	`json:"blend,omitempty"`   // How the effect's output is combined with the frame, "normal" by default
+	Opacity *float64               	`json:"opacity,omitempty"` // Strength of the effect's output (0-1), 1 by default
+}
+
+// LampRange selects lamps of a chain. With a segment, the other fields select
+// within the segment's lamps.
+type LampRange struct {
+	Segment  string 	`json:"segment,omitempty"`  // Name of a segment of the chain, only for effects
+	Start    int    	`json:"start,omitempty"`    // Index of the first lamp
+	End      int    	`json:"end,omitempty"`      // Index after the last lamp, 0 for all remaining lamps
+	Every    int    	`json:"every,omitempty"`    // Only every Nth lamp, defaults to 1
+	Reversed bool   	`json:"reversed,omitempty"` // Present the lamps to the effect in reverse order
 }
 
 // UnmarshalJSON for EffectConfig to default Enabled to true if not present
@@ -77,6 +181,8 @@ type OutputConfig struct {
 	ChannelMapping     string                 	`json:"channelMapping"`
 	NumChannelsPerLamp int                    	`json:"numChannelsPerLamp"`
 	Govee              GoveeOutputConfig      	`json:"govee,omitempty"`
+	ArtNet             *ArtNetOutputConfig    	`json:"artnet,omitempty"`
+	SACN               *SACNOutputConfig      	`json:"sacn,omitempty"`
 }
 
 // GoveeDeviceConfig represents a single Govee device.
@@ -91,13 +197,46 @@ type GoveeOutputConfig struct {
 	Devices []GoveeDeviceConfig 	`json:"devices"`
 }
 
+// SACNOutputConfig represents the configuration for sACN (E1.31) output.
+type SACNOutputConfig struct {
+	Universe     int      	`json:"universe"`                // First universe (1-63999); lamps spill into the following universes
+	Priority     *int     	`json:"priority,omitempty"`      // Source priority (0-200), defaults to 100
+	SourceName   string   	`json:"source_name,omitempty"`   // Human readable source name shown by receivers
+	CID          string   	`json:"cid,omitempty"`           // Component identifier (UUID); a random one is generated if empty
+	SyncUniverse int      	`json:"sync_universe,omitempty"` // Universe for synchronization packets, 0 disables sync
+	Targets      []string 	`json:"targets,omitempty"`       // Unicast targets ("ip" or "ip:port"); multicast is used if empty
+	StartAddress int      	`json:"start_address,omitempty"` // DMX address (1-512) of the first lamp, defaults to 1
+	NoSplit      bool     	`json:"no_split,omitempty"`      // Never split a lamp across two universes
+}
+
+// ArtNetOutputConfig represents the configuration for Art-Net output.
+type ArtNetOutputConfig struct {
+	Net          int  	`json:"net"`                     // Art-Net net (0-127)
+	Subnet       int  	`json:"subnet"`                  // Art-Net sub-net (0-15)
+	Universe     int  	`json:"universe"`                // First universe within the sub-net (0-15); lamps spill into the following universes
+	StartAddress int  	`json:"start_address,omitempty"` // DMX address (1-512) of the first lamp, defaults to 1
+	NoSplit      bool 	`json:"no_split,omitempty"`      // Never split a lamp across two universes
+	DisableSync  bool 	`json:"disable_sync,omitempty"`  // Don't send an ArtSync after every frame
+}
+
 // GlobalsConfig represents the global parameters configuration.
 type GlobalsConfig struct {
-	BPM    float64 	`json:"bpm"`
-	Color1 string  	`json:"color1"`
-	Color2 string  	`json:"color2"`
+	BPM           float64 	`json:"bpm"`
+	Color1        string  	`json:"color1"`
+	Color2        string  	`json:"color2"`
+	Intensity     *int    	`json:"intensity,omitempty"`       // Grand master (0-255)
+	BeatsPerBar   int     	`json:"beats_per_bar,omitempty"`   // Time signature, defaults to 4
+	BarsPerPhrase int     	`json:"bars_per_phrase,omitempty"` // Phrase length in bars, defaults to 16
+	ClockSource   string  	`json:"clock_source,omitempty"`    // ClockSourceInternal (default) or ClockSourceMidi
+	Blackout      bool    	`json:"-"`                         // Runtime only, a restart never starts dark
 }
 
+// Clock sources that can drive the beat.
+const (
+	ClockSourceInternal = "internal" // The beat runs at the configured BPM
+	ClockSourceMidi     = "midi"     // The beat follows MIDI clock on midi_port_name
+)
+
 // --- Config Manipulation Functions ---
 
 func (c *Config) findChain(chainID string) (*ChainConfig, error) {
@@ -109,6 +248,16 @@ func (c *Config) findChain(chainID string) (*ChainConfig, error) {
 	return nil, fmt.Errorf("chain with id '%s' not found", chainID)
 }
 
+// FindSharedOutput returns the shared output with the given ID.
+func (c *Config) FindSharedOutput(outputID string) (*SharedOutputConfig, error) {
+	for i := range c.SharedOutputs {
+		if c.SharedOutputs[i].ID == outputID {
+			return &c.SharedOutputs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("shared output with id '%s' not found", outputID)
+}
+
 // AddEffectToChain adds a new effect to a chain configuration.
 func (c *Config) AddEffectToChain(chainID string, effect EffectConfig) error {
 	chain, err := c.findChain(chainID)
@@ -177,6 +326,85 @@ func (c *Config) ToggleEffectInChain(chainID, effectID string, enabled bool) err
 	return nil
 }
 
+// MoveEffectInChain moves an effect to a position in the effect list of a chain
+// configuration. Positions past the end move it to the end.
+func (c *Config) MoveEffectInChain(chainID, effectID string, position int) error {
+	chain, err := c.findChain(chainID)
+	if err != nil {
+		return err
+	}
+	from := -1
+	for i := range chain.Effects {
+		if chain.Effects[i].ID == effectID {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return fmt.Errorf("effect with id '%s' not found in chain '%s'", effectID, chainID)
+	}
+	if position < 0 {
+		return fmt.Errorf("invalid position %d, must not be negative", position)
+	}
+	if position >= len(chain.Effects) {
+		position = len(chain.Effects) - 1
+	}
+	effect := chain.Effects[from]
+	chain.Effects = append(chain.Effects[:from], chain.Effects[from+1:]...)
+	chain.Effects = append(chain.Effects[:position], append([]EffectConfig{effect}, chain.Effects[position:]...)...)
+	return nil
+}
+
+// SetChainEnabled enables or disables a chain configuration.
+func (c *Config) SetChainEnabled(chainID string, enabled bool) error {
+	chain, err := c.findChain(chainID)
+	if err != nil {
+		return err
+	}
+	chain.Enabled = &enabled
+	return nil
+}
+
+// SetChainTickRate sets the tick rate of a chain configuration.
+func (c *Config) SetChainTickRate(chainID string, tickRate int) error {
+	if tickRate <= 0 {
+		return fmt.Errorf("invalid tick rate %d, must be greater than 0", tickRate)
+	}
+	chain, err := c.findChain(chainID)
+	if err != nil {
+		return err
+	}
+	chain.TickRate = tickRate
+	return nil
+}
+
+// FindEffect returns the effect with the given ID in a chain configuration.
+func (c *Config) FindEffect(chainID, effectID string) (*EffectConfig, error) {
+	chain, err := c.findChain(chainID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range chain.Effects {
+		if chain.Effects[i].ID == effectID {
+			return &chain.Effects[i], nil
+		}
+	}
+	return nil, fmt.Errorf("effect with id '%s' not found in chain '%s'", effectID, chainID)
+}
+
+// SetEffectArg sets a single arg of an effect in a chain configuration.
+func (c *Config) SetEffectArg(chainID, effectID, key string, value interface{}) error {
+	effect, err := c.FindEffect(chainID, effectID)
+	if err != nil {
+		return err
+	}
+	if effect.Args == nil {
+		effect.Args = make(map[string]interface{})
+	}
+	effect.Args[key] = value
+	return nil
+}
+
 // SetGlobal sets a global parameter.
 func (c *Config) SetGlobal(key string, value interface{}) error {
 	switch key {
@@ -198,6 +426,40 @@ func (c *Config) SetGlobal(key string, value interface{}) error {
 		} else {
 			return fmt.Errorf("invalid type for color2: expected string, got %T", value)
 		}
+	case "intensity":
+		if intensity, ok := value.(float64); ok {
+			if intensity < 0 || intensity > 255 {
+				return fmt.Errorf("invalid value for intensity: %v, must be between 0 and 255", intensity)
+			}
+			intVal := int(intensity)
+			c.Globals.Intensity = &intVal
+		} else {
+			return fmt.Errorf("invalid type for intensity: expected float64, got %T", value)
+		}
+	case "beats_per_bar":
+		if beats, ok := value.(float64); ok && beats >= 1 {
+			c.Globals.BeatsPerBar = int(beats)
+		} else {
+			return fmt.Errorf("invalid value for beats_per_bar: expected a number >= 1, got %v", value)
+		}
+	case "bars_per_phrase":
+		if bars, ok := value.(float64); ok && bars >= 1 {
+			c.Globals.BarsPerPhrase = int(bars)
+		} else {
+			return fmt.Errorf("invalid value for bars_per_phrase: expected a number >= 1, got %v", value)
+		}
+	case "clock_source":
+		if source, ok := value.(string); ok && (source == ClockSourceInternal || source == ClockSourceMidi) {
+			c.Globals.ClockSource = source
+		} else {
+			return fmt.Errorf("invalid value for clock_source: expected \"%s\" or \"%s\", got %v", ClockSourceInternal, ClockSourceMidi, value)
+		}
+	case "blackout":
+		if blackout, ok := value.(bool); ok {
+			c.Globals.Blackout = blackout
+		} else {
+			return fmt.Errorf("invalid type for blackout: expected bool, got %T", value)
+		}
 	default:
 		return fmt.Errorf("unknown global parameter: %s", key)
 	}
@@ -219,9 +481,35 @@ func LoadConfig(filePath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	cfg, configModified, err := parseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save config if any changes were made (either by mergeConfigs or effect arg augmentation)
+	if configModified {
+		log.Println("Updating config file with missing default values or augmented effect arguments.")
+		if err := SaveConfig(cfg, filePath); err != nil {
+			log.Printf("Error saving updated config file: %v\n", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ParseConfig unmarshals a JSON configuration and fills in default values like LoadConfig,
+// without reading or writing any file.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg, _, err := parseConfig(data)
+	return cfg, err
+}
+
+// parseConfig unmarshals a JSON configuration and fills in default values.
+// It returns true if any defaults were added.
+func parseConfig(data []byte) (*Config, bool, error) {
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config JSON: %w", err)
+		return nil, false, fmt.Errorf("failed to unmarshal config JSON: %w", err)
 	}
 
 	// Create a default config to merge missing values from
@@ -254,22 +542,23 @@ func LoadConfig(filePath string) (*Config, error) {
 		}
 	}
 
-	// Save config if any changes were made (either by mergeConfigs or effect arg augmentation)
-	if configModified {
-		log.Println("Updating config file with missing default values or augmented effect arguments.")
-		if err := SaveConfig(&cfg, filePath); err != nil {
-			log.Printf("Error saving updated config file: %v\n", err)
-		}
-	}
+	return &cfg, configModified, nil
+}
 
-	return &cfg, nil
+// MarshalConfig returns the JSON a Config is saved as.
+func MarshalConfig(cfg *Config) ([]byte, error) {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
 }
 
 // SaveConfig marshals the Config struct to JSON and writes it to the specified file path.
 func SaveConfig(cfg *Config, filePath string) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	data, err := MarshalConfig(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
 
 	if err := os.WriteFile(filePath, data, 0644); err != nil {
@@ -297,6 +586,18 @@ func mergeConfigs(loaded *Config, defaults *Config) bool {
 		loaded.Globals.Color2 = defaults.Globals.Color2
 		changed = true
 	}
+	if loaded.Globals.Intensity == nil {
+		loaded.Globals.Intensity = defaults.Globals.Intensity
+		changed = true
+	}
+	if loaded.Globals.BeatsPerBar == 0 {
+		loaded.Globals.BeatsPerBar = defaults.Globals.BeatsPerBar
+		changed = true
+	}
+	if loaded.Globals.BarsPerPhrase == 0 {
+		loaded.Globals.BarsPerPhrase = defaults.Globals.BarsPerPhrase
+		changed = true
+	}
 
 	// Ensure Chains, Actions, and Triggers are initialized if they are nil after unmarshaling
 	if loaded.Chains == nil {
@@ -323,11 +624,15 @@ func mergeConfigs(loaded *Config, defaults *Config) bool {
 
 // CreateDefaultConfig creates a default Config struct.
 func CreateDefaultConfig() Config {
+	intensity := 255
 	return Config{
 		Globals: GlobalsConfig{
-			BPM:    174,
-			Color1: "FFA000",
-			Color2: "000000",
+			BPM:           174,
+			Color1:        "FFA000",
+			Color2:        "000000",
+			Intensity:     &intensity,
+			BeatsPerBar:   4,
+			BarsPerPhrase: 16,
 		},
 		Chains:       []ChainConfig{},
 		Actions:      make(map[string][]ActionConfig),
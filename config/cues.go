@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// cueAdvances are the ways a cue can advance to the next one.
+var cueAdvances = []string{"manual", "time", "beats", "bars"}
+
+// FindCue returns the index of the cue with the given name in a cue list.
+func (l *CueListConfig) FindCue(name string) (int, error) {
+	for i, cue := range l.Cues {
+		if cue.Name != "" && cue.Name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("cue '%s' not found", name)
+}
+
+func (c *Config) validateCueLists() Issues {
+	var issues Issues
+	names := make([]string, 0, len(c.CueLists))
+	for name := range c.CueLists {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Stable order of the issues
+	for _, name := range names {
+		list := c.CueLists[name]
+		path := "cue_lists." + name
+		if len(list.Cues) == 0 {
+			issues = append(issues, WarningIssue(path+".cues", "cue list has no cues"))
+		}
+		cueNames := make(map[string]bool)
+		for i, cue := range list.Cues {
+			cuePath := fmt.Sprintf("%s.cues[%d]", path, i)
+			if cue.Name != "" && cueNames[cue.Name] {
+				issues = append(issues, ErrorIssue(cuePath+".name", "duplicate cue name '%s'", cue.Name))
+			}
+			cueNames[cue.Name] = true
+			if cue.Event != "" {
+				if _, ok := c.Actions[cue.Event]; !ok {
+					issues = append(issues, ErrorIssue(cuePath+".event", "unknown event '%s'", cue.Event))
+				}
+			} else if len(cue.Actions) == 0 {
+				issues = append(issues, WarningIssue(cuePath, "cue has no event and no actions"))
+			}
+			switch {
+			case cue.Advance != "" && !contains(cueAdvances, cue.Advance):
+				issues = append(issues, ErrorIssue(cuePath+".advance", "unknown advance '%s', must be one of %s", cue.Advance, strings.Join(cueAdvances, ", ")))
+			case cue.Advance != "" && cue.Advance != "manual" && cue.Duration <= 0:
+				issues = append(issues, ErrorIssue(cuePath+".duration", "must be greater than 0 for advance '%s'", cue.Advance))
+			case (cue.Advance == "" || cue.Advance == "manual") && cue.Duration != 0:
+				issues = append(issues, WarningIssue(cuePath+".duration", "ignored, the cue only advances on GO"))
+			}
+		}
+	}
+	return issues
+}
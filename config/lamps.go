@@ -0,0 +1,55 @@
+package config
+
+import "fmt"
+
+// Indices returns the indices of the lamps a range selects from a chain of numLamps
+// lamps, in the order the effect sees them. segments are the chain's segments.
+func (r *LampRange) Indices(numLamps int, segments map[string]LampRange) ([]int, error) {
+	lamps := make([]int, numLamps)
+	for i := range lamps {
+		lamps[i] = i
+	}
+	if r.Segment != "" {
+		segment, ok := segments[r.Segment]
+		if !ok {
+			return nil, fmt.Errorf("unknown segment '%s'", r.Segment)
+		}
+		if segment.Segment != "" {
+			return nil, fmt.Errorf("segment '%s' refers to another segment", r.Segment)
+		}
+		var err error
+		if lamps, err = segment.selectFrom(lamps); err != nil {
+			return nil, fmt.Errorf("segment '%s': %w", r.Segment, err)
+		}
+	}
+	return r.selectFrom(lamps)
+}
+
+// selectFrom applies start, end, every and reversed to a list of lamp indices.
+func (r *LampRange) selectFrom(lamps []int) ([]int, error) {
+	end := r.End
+	if end == 0 {
+		end = len(lamps)
+	}
+	if r.Start < 0 || end > len(lamps) || r.Start >= end {
+		return nil, fmt.Errorf("lamps %d to %d are outside of the %d lamps available", r.Start, end, len(lamps))
+	}
+	every := r.Every
+	if every == 0 {
+		every = 1
+	}
+	if every < 0 {
+		return nil, fmt.Errorf("every must be greater than 0")
+	}
+
+	selected := make([]int, 0, (end-r.Start+every-1)/every)
+	for i := r.Start; i < end; i += every {
+		selected = append(selected, lamps[i])
+	}
+	if r.Reversed {
+		for i, j := 0, len(selected)-1; i < j; i, j = i+1, j-1 {
+			selected[i], selected[j] = selected[j], selected[i]
+		}
+	}
+	return selected, nil
+}
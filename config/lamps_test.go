@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLampRangeIndices(t *testing.T) {
+	segments := map[string]LampRange{
+		"left":     {Start: 0, End: 5},
+		"right":    {Start: 5, Reversed: true},
+		"odd":      {Start: 1, Every: 2},
+		"nested":   {Segment: "left"},
+		"tooLarge": {Start: 8, End: 12},
+	}
+	tests := []struct {
+		name    string
+		lamps   LampRange
+		want    []int
+		wantErr bool
+	}{
+		{name: "all lamps", lamps: LampRange{}, want: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{name: "start and end", lamps: LampRange{Start: 2, End: 5}, want: []int{2, 3, 4}},
+		{name: "end 0 is the last lamp", lamps: LampRange{Start: 7}, want: []int{7, 8, 9}},
+		{name: "single lamp", lamps: LampRange{Start: 9, End: 10}, want: []int{9}},
+		{name: "every", lamps: LampRange{Start: 1, End: 8, Every: 3}, want: []int{1, 4, 7}},
+		{name: "reversed", lamps: LampRange{Start: 2, End: 5, Reversed: true}, want: []int{4, 3, 2}},
+		{name: "every reversed", lamps: LampRange{Every: 4, Reversed: true}, want: []int{8, 4, 0}},
+		{name: "segment", lamps: LampRange{Segment: "left"}, want: []int{0, 1, 2, 3, 4}},
+		{name: "within a segment", lamps: LampRange{Segment: "left", Start: 1, End: 3}, want: []int{1, 2}},
+		{name: "reversed segment", lamps: LampRange{Segment: "right"}, want: []int{9, 8, 7, 6, 5}},
+		{name: "within a reversed segment", lamps: LampRange{Segment: "right", Start: 1, End: 3}, want: []int{8, 7}},
+		{name: "reversed within a reversed segment", lamps: LampRange{Segment: "right", Reversed: true}, want: []int{5, 6, 7, 8, 9}},
+		{name: "every within a segment", lamps: LampRange{Segment: "odd", Every: 2}, want: []int{1, 5, 9}},
+		{name: "unknown segment", lamps: LampRange{Segment: "center"}, wantErr: true},
+		{name: "segment of a segment", lamps: LampRange{Segment: "nested"}, wantErr: true},
+		{name: "segment outside the chain", lamps: LampRange{Segment: "tooLarge"}, wantErr: true},
+		{name: "end past the chain", lamps: LampRange{End: 11}, wantErr: true},
+		{name: "end past the segment", lamps: LampRange{Segment: "left", End: 6}, wantErr: true},
+		{name: "empty range", lamps: LampRange{Start: 4, End: 4}, wantErr: true},
+		{name: "start past the chain", lamps: LampRange{Start: 10}, wantErr: true},
+		{name: "negative start", lamps: LampRange{Start: -1}, wantErr: true},
+		{name: "negative every", lamps: LampRange{Every: -2}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.lamps.Indices(10, segments)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import "godmx/effects"
+
+// modulatorTypes are the types of modulators.
+var modulatorTypes = []string{"lfo", "envelope", "midi", "osc"}
+
+// lfoShapes are the waveforms of LFO modulators.
+var lfoShapes = []string{"sine", "triangle", "saw", "square", "random"}
+
+// AsModulatorBinding returns the binding if an arg value binds the arg to a modulator.
+func AsModulatorBinding(value interface{}) (ModulatorBinding, bool) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return ModulatorBinding{}, false
+	}
+	name, ok := object["modulator"].(string)
+	if !ok {
+		return ModulatorBinding{}, false
+	}
+	binding := ModulatorBinding{Modulator: name}
+	if min, ok := effects.ToFloat(object["min"]); ok {
+		binding.Min = &min
+	}
+	if max, ok := effects.ToFloat(object["max"]); ok {
+		binding.Max = &max
+	}
+	return binding, true
+}
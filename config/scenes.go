@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// SaveScene stores the effects of all chains and the given globals as a scene,
+// replacing a scene with the same name.
+func (c *Config) SaveScene(name string, globals GlobalsConfig) error {
+	scene := SceneConfig{Globals: &globals, Chains: make(map[string][]EffectConfig, len(c.Chains))}
+	for _, chain := range c.Chains {
+		var effects []EffectConfig
+		if err := deepCopy(chain.Effects, &effects); err != nil {
+			return fmt.Errorf("failed to copy the effects of chain '%s': %w", chain.ID, err)
+		}
+		scene.Chains[chain.ID] = effects
+	}
+	if c.Scenes == nil {
+		c.Scenes = make(map[string]SceneConfig)
+	}
+	c.Scenes[name] = scene
+	return nil
+}
+
+// WithScene returns a copy of the configuration with the effects and globals of a
+// scene. Chains the scene has no effects for, or doesn't know, are left as they are.
+func (c *Config) WithScene(name string) (*Config, error) {
+	scene, ok := c.Scenes[name]
+	if !ok {
+		return nil, fmt.Errorf("scene '%s' not found", name)
+	}
+	recalled := &Config{}
+	if err := deepCopy(c, recalled); err != nil {
+		return nil, fmt.Errorf("failed to copy the config: %w", err)
+	}
+	if scene.Globals != nil {
+		recalled.Globals = *scene.Globals
+	}
+	recalled.Globals.Blackout = c.Globals.Blackout // Runtime only, a scene never changes it
+	for chainID, effects := range scene.Chains {
+		chain, err := recalled.findChain(chainID)
+		if err != nil {
+			continue
+		}
+		if err := deepCopy(effects, &chain.Effects); err != nil {
+			return nil, fmt.Errorf("failed to copy the effects of chain '%s': %w", chainID, err)
+		}
+	}
+	return recalled, nil
+}
+
+// deepCopy copies src into dst through JSON, so nothing is shared between them.
+func deepCopy(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+func (c *Config) validateScenes() Issues {
+	var issues Issues
+	names := make([]string, 0, len(c.Scenes))
+	for name := range c.Scenes {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Stable order of the issues
+	for _, name := range names {
+		scene := c.Scenes[name]
+		path := "scenes." + name
+		if scene.Globals != nil {
+			issues = append(issues, validateGlobals(scene.Globals, path+".globals")...)
+		}
+
+		chainIDs := make([]string, 0, len(scene.Chains))
+		for chainID := range scene.Chains {
+			chainIDs = append(chainIDs, chainID)
+		}
+		sort.Strings(chainIDs)
+		for _, chainID := range chainIDs {
+			chainPath := path + ".chains." + chainID
+			chain, err := c.findChain(chainID)
+			if err != nil {
+				issues = append(issues, WarningIssue(chainPath, "unknown chain '%s', ignored", chainID))
+				continue
+			}
+			issues = append(issues, chain.validateEffects(scene.Chains[chainID], chainPath)...)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"godmx/effects"
+	"godmx/types"
+)
+
+// Schema is a JSON Schema (draft-07) document or subschema.
+type Schema map[string]interface{}
+
+// schemaFields refines the schemas derived from the config structs, keyed by
+// "<struct name>.<json name>".
+var schemaFields = map[string]Schema{
+	"Config.osc_port":                  {"minimum": 0, "maximum": 65535},
+	"GlobalsConfig.bpm":                {"exclusiveMinimum": 0},
+	"GlobalsConfig.color1":             {"pattern": "^#?[0-9A-Fa-f]{6}$"},
+	"GlobalsConfig.color2":             {"pattern": "^#?[0-9A-Fa-f]{6}$"},
+	"GlobalsConfig.intensity":          {"minimum": 0, "maximum": 255},
+	"GlobalsConfig.beats_per_bar":      {"minimum": 1},
+	"GlobalsConfig.bars_per_phrase":    {"minimum": 1},
+	"GlobalsConfig.clock_source":       {"enum": []string{ClockSourceInternal, ClockSourceMidi}},
+	"ChainConfig.tickRate":             {"exclusiveMinimum": 0},
+	"ChainConfig.numLamps":             {"minimum": 0},
+	"EffectConfig.blend":               {"enum": blendModes},
+	"EffectConfig.opacity":             {"minimum": 0, "maximum": 1},
+	"ModulatorConfig.type":             {"enum": modulatorTypes},
+	"ModulatorConfig.shape":            {"enum": lfoShapes},
+	"ModulatorConfig.beats":            {"minimum": 0},
+	"ModulatorConfig.phase":            {"minimum": 0, "exclusiveMaximum": 1},
+	"ModulatorConfig.attack":           {"minimum": 0},
+	"ModulatorConfig.decay":            {"minimum": 0},
+	"ModulatorConfig.sustain":          {"minimum": 0, "maximum": 1},
+	"ModulatorConfig.hold":             {"minimum": 0},
+	"ModulatorConfig.release":          {"minimum": 0},
+	"ModulatorConfig.message_type":     {"enum": []string{"cc", "note_on", "pitch_bend"}},
+	"ModulatorConfig.number":           {"minimum": 0, "maximum": 127},
+	"ModulatorConfig.address":          {"pattern": "^/"},
+	"ActionConfig.delay":               {"minimum": 0},
+	"ActionConfig.delay_unit":          {"enum": delayUnits},
+	"ActionConfig.quantize":            {"enum": quantizeModes},
+	"CueConfig.advance":                {"enum": cueAdvances},
+	"CueConfig.duration":               {"minimum": 0},
+	"LampRange.start":                  {"minimum": 0},
+	"LampRange.end":                    {"minimum": 0},
+	"LampRange.every":                  {"minimum": 1},
+	"SharedOutputConfig.merge_mode":    {"enum": []string{"htp", "ltp", "priority"}},
+	"OutputConfig.type":                {"enum": outputTypes},
+	"MidiTriggerConfig.message_type":   {"enum": []string{"cc", "note_on", "note_off"}},
+	"MidiTriggerConfig.number":         {"minimum": 0, "maximum": 127},
+	"MidiTriggerConfig.value":          {"minimum": -1, "maximum": 127},
+	"MidiMasterConfig.intensity_cc":    {"minimum": 0, "maximum": 127},
+	"MidiMasterConfig.blackout_note":   {"minimum": 0, "maximum": 127},
+	"MidiMappingConfig.message_type":   {"enum": []string{"cc", "note_on", "pitch_bend"}},
+	"MidiMappingConfig.number":         {"minimum": 0, "maximum": 127},
+	"MidiMappingConfig.target":         {"enum": []string{"effect_param", "bpm", "intensity", "color1_hue", "color2_hue"}},
+	"MidiMappingConfig.curve":          {"enum": []string{"linear", "exponential", "logarithmic"}},
+	"ArtNetOutputConfig.net":           {"minimum": 0, "maximum": 127},
+	"ArtNetOutputConfig.subnet":        {"minimum": 0, "maximum": 15},
+	"ArtNetOutputConfig.universe":      {"minimum": 0, "maximum": 15},
+	"ArtNetOutputConfig.start_address": {"minimum": 1, "maximum": 512},
+	"SACNOutputConfig.universe":        {"minimum": 1, "maximum": 63999},
+	"SACNOutputConfig.priority":        {"minimum": 0, "maximum": 200},
+	"SACNOutputConfig.sync_universe":   {"minimum": 0, "maximum": 63999},
+	"SACNOutputConfig.start_address":   {"minimum": 1, "maximum": 512},
+}
+
+// JSONSchema returns a JSON Schema describing the configuration file. It is derived
+// from the config structs, the args of every effect come from the metadata of the
+// registered effects, so the schema always matches the effects that are built in.
+// actions holds the schema an action must match per action type, it is merged
+// into the action's object. Definitions are named after the config structs, e.g.
+// "#/definitions/EffectConfig".
+func JSONSchema(actions map[string]Schema) Schema {
+	b := &schemaBuilder{definitions: make(map[string]Schema)}
+	root := b.structSchema(reflect.TypeOf(Config{}))
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "GoDMX configuration"
+	root["definitions"] = b.definitions
+	b.typeSchema(reflect.TypeOf(ModulatorBinding{})) // Number args can be bound to modulators
+	b.definitions["ModulatorBinding"]["required"] = []string{"modulator"}
+
+	// Effect types and their args
+	names := effects.GetAvailableEffects()
+	sort.Strings(names)
+	var effectRules []Schema
+	for _, name := range names {
+		metadata, ok := effects.GetEffectMetadata(name)
+		if !ok {
+			continue
+		}
+		effectRules = append(effectRules, typeRule(name, Schema{
+			"properties": Schema{"args": effectArgsSchema(metadata)},
+		}))
+	}
+	effect := b.definitions["EffectConfig"]
+	setProperty(effect, "type", Schema{"enum": names})
+	effect["allOf"] = effectRules
+	effect["required"] = []string{"type"}
+
+	// Action types and their params
+	actionTypes := make([]string, 0, len(actions))
+	for name := range actions {
+		actionTypes = append(actionTypes, name)
+	}
+	sort.Strings(actionTypes)
+	var actionRules []Schema
+	for _, name := range actionTypes {
+		actionRules = append(actionRules, typeRule(name, actions[name]))
+	}
+	action := b.definitions["ActionConfig"]
+	setProperty(action, "type", Schema{"enum": actionTypes})
+	action["allOf"] = actionRules
+	action["required"] = []string{"type"}
+
+	return root
+}
+
+// typeRule applies then to objects whose type is typeName.
+func typeRule(typeName string, then Schema) Schema {
+	return Schema{
+		"if":   Schema{"properties": Schema{"type": Schema{"const": typeName}}, "required": []string{"type"}},
+		"then": then,
+	}
+}
+
+// setProperty merges a schema into a property of an object schema.
+func setProperty(object Schema, name string, schema Schema) {
+	property := object["properties"].(Schema)[name].(Schema)
+	for k, v := range schema {
+		property[k] = v
+	}
+}
+
+// effectArgsSchema describes the args of an effect.
+func effectArgsSchema(metadata types.EffectMetadata) Schema {
+	properties := Schema{}
+	for _, param := range metadata.Parameters {
+		properties[param.InternalName] = parameterSchema(param)
+	}
+	return Schema{
+		"type":        "object",
+		"description": metadata.Description,
+		"properties":  properties,
+	}
+}
+
+// parameterSchema describes the values of an effect parameter. Number parameters
+// can also be bound to a modulator.
+func parameterSchema(param types.ParameterMetadata) Schema {
+	schema := Schema{
+		"title":       param.DisplayName,
+		"description": param.Description,
+	}
+	switch param.DataType {
+	case "float64":
+		schema["type"] = "number"
+	case "int":
+		schema["type"] = "integer"
+	case "bool":
+		schema["type"] = "boolean"
+	case "string":
+		schema["type"] = "string"
+	case "enum":
+		schema["type"] = "string"
+		schema["enum"] = param.Options
+	case "color":
+		schema["type"] = "string"
+		schema["pattern"] = "^(#?[0-9A-Fa-f]{6})?$" // Empty for optional colors
+	}
+	if param.DefaultValue != nil {
+		schema["default"] = param.DefaultValue
+	}
+	if param.MinValue != nil {
+		schema["minimum"] = param.MinValue
+	}
+	if param.MaxValue != nil {
+		schema["maximum"] = param.MaxValue
+	}
+	if param.DataType == "float64" || param.DataType == "int" {
+		number := Schema{}
+		for _, key := range []string{"type", "minimum", "maximum"} {
+			if value, ok := schema[key]; ok {
+				number[key] = value
+				delete(schema, key)
+			}
+		}
+		schema["anyOf"] = []Schema{number, {"$ref": "#/definitions/ModulatorBinding"}}
+	}
+	return schema
+}
+
+// schemaBuilder derives schemas from Go types, structs become definitions.
+type schemaBuilder struct {
+	definitions map[string]Schema
+}
+
+func (b *schemaBuilder) typeSchema(t reflect.Type) Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.typeSchema(t.Elem())
+	case reflect.Struct:
+		if _, ok := b.definitions[t.Name()]; !ok {
+			b.definitions[t.Name()] = Schema{} // Placeholder for recursive types
+			b.definitions[t.Name()] = b.structSchema(t)
+		}
+		return Schema{"$ref": "#/definitions/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": b.typeSchema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	}
+	return Schema{} // Any value, e.g. interface{}
+}
+
+// structSchema describes a struct by its JSON fields. Unknown fields are rejected,
+// as they are most likely typos.
+func (b *schemaBuilder) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := b.typeSchema(field.Type)
+		if refined, ok := schemaFields[t.Name()+"."+name]; ok {
+			if _, isRef := schema["$ref"]; !isRef {
+				for k, v := range refined {
+					schema[k] = v
+				}
+			}
+		}
+		properties[name] = schema
+	}
+	return Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
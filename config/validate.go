@@ -0,0 +1,566 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"godmx/effects"
+	"godmx/types"
+	"godmx/utils"
+)
+
+// Severity tells whether a validation issue keeps a configuration from being used.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // The configuration can't be used
+	SeverityWarning Severity = "warning" // Most likely a mistake, but the configuration works
+)
+
+// outputTypes are the output types main knows how to create.
+var outputTypes = []string{"artnet", "ddp", "sacn", "govee"}
+
+// blendModes are the ways the orchestrator can combine an effect's output with the frame.
+var blendModes = []string{"normal", "add", "multiply", "screen", "max", "min", "subtract"}
+
+// Issue is a problem found in a configuration.
+type Issue struct {
+	Path     string   `json:"path"` // JSON path of the offending value, e.g. "chains[0].effects[1].args.speed"
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Path, i.Message)
+}
+
+// ErrorIssue returns an error at path.
+func ErrorIssue(path, format string, args ...interface{}) Issue {
+	return Issue{Path: path, Message: fmt.Sprintf(format, args...), Severity: SeverityError}
+}
+
+// WarningIssue returns a warning at path.
+func WarningIssue(path, format string, args ...interface{}) Issue {
+	return Issue{Path: path, Message: fmt.Sprintf(format, args...), Severity: SeverityWarning}
+}
+
+// Issues is a list of validation issues.
+type Issues []Issue
+
+// Errors returns the issues that keep the configuration from being used.
+func (issues Issues) Errors() Issues {
+	return issues.filter(SeverityError)
+}
+
+// Warnings returns the issues that don't keep the configuration from being used.
+func (issues Issues) Warnings() Issues {
+	return issues.filter(SeverityWarning)
+}
+
+func (issues Issues) filter(severity Severity) Issues {
+	var filtered Issues
+	for _, issue := range issues {
+		if issue.Severity == severity {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// Err returns a *ValidationError with the errors, or nil if there are none.
+func (issues Issues) Err() error {
+	errs := issues.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: errs}
+}
+
+// ValidationError is the error for a configuration with validation errors.
+type ValidationError struct {
+	Issues Issues
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.Path + ": " + issue.Message
+	}
+	if len(messages) == 1 {
+		return messages[0]
+	}
+	return fmt.Sprintf("%d errors: %s", len(messages), strings.Join(messages, "; "))
+}
+
+// Validate checks the configuration for problems that would otherwise only show up
+// once the show is running, or never: invalid globals, duplicate or missing IDs,
+// unknown outputs and effects, effect args of the wrong type or out of range, and
+// triggers and MIDI mappings pointing at things that don't exist. Actions are
+// checked by the orchestrator, which defines them.
+func (c *Config) Validate() Issues {
+	var issues Issues
+	issues = append(issues, validateGlobals(&c.Globals, "globals")...)
+	sharedOutputs, sharedIssues := c.validateSharedOutputs()
+	issues = append(issues, sharedIssues...)
+	chains := make(map[string]bool)
+	for i := range c.Chains {
+		path := fmt.Sprintf("chains[%d]", i)
+		if id := c.Chains[i].ID; id != "" && chains[id] {
+			issues = append(issues, ErrorIssue(path+".id", "duplicate chain id '%s'", id))
+		}
+		chains[c.Chains[i].ID] = true
+		issues = append(issues, c.Chains[i].validate(path, sharedOutputs)...)
+	}
+
+	issues = append(issues, c.validateTriggers()...)
+	issues = append(issues, c.validateMidiMappings()...)
+	if c.OSCPort < 0 || c.OSCPort > 65535 {
+		issues = append(issues, ErrorIssue("osc_port", "must be between 1 and 65535, or 0 to not listen"))
+	}
+	issues = append(issues, c.validateModulators()...)
+	issues = append(issues, c.validateScenes()...)
+	issues = append(issues, c.validateCueLists()...)
+	return issues
+}
+
+func validateGlobals(globals *GlobalsConfig, path string) Issues {
+	var issues Issues
+	if globals.BPM <= 0 {
+		issues = append(issues, ErrorIssue(path+".bpm", "must be greater than 0"))
+	}
+	if _, err := utils.ParseHexColor(globals.Color1); err != nil {
+		issues = append(issues, ErrorIssue(path+".color1", "%v", err))
+	}
+	if _, err := utils.ParseHexColor(globals.Color2); err != nil {
+		issues = append(issues, ErrorIssue(path+".color2", "%v", err))
+	}
+	if globals.Intensity != nil && (*globals.Intensity < 0 || *globals.Intensity > 255) {
+		issues = append(issues, ErrorIssue(path+".intensity", "must be between 0 and 255"))
+	}
+	if globals.BeatsPerBar < 1 {
+		issues = append(issues, ErrorIssue(path+".beats_per_bar", "must be at least 1"))
+	}
+	if globals.BarsPerPhrase < 1 {
+		issues = append(issues, ErrorIssue(path+".bars_per_phrase", "must be at least 1"))
+	}
+	switch globals.ClockSource {
+	case "", ClockSourceInternal, ClockSourceMidi:
+	default:
+		issues = append(issues, ErrorIssue(path+".clock_source", "unknown clock source '%s', must be %s or %s", globals.ClockSource, ClockSourceInternal, ClockSourceMidi))
+	}
+	return issues
+}
+
+// validateSharedOutputs checks the shared outputs and returns the set of their IDs.
+func (c *Config) validateSharedOutputs() (map[string]bool, Issues) {
+	var issues Issues
+	ids := make(map[string]bool)
+	for i, shared := range c.SharedOutputs {
+		path := fmt.Sprintf("shared_outputs[%d]", i)
+		if shared.ID == "" {
+			issues = append(issues, ErrorIssue(path+".id", "missing id"))
+		} else if ids[shared.ID] {
+			issues = append(issues, ErrorIssue(path+".id", "duplicate shared output id '%s'", shared.ID))
+		}
+		ids[shared.ID] = true
+		switch shared.MergeMode {
+		case "", "htp", "ltp", "priority":
+		default:
+			issues = append(issues, ErrorIssue(path+".merge_mode", "unknown merge mode '%s', must be htp, ltp or priority", shared.MergeMode))
+		}
+		issues = append(issues, shared.Output.validate(path+".output")...)
+	}
+	return ids, issues
+}
+
+func (chain *ChainConfig) validate(path string, sharedOutputs map[string]bool) Issues {
+	var issues Issues
+	if chain.ID == "" {
+		issues = append(issues, ErrorIssue(path+".id", "missing id"))
+	}
+	if chain.TickRate <= 0 {
+		issues = append(issues, ErrorIssue(path+".tickRate", "must be greater than 0"))
+	}
+	if chain.NumLamps < 0 {
+		issues = append(issues, ErrorIssue(path+".numLamps", "must not be negative"))
+	} else if chain.NumLamps == 0 {
+		issues = append(issues, WarningIssue(path+".numLamps", "chain has no lamps"))
+	}
+	if chain.OutputID != "" {
+		if !sharedOutputs[chain.OutputID] {
+			issues = append(issues, ErrorIssue(path+".output_id", "unknown shared output '%s'", chain.OutputID))
+		}
+		if chain.Output.Type != "" {
+			issues = append(issues, WarningIssue(path+".output", "ignored, the chain sends to shared output '%s'", chain.OutputID))
+		}
+	} else {
+		issues = append(issues, chain.Output.validate(path+".output")...)
+	}
+
+	segments := make([]string, 0, len(chain.Segments))
+	for name := range chain.Segments {
+		segments = append(segments, name)
+	}
+	sort.Strings(segments) // Stable order of the issues
+	for _, name := range segments {
+		segment := chain.Segments[name]
+		if segment.Segment != "" {
+			issues = append(issues, ErrorIssue(path+".segments."+name+".segment", "segments can't refer to other segments"))
+		} else if _, err := segment.Indices(chain.NumLamps, nil); err != nil {
+			issues = append(issues, ErrorIssue(path+".segments."+name, "%v", err))
+		}
+	}
+
+	issues = append(issues, chain.validateEffects(chain.Effects, path+".effects")...)
+	return issues
+}
+
+// validateEffects checks a list of effects of the chain, at path.
+func (chain *ChainConfig) validateEffects(effectConfigs []EffectConfig, path string) Issues {
+	var issues Issues
+	effectIDs := make(map[string]bool)
+	activeGroups := make(map[string]string)
+	for i, effect := range effectConfigs {
+		effectPath := fmt.Sprintf("%s[%d]", path, i)
+		if effect.ID == "" {
+			issues = append(issues, WarningIssue(effectPath+".id", "missing id, actions can't refer to the effect"))
+		} else if effectIDs[effect.ID] {
+			issues = append(issues, ErrorIssue(effectPath+".id", "duplicate effect id '%s' in chain", effect.ID))
+		}
+		effectIDs[effect.ID] = true
+		if effect.Group != "" && (effect.Enabled == nil || *effect.Enabled) {
+			if first, ok := activeGroups[effect.Group]; ok {
+				issues = append(issues, WarningIssue(effectPath+".enabled", "effect '%s' is already enabled in group '%s', this one will be disabled", first, effect.Group))
+			} else {
+				activeGroups[effect.Group] = effect.ID
+			}
+		}
+
+		if effect.Lamps != nil {
+			if _, err := effect.Lamps.Indices(chain.NumLamps, chain.Segments); err != nil {
+				issues = append(issues, ErrorIssue(effectPath+".lamps", "%v", err))
+			}
+		}
+		if effect.Blend != "" && !contains(blendModes, effect.Blend) {
+			issues = append(issues, ErrorIssue(effectPath+".blend", "unknown blend mode '%s', must be one of %s", effect.Blend, strings.Join(blendModes, ", ")))
+		}
+		if effect.Opacity != nil && (*effect.Opacity < 0 || *effect.Opacity > 1) {
+			issues = append(issues, ErrorIssue(effectPath+".opacity", "must be between 0 and 1"))
+		}
+
+		if _, ok := effects.GetEffectMetadata(effect.Type); !ok {
+			issues = append(issues, ErrorIssue(effectPath+".type", "unknown effect type '%s'", effect.Type))
+			continue
+		}
+		issues = append(issues, ValidateEffectArgs(effect.Type, effect.Args, effectPath+".args")...)
+	}
+	return issues
+}
+
+func (output *OutputConfig) validate(path string) Issues {
+	known := false
+	for _, outputType := range outputTypes {
+		known = known || output.Type == outputType
+	}
+	if !known {
+		return Issues{ErrorIssue(path+".type", "unknown output type '%s', must be one of %s", output.Type, strings.Join(outputTypes, ", "))}
+	}
+
+	var issues Issues
+	switch output.Type {
+	case "artnet", "ddp":
+		if _, ok := output.Args["ip"].(string); !ok {
+			issues = append(issues, ErrorIssue(path+".args.ip", "missing or not a string"))
+		}
+	}
+	if output.NumChannelsPerLamp < 0 {
+		issues = append(issues, ErrorIssue(path+".numChannelsPerLamp", "must not be negative"))
+	}
+	return issues
+}
+
+func (c *Config) validateTriggers() Issues {
+	var issues Issues
+	for i, trigger := range c.Triggers {
+		path := fmt.Sprintf("triggers[%d]", i)
+		switch trigger.MessageType {
+		case "cc", "note_on", "note_off":
+		default:
+			issues = append(issues, ErrorIssue(path+".message_type", "unknown message type '%s', must be cc, note_on or note_off", trigger.MessageType))
+		}
+		if trigger.Number < 0 || trigger.Number > 127 {
+			issues = append(issues, ErrorIssue(path+".number", "must be between 0 and 127"))
+		}
+		if trigger.Value < -1 || trigger.Value > 127 {
+			issues = append(issues, ErrorIssue(path+".value", "must be between 0 and 127, or -1 for any value"))
+		}
+		if _, ok := c.Actions[trigger.EventName]; !ok {
+			issues = append(issues, ErrorIssue(path+".event_name", "unknown event '%s'", trigger.EventName))
+		}
+	}
+
+	if c.MidiMaster != nil {
+		if cc := c.MidiMaster.IntensityCC; cc != nil && (*cc < 0 || *cc > 127) {
+			issues = append(issues, ErrorIssue("midi_master.intensity_cc", "must be between 0 and 127"))
+		}
+		if note := c.MidiMaster.BlackoutNote; note != nil && (*note < 0 || *note > 127) {
+			issues = append(issues, ErrorIssue("midi_master.blackout_note", "must be between 0 and 127"))
+		}
+	}
+	return issues
+}
+
+func (c *Config) validateMidiMappings() Issues {
+	var issues Issues
+	for i, mapping := range c.MidiMappings {
+		path := fmt.Sprintf("midi_mappings[%d]", i)
+		switch mapping.MessageType {
+		case "cc", "note_on":
+			if mapping.Number < 0 || mapping.Number > 127 {
+				issues = append(issues, ErrorIssue(path+".number", "must be between 0 and 127"))
+			}
+		case "pitch_bend":
+		default:
+			issues = append(issues, ErrorIssue(path+".message_type", "unknown message type '%s', must be cc, note_on or pitch_bend", mapping.MessageType))
+		}
+		switch mapping.Curve {
+		case "", "linear", "exponential", "logarithmic":
+		default:
+			issues = append(issues, ErrorIssue(path+".curve", "unknown curve '%s', must be linear, exponential or logarithmic", mapping.Curve))
+		}
+
+		switch mapping.Target {
+		case "bpm", "intensity", "color1_hue", "color2_hue":
+		case "effect_param":
+			effect, err := c.FindEffect(mapping.ChainID, mapping.EffectID)
+			if err != nil {
+				issues = append(issues, ErrorIssue(path, "%v", err))
+				continue
+			}
+			param, ok := effects.FindParameter(effect.Type, mapping.Param)
+			if !ok {
+				issues = append(issues, ErrorIssue(path+".param", "effect '%s' has no parameter '%s'", effect.ID, mapping.Param))
+			} else if param.DataType != "float64" && param.DataType != "int" {
+				issues = append(issues, ErrorIssue(path+".param", "parameter '%s' is not a number", mapping.Param))
+			}
+		default:
+			issues = append(issues, ErrorIssue(path+".target", "unknown target '%s', must be effect_param, bpm, intensity, color1_hue or color2_hue", mapping.Target))
+		}
+	}
+	return issues
+}
+
+// ValidateEffectArgs checks effect args against the metadata of the effect type:
+// args the effect doesn't know, values of the wrong type, values out of range (the
+// effect clamps them) and args the effect's constructor rejects. path is the JSON
+// path of the args.
+func ValidateEffectArgs(effectType string, args map[string]interface{}, path string) Issues {
+	if _, ok := effects.GetEffectMetadata(effectType); !ok {
+		return Issues{ErrorIssue(path, "unknown effect type '%s'", effectType)}
+	}
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Stable order of the issues
+	var issues Issues
+	for _, name := range names {
+		value := args[name]
+		param, ok := effects.FindParameter(effectType, name)
+		if !ok {
+			issues = append(issues, WarningIssue(path+"."+name, "unknown parameter of %s, ignored", effectType))
+			continue
+		}
+		if _, ok := AsModulatorBinding(value); ok {
+			if param.DataType != "float64" && param.DataType != "int" {
+				issues = append(issues, ErrorIssue(path+"."+name, "only number parameters can be bound to a modulator"))
+			}
+			continue
+		}
+		if message := checkParameterValue(param, value); message != "" {
+			issues = append(issues, ErrorIssue(path+"."+name, "%s", message))
+			continue
+		}
+		// Effects clamp numbers to their range
+		if number, ok := effects.ToFloat(value); ok {
+			if min, ok := effects.ToFloat(param.MinValue); ok && number < min {
+				issues = append(issues, WarningIssue(path+"."+name, "%v is below the minimum of %v, clamped", number, min))
+			}
+			if max, ok := effects.ToFloat(param.MaxValue); ok && number > max {
+				issues = append(issues, WarningIssue(path+"."+name, "%v is above the maximum of %v, clamped", number, max))
+			}
+		}
+	}
+	if len(issues.Errors()) > 0 {
+		return issues
+	}
+
+	// The constructor may have its own rules, e.g. for combinations of args
+	constructor, ok := effects.GetEffectConstructor(effectType)
+	if !ok {
+		return append(issues, ErrorIssue(path, "unknown effect type '%s'", effectType))
+	}
+	// Args bound to modulators get their values per tick
+	static := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		if _, ok := AsModulatorBinding(value); !ok {
+			static[name] = value
+		}
+	}
+	if _, err := constructor(static); err != nil {
+		issues = append(issues, ErrorIssue(path, "%v", err))
+	}
+	return issues
+}
+
+
+// CheckEffectArg checks a single effect arg against its parameter, without creating
+// the effect. Only number parameters can be bound to a modulator.
+func CheckEffectArg(param types.ParameterMetadata, value interface{}) error {
+	if _, ok := AsModulatorBinding(value); ok {
+		if param.DataType != "float64" && param.DataType != "int" {
+			return fmt.Errorf("parameter '%s': only number parameters can be bound to a modulator", param.InternalName)
+		}
+		return nil
+	}
+	if message := checkParameterValue(param, value); message != "" {
+		return fmt.Errorf("parameter '%s': %s", param.InternalName, message)
+	}
+	return nil
+}
+
+// checkParameterValue checks a value against a parameter's type. It
+// returns a message describing the problem, or "" if the value is valid.
+func checkParameterValue(param types.ParameterMetadata, value interface{}) string {
+	switch param.DataType {
+	case "float64", "int":
+		number, ok := effects.ToFloat(value)
+		if !ok {
+			return fmt.Sprintf("expected a number, got %T", value)
+		}
+		if param.DataType == "int" && number != math.Trunc(number) {
+			return fmt.Sprintf("expected a whole number, got %v", number)
+		}
+	case "bool":
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("expected true or false, got %T", value)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Sprintf("expected a string, got %T", value)
+		}
+	case "enum":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("expected a string, got %T", value)
+		}
+		for _, option := range param.Options {
+			if s == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("invalid value '%s', must be one of %s", s, strings.Join(param.Options, ", "))
+	case "color":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("expected a hex color string, got %T", value)
+		}
+		if s == "" {
+			return ""
+		}
+		if _, err := utils.ParseHexColor(s); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+func (c *Config) validateModulators() Issues {
+	var issues Issues
+	names := make([]string, 0, len(c.Modulators))
+	for name := range c.Modulators {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Stable order of the issues
+	for _, name := range names {
+		modulator := c.Modulators[name]
+		path := "modulators." + name
+		switch modulator.Type {
+		case "lfo":
+			if modulator.Shape != "" && !contains(lfoShapes, modulator.Shape) {
+				issues = append(issues, ErrorIssue(path+".shape", "unknown shape '%s', must be one of %s", modulator.Shape, strings.Join(lfoShapes, ", ")))
+			}
+			if modulator.Beats < 0 {
+				issues = append(issues, ErrorIssue(path+".beats", "must not be negative"))
+			}
+			if modulator.Phase < 0 || modulator.Phase >= 1 {
+				issues = append(issues, ErrorIssue(path+".phase", "must be at least 0 and below 1"))
+			}
+		case "envelope":
+			if modulator.Event == "" {
+				issues = append(issues, ErrorIssue(path+".event", "missing, nothing triggers the envelope"))
+			} else if _, ok := c.Actions[modulator.Event]; !ok {
+				issues = append(issues, ErrorIssue(path+".event", "unknown event '%s'", modulator.Event))
+			}
+			if modulator.Attack < 0 || modulator.Decay < 0 || modulator.Hold < 0 || modulator.Release < 0 {
+				issues = append(issues, ErrorIssue(path, "attack, decay, hold and release must not be negative"))
+			}
+			if modulator.Sustain != nil && (*modulator.Sustain < 0 || *modulator.Sustain > 1) {
+				issues = append(issues, ErrorIssue(path+".sustain", "must be between 0 and 1"))
+			}
+		case "midi":
+			switch modulator.MessageType {
+			case "cc", "note_on", "pitch_bend":
+			default:
+				issues = append(issues, ErrorIssue(path+".message_type", "invalid message type '%s', must be cc, note_on or pitch_bend", modulator.MessageType))
+			}
+			if modulator.Number < 0 || modulator.Number > 127 {
+				issues = append(issues, ErrorIssue(path+".number", "must be between 0 and 127"))
+			}
+		case "osc":
+			if !strings.HasPrefix(modulator.Address, "/") {
+				issues = append(issues, ErrorIssue(path+".address", "invalid OSC address '%s', must start with /", modulator.Address))
+			}
+			if c.OSCPort == 0 {
+				issues = append(issues, WarningIssue(path, "osc_port is not set, the modulator never moves"))
+			}
+		default:
+			issues = append(issues, ErrorIssue(path+".type", "unknown modulator type '%s', must be one of %s", modulator.Type, strings.Join(modulatorTypes, ", ")))
+		}
+	}
+
+	// Effect args bound to modulators
+	for i, chain := range c.Chains {
+		for j, effect := range chain.Effects {
+			args := make([]string, 0, len(effect.Args))
+			for arg := range effect.Args {
+				args = append(args, arg)
+			}
+			sort.Strings(args)
+			for _, arg := range args {
+				binding, ok := AsModulatorBinding(effect.Args[arg])
+				if !ok {
+					continue
+				}
+				if _, ok := c.Modulators[binding.Modulator]; !ok {
+					path := fmt.Sprintf("chains[%d].effects[%d].args.%s.modulator", i, j, arg)
+					issues = append(issues, ErrorIssue(path, "unknown modulator '%s'", binding.Modulator))
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// contains reports whether a list of names contains name.
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
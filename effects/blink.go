@@ -1,7 +1,6 @@
 package effects
 
 import (
-	"fmt"
 	"godmx/dmx"
 	"godmx/types"
 )
@@ -61,15 +60,31 @@ type Blink struct {
 
 // NewBlink creates a new Blink effect.
 func NewBlink(args map[string]interface{}) (types.Effect, error) {
-	divider, ok := args["divider"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("blink effect: missing or invalid 'divider' parameter")
+	p := NewParams("blink", args)
+	b := &Blink{Divider: p.Int("divider"), DutyCycle: p.Float("dutyCycle")}
+	if err := p.Err(); err != nil {
+		return nil, err
 	}
-	dutyCycle, ok := args["dutyCycle"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("blink effect: missing or invalid 'dutyCycle' parameter")
+	return b, nil
+}
+
+// SetParameters updates the changed parameters in place.
+func (b *Blink) SetParameters(args map[string]interface{}) error {
+	p := NewParams("blink", args)
+	updated := *b
+	for name := range args {
+		switch name {
+		case "divider":
+			updated.Divider = p.Int(name)
+		case "dutyCycle":
+			updated.DutyCycle = p.Float(name)
+		}
+	}
+	if err := p.Err(); err != nil {
+		return err
 	}
-	return &Blink{Divider: int(divider), DutyCycle: dutyCycle}, nil
+	*b = updated
+	return nil
 }
 
 // Process applies the blink effect to the lamps.
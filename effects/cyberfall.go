@@ -1,7 +1,6 @@
 package effects
 
 import (
-	"fmt"
 	"math/rand"
 	"time"
 
@@ -137,40 +136,47 @@ type Cyberfall struct {
 
 // NewCyberfall creates a new Cyberfall effect.
 func NewCyberfall(args map[string]interface{}) (types.Effect, error) {
-	speed, ok := args["speed"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("cyberfall effect: missing or invalid 'speed' parameter")
-	}
-	density, ok := args["density"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("cyberfall effect: missing or invalid 'density' parameter")
-	}
-	trailLength, ok := args["trail_length"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("cyberfall effect: missing or invalid 'trail_length' parameter")
+	p := NewParams("cyberfall", args)
+	c := &Cyberfall{
+		Speed:         p.Float("speed"),
+		Density:       p.Float("density"),
+		TrailLength:   p.Int("trail_length"),
+		MinBrightness: uint8(p.Int("min_brightness")),
+		MaxBrightness: uint8(p.Int("max_brightness")),
+		FlickerIntensity: p.Float("flicker_intensity"),
+		lastUpdate:    time.Now(),
 	}
-	minBrightness, ok := args["min_brightness"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("cyberfall effect: missing or invalid 'min_brightness' parameter")
+	if err := p.Err(); err != nil {
+		return nil, err
 	}
-	maxBrightness, ok := args["max_brightness"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("cyberfall effect: missing or invalid 'max_brightness' parameter")
+	return c, nil
+}
+
+// SetParameters updates the changed parameters in place, keeping the falling rain.
+func (c *Cyberfall) SetParameters(args map[string]interface{}) error {
+	p := NewParams("cyberfall", args)
+	updated := *c
+	for name := range args {
+		switch name {
+		case "speed":
+			updated.Speed = p.Float(name)
+		case "density":
+			updated.Density = p.Float(name)
+		case "trail_length":
+			updated.TrailLength = p.Int(name)
+		case "min_brightness":
+			updated.MinBrightness = uint8(p.Int(name))
+		case "max_brightness":
+			updated.MaxBrightness = uint8(p.Int(name))
+		case "flicker_intensity":
+			updated.FlickerIntensity = p.Float(name)
+		}
 	}
-	flickerIntensity, ok := args["flicker_intensity"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("cyberfall effect: missing or invalid 'flicker_intensity' parameter")
+	if err := p.Err(); err != nil {
+		return err
 	}
-
-	return &Cyberfall{
-		Speed:         speed,
-		Density:       density,
-		TrailLength:   int(trailLength),
-		MinBrightness: uint8(minBrightness),
-		MaxBrightness: uint8(maxBrightness),
-		FlickerIntensity: flickerIntensity,
-		lastUpdate:    time.Now(),
-	}, nil
+	*c = updated
+	return nil
 }
 
 // Process applies the Cyberfall effect as a brightness mask to the lamps.
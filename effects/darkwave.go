@@ -1,7 +1,6 @@
 package effects
 
 import (
-	"fmt"
 	"godmx/dmx"
 	"godmx/types"
 	"math"
@@ -62,16 +61,31 @@ func init() {
 
 // NewDarkWave creates a new DarkWave effect.
 func NewDarkWave(args map[string]interface{}) (types.Effect, error) {
-	percentage, ok := args["percentage"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("darkwave effect: missing or invalid 'percentage' parameter")
-	}
-	speed, ok := args["speed"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("darkwave effect: missing or invalid 'speed' parameter")
+	p := NewParams("darkwave", args)
+	dw := &DarkWave{Percentage: p.Float("percentage"), Speed: p.Float("speed")}
+	if err := p.Err(); err != nil {
+		return nil, err
 	}
+	return dw, nil
+}
 
-	return &DarkWave{Percentage: percentage, Speed: speed}, nil
+// SetParameters updates the changed parameters in place.
+func (dw *DarkWave) SetParameters(args map[string]interface{}) error {
+	p := NewParams("darkwave", args)
+	updated := *dw
+	for name := range args {
+		switch name {
+		case "percentage":
+			updated.Percentage = p.Float(name)
+		case "speed":
+			updated.Speed = p.Float(name)
+		}
+	}
+	if err := p.Err(); err != nil {
+		return err
+	}
+	*dw = updated
+	return nil
 }
 
 // Process applies the DarkWave effect to the lamp strip.
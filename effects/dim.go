@@ -1,7 +1,6 @@
 package effects
 
 import (
-	"fmt"
 	"godmx/dmx"
 	"godmx/types"
 	"math"
@@ -47,12 +46,29 @@ type Dim struct {
 
 // NewDim creates a new Dim effect.
 func NewDim(args map[string]interface{}) (types.Effect, error) {
-	percentage, ok := args["percentage"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("dim effect: missing or invalid 'percentage' parameter")
+	p := NewParams("dim", args)
+	d := &Dim{Percentage: p.Float("percentage")}
+	if err := p.Err(); err != nil {
+		return nil, err
 	}
+	return d, nil
+}
 
-	return &Dim{Percentage: percentage}, nil
+// SetParameters updates the changed parameters in place.
+func (d *Dim) SetParameters(args map[string]interface{}) error {
+	p := NewParams("dim", args)
+	updated := *d
+	for name := range args {
+		switch name {
+		case "percentage":
+			updated.Percentage = p.Float(name)
+		}
+	}
+	if err := p.Err(); err != nil {
+		return err
+	}
+	*d = updated
+	return nil
 }
 
 // Process applies the dim effect to the lamps.
@@ -56,8 +56,18 @@ func GenerateEffectDocumentation() error {
 					maxVal = "-"
 				}
 
-				builder.WriteString(fmt.Sprintf("| %s | %s | %s | %v | %s | %s | %s |\n",
-					p.InternalName, p.DisplayName, p.DataType, p.DefaultValue, minVal, maxVal, p.Description))
+				// Enums list their options, optional colors have no default
+				dataType := p.DataType
+				if len(p.Options) > 0 {
+					dataType = fmt.Sprintf("%s (%s)", p.DataType, strings.Join(p.Options, ", "))
+				}
+				defaultVal := fmt.Sprintf("%v", p.DefaultValue)
+				if p.DefaultValue == nil || p.DefaultValue == "" {
+					defaultVal = "-"
+				}
+
+				builder.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %s |\n",
+					p.InternalName, p.DisplayName, dataType, defaultVal, minVal, maxVal, p.Description))
 			}
 			builder.WriteString("\n") // Add a newline after the table for spacing
 		}
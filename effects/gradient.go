@@ -9,37 +9,81 @@ import (
 
 /*
 Effect Name: Gradient
-Description: Creates a smooth color gradient across the lamps, interpolating between global Color1 and Color2.
+Description: Creates a smooth color gradient across the lamps, interpolating between two colors, its own colors or global Color1 and Color2.
 Tags: [color_source, pattern]
-Parameters: []
+Parameters:
+  - InternalName: color1
+    DisplayName: Color 1
+    Description: The color of the first lamp, e.g. "#FF0000". Empty uses global Color1.
+    DataType: color
+    DefaultValue: ""
+  - InternalName: color2
+    DisplayName: Color 2
+    Description: The color of the last lamp, e.g. "#0000FF". Empty uses global Color2.
+    DataType: color
+    DefaultValue: ""
 */
 func init() {
 	RegisterEffect("gradient", NewGradient)
 	RegisterEffectMetadata("gradient", types.EffectMetadata{
 		HumanReadableName: "Gradient",
-		Description:       "Creates a smooth color gradient across the lamps, interpolating between global Color1 and Color2.",
+		Description:       "Creates a smooth color gradient across the lamps, interpolating between two colors, its own colors or global Color1 and Color2.",
 		Tags:              []string{"color_source", "pattern"},
-		Parameters:        []types.ParameterMetadata{},
+		Parameters: []types.ParameterMetadata{
+			{
+				InternalName: "color1",
+				DisplayName:  "Color 1",
+				Description:  "The color of the first lamp, e.g. \"#FF0000\". Empty uses global Color1.",
+				DataType:     "color",
+				DefaultValue: "",
+			},
+			{
+				InternalName: "color2",
+				DisplayName:  "Color 2",
+				Description:  "The color of the last lamp, e.g. \"#0000FF\". Empty uses global Color2.",
+				DataType:     "color",
+				DefaultValue: "",
+			},
+		},
 	})
 }
 
 // Gradient creates a color gradient across the lamps.
 type Gradient struct {
-	// No fields needed, colors come from globals
+	Color1 *dmx.Lamp // Own start color, nil to use global Color1
+	Color2 *dmx.Lamp // Own end color, nil to use global Color2
 }
 
 // NewGradient creates a new Gradient effect.
 func NewGradient(args map[string]interface{}) (types.Effect, error) {
-	return &Gradient{}, nil
+	p := NewParams("gradient", args)
+	g := &Gradient{}
+	if color, ok := p.Color("color1"); ok {
+		g.Color1 = &color
+	}
+	if color, ok := p.Color("color2"); ok {
+		g.Color2 = &color
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
 }
 
-// Process applies the gradient effect to the lamps using globals.Color1 and globals.Color2.
+// Process applies the gradient effect to the lamps, using globals.Color1 and globals.Color2 unless the effect has its own colors.
 func (g *Gradient) Process(lamps []dmx.Lamp, globals *types.OrchestratorGlobals, channelMapping string, numChannelsPerLamp int) {
 	numLamps := float64(len(lamps))
+	color1, color2 := globals.Color1, globals.Color2
+	if g.Color1 != nil {
+		color1 = *g.Color1
+	}
+	if g.Color2 != nil {
+		color2 = *g.Color2
+	}
 
-	// Convert Color1 and Color2 to HSV for interpolation
-	h1, s1, v1 := utils.RgbToHsv(globals.Color1.R, globals.Color1.G, globals.Color1.B)
-	h2, s2, v2 := utils.RgbToHsv(globals.Color2.R, globals.Color2.G, globals.Color2.B)
+	// Convert the colors to HSV for interpolation
+	h1, s1, v1 := utils.RgbToHsv(color1.R, color1.G, color1.B)
+	h2, s2, v2 := utils.RgbToHsv(color2.R, color2.G, color2.B)
 
 	// Handle hue interpolation across the color wheel (shortest path)
 	if math.Abs(h1-h2) > 0.5 {
@@ -1,7 +1,6 @@
 package effects
 
 import (
-	"fmt"
 	"godmx/dmx"
 	"godmx/types"
 	"godmx/utils"
@@ -16,7 +15,8 @@ Parameters:
   - InternalName: direction
     DisplayName: Direction
     Description: The direction to shift the hue ('left' or 'right').
-    DataType: string
+    DataType: enum
+    Options: [left, right]
     DefaultValue: "left"
   - InternalName: beatspan
     DisplayName: Beat Span
@@ -40,8 +40,9 @@ func init() {
 				InternalName: "direction",
 				DisplayName:  "Direction",
 				Description:  "The direction to shift the hue ('left' or 'right').",
-				DataType:     "string",
+				DataType:     "enum",
 				DefaultValue: "left",
+				Options:      []string{"left", "right"},
 			},
 			{
 				InternalName: "beatspan",
@@ -66,43 +67,43 @@ type HueShift struct {
 	Direction string  // "left" or "right"
 	BeatSpan  float64 // Number of beats for the huerange to complete
 	HueRange  float64 // Total hue shift in degrees (0-360) over the BeatSpan
-	accumulatedHueShift float64 // Internal state to accumulate hue shift over beats
-	LastBeatProgress float64 // Stores BeatProgress from the previous frame to detect beat transitions
 }
 
 // NewHueShift creates a new HueShift effect.
 func NewHueShift(args map[string]interface{}) (types.Effect, error) {
-	direction, ok := args["direction"].(string)
-	if !ok {
-		return nil, fmt.Errorf("hueshift effect: missing or invalid 'direction' parameter")
-	}
-	if direction != "left" && direction != "right" {
-		return nil, fmt.Errorf("hueshift effect: invalid direction '%s'. Must be 'left' or 'right'", direction)
+	p := NewParams("hueshift", args)
+	s := &HueShift{Direction: p.Enum("direction"), BeatSpan: p.Float("beatspan"), HueRange: p.Float("huerange")}
+	if err := p.Err(); err != nil {
+		return nil, err
 	}
+	return s, nil
+}
 
-	beatSpan, ok := args["beatspan"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("hueshift effect: missing or invalid 'beatspan' parameter")
+// SetParameters updates the changed parameters in place.
+func (s *HueShift) SetParameters(args map[string]interface{}) error {
+	p := NewParams("hueshift", args)
+	updated := *s
+	for name := range args {
+		switch name {
+		case "direction":
+			updated.Direction = p.Enum(name)
+		case "beatspan":
+			updated.BeatSpan = p.Float(name)
+		case "huerange":
+			updated.HueRange = p.Float(name)
+		}
 	}
-	hueRange, ok := args["huerange"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("hueshift effect: missing or invalid 'huerange' parameter")
+	if err := p.Err(); err != nil {
+		return err
 	}
-
-	return &HueShift{Direction: direction, BeatSpan: beatSpan, HueRange: hueRange, accumulatedHueShift: 0.0, LastBeatProgress: 0.0}, nil
+	*s = updated
+	return nil
 }
 
 // Process applies the hueshift effect to the lamps.
 func (s *HueShift) Process(lamps []dmx.Lamp, globals *types.OrchestratorGlobals, channelMapping string, numChannelsPerLamp int) {
-	// Update accumulatedHueShift based on beat progress
-	if globals.BeatProgress < s.LastBeatProgress {
-		s.accumulatedHueShift += (1.0 - s.LastBeatProgress) + globals.BeatProgress
-	} else {
-		s.accumulatedHueShift += (globals.BeatProgress - s.LastBeatProgress)
-	}
-
-	s.accumulatedHueShift = math.Mod(s.accumulatedHueShift, s.BeatSpan)
-	beatspanProgress := s.accumulatedHueShift / s.BeatSpan
+	// Progress through the beatspan, locked to the global beat time
+	beatspanProgress := math.Mod(globals.BeatTime, s.BeatSpan) / s.BeatSpan
 	hueShiftAmount := beatspanProgress * (s.HueRange / 360.0)
 
 	for i := range lamps {
@@ -133,7 +134,4 @@ func (s *HueShift) Process(lamps []dmx.Lamp, globals *types.OrchestratorGlobals,
 		lamps[i].G = newG
 		lamps[i].B = newB
 	}
-
-	// Store current BeatProgress for the next frame's calculation
-	s.LastBeatProgress = globals.BeatProgress
 }
\ No newline at end of file
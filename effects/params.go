@@ -0,0 +1,208 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"godmx/dmx"
+	"godmx/types"
+	"godmx/utils"
+)
+
+// Params decodes the args of an effect according to its registered parameter
+// metadata. Missing args get the default value and numbers are clamped to the
+// parameter's range. The first error is kept and returned by Err, so a
+// constructor can read all its parameters and check for errors once:
+//
+//	p := NewParams("dim", args)
+//	d := &Dim{Percentage: p.Float("percentage")}
+//	if err := p.Err(); err != nil {
+//		return nil, err
+//	}
+type Params struct {
+	effect string
+	args   map[string]interface{}
+	params []types.ParameterMetadata
+	err    error
+}
+
+// NewParams returns a decoder for the args of a registered effect.
+func NewParams(effect string, args map[string]interface{}) *Params {
+	p := &Params{effect: effect, args: args}
+	metadata, ok := GetEffectMetadata(effect)
+	if !ok {
+		p.err = fmt.Errorf("%s effect: no metadata registered", effect)
+		return p
+	}
+	p.params = metadata.Parameters
+	return p
+}
+
+// Err returns the first error that occurred while decoding.
+func (p *Params) Err() error {
+	return p.err
+}
+
+// Float returns a float64 or int parameter.
+func (p *Params) Float(name string) float64 {
+	param, value, ok := p.lookup(name, "float64", "int")
+	if !ok {
+		return 0
+	}
+	number, ok := ToFloat(value)
+	if !ok {
+		p.fail(name, "expected a number, got %T", value)
+		return 0
+	}
+	return p.clamp(param, number)
+}
+
+// Int returns an int parameter. Numbers with a fraction are an error.
+func (p *Params) Int(name string) int {
+	param, value, ok := p.lookup(name, "int")
+	if !ok {
+		return 0
+	}
+	number, ok := ToFloat(value)
+	if !ok {
+		p.fail(name, "expected a whole number, got %T", value)
+		return 0
+	}
+	if number != math.Trunc(number) {
+		p.fail(name, "expected a whole number, got %v", number)
+		return 0
+	}
+	return int(p.clamp(param, number))
+}
+
+// Bool returns a bool parameter.
+func (p *Params) Bool(name string) bool {
+	_, value, ok := p.lookup(name, "bool")
+	if !ok {
+		return false
+	}
+	b, ok := value.(bool)
+	if !ok {
+		p.fail(name, "expected true or false, got %T", value)
+	}
+	return b
+}
+
+// String returns a string parameter.
+func (p *Params) String(name string) string {
+	_, value, ok := p.lookup(name, "string")
+	if !ok {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		p.fail(name, "expected a string, got %T", value)
+	}
+	return s
+}
+
+// Enum returns an enum parameter, which must be one of the parameter's options.
+func (p *Params) Enum(name string) string {
+	param, value, ok := p.lookup(name, "enum")
+	if !ok {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		p.fail(name, "expected a string, got %T", value)
+		return ""
+	}
+	for _, option := range param.Options {
+		if s == option {
+			return s
+		}
+	}
+	p.fail(name, "invalid value '%s', must be one of %s", s, strings.Join(param.Options, ", "))
+	return ""
+}
+
+// Color returns a color parameter given as hex string, e.g. "#FF8000". ok is false
+// if the color is empty, which optional colors use for "not set".
+func (p *Params) Color(name string) (color dmx.Lamp, ok bool) {
+	_, value, found := p.lookup(name, "color")
+	if !found {
+		return dmx.Lamp{}, false
+	}
+	s, isString := value.(string)
+	if !isString {
+		p.fail(name, "expected a hex color string, got %T", value)
+		return dmx.Lamp{}, false
+	}
+	if s == "" {
+		return dmx.Lamp{}, false
+	}
+	color, err := utils.ParseHexColor(s)
+	if err != nil {
+		p.fail(name, "%v", err)
+		return dmx.Lamp{}, false
+	}
+	return color, true
+}
+
+// lookup returns the metadata and the value of a parameter, or its default value
+// if it isn't set. ok is false if the parameter doesn't exist, has none of the
+// given data types or an earlier error occurred.
+func (p *Params) lookup(name string, dataTypes ...string) (types.ParameterMetadata, interface{}, bool) {
+	if p.err != nil {
+		return types.ParameterMetadata{}, nil, false
+	}
+	for _, param := range p.params {
+		if param.InternalName != name {
+			continue
+		}
+		known := false
+		for _, dataType := range dataTypes {
+			known = known || param.DataType == dataType
+		}
+		if !known {
+			p.fail(name, "is a %s parameter, not %s", param.DataType, strings.Join(dataTypes, " or "))
+			return param, nil, false
+		}
+		value, ok := p.args[name]
+		if !ok || value == nil {
+			value = param.DefaultValue
+		}
+		return param, value, true
+	}
+	p.fail(name, "unknown parameter")
+	return types.ParameterMetadata{}, nil, false
+}
+
+// clamp limits a number to the range of a parameter.
+func (p *Params) clamp(param types.ParameterMetadata, number float64) float64 {
+	if min, ok := ToFloat(param.MinValue); ok && number < min {
+		return min
+	}
+	if max, ok := ToFloat(param.MaxValue); ok && number > max {
+		return max
+	}
+	return number
+}
+
+// fail records the first error.
+func (p *Params) fail(name, format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%s effect: parameter '%s': %s", p.effect, name, fmt.Sprintf(format, args...))
+	}
+}
+
+// ToFloat converts a number from JSON (float64) or from metadata (int) to a float64.
+func ToFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
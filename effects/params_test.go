@@ -0,0 +1,149 @@
+package effects
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"godmx/dmx"
+	"godmx/types"
+)
+
+func init() {
+	RegisterEffectMetadata("paramsTest", types.EffectMetadata{
+		HumanReadableName: "Params Test",
+		Parameters: []types.ParameterMetadata{
+			{InternalName: "ratio", DataType: "float64", DefaultValue: 0.5, MinValue: 0.0, MaxValue: 1.0},
+			{InternalName: "count", DataType: "int", DefaultValue: 3, MinValue: 1, MaxValue: 10},
+			{InternalName: "free", DataType: "float64", DefaultValue: 2.0},
+			{InternalName: "on", DataType: "bool", DefaultValue: true},
+			{InternalName: "label", DataType: "string", DefaultValue: "x"},
+			{InternalName: "direction", DataType: "enum", DefaultValue: "left", Options: []string{"left", "right"}},
+			{InternalName: "color", DataType: "color", DefaultValue: ""},
+		},
+	})
+}
+
+// colorResult is the result of Params.Color.
+type colorResult struct {
+	color dmx.Lamp
+	ok    bool
+}
+
+func TestParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		decode  func(p *Params) interface{}
+		want    interface{}
+		wantErr string // Part of the error, "" for none
+	}{
+		{name: "float default", decode: func(p *Params) interface{} { return p.Float("ratio") }, want: 0.5},
+		{name: "int default", decode: func(p *Params) interface{} { return p.Int("count") }, want: 3},
+		{name: "bool default", decode: func(p *Params) interface{} { return p.Bool("on") }, want: true},
+		{name: "enum default", decode: func(p *Params) interface{} { return p.Enum("direction") }, want: "left"},
+		{name: "nil uses the default", args: map[string]interface{}{"ratio": nil}, decode: func(p *Params) interface{} { return p.Float("ratio") }, want: 0.5},
+		{name: "float", args: map[string]interface{}{"ratio": 0.25}, decode: func(p *Params) interface{} { return p.Float("ratio") }, want: 0.25},
+		{name: "int given as float64", args: map[string]interface{}{"count": 7.0}, decode: func(p *Params) interface{} { return p.Int("count") }, want: 7},
+		{name: "int for a float", args: map[string]interface{}{"ratio": 1}, decode: func(p *Params) interface{} { return p.Float("ratio") }, want: 1.0},
+		{name: "fractional int", args: map[string]interface{}{"count": 2.5}, decode: func(p *Params) interface{} { return p.Int("count") }, want: 0, wantErr: "expected a whole number"},
+		{name: "string for a number", args: map[string]interface{}{"ratio": "half"}, decode: func(p *Params) interface{} { return p.Float("ratio") }, want: 0.0, wantErr: "expected a number"},
+		{name: "clamped to min", args: map[string]interface{}{"ratio": -0.5}, decode: func(p *Params) interface{} { return p.Float("ratio") }, want: 0.0},
+		{name: "clamped to max", args: map[string]interface{}{"ratio": 1.5}, decode: func(p *Params) interface{} { return p.Float("ratio") }, want: 1.0},
+		{name: "int clamped to max", args: map[string]interface{}{"count": 11.0}, decode: func(p *Params) interface{} { return p.Int("count") }, want: 10},
+		{name: "int clamped to min", args: map[string]interface{}{"count": 0.0}, decode: func(p *Params) interface{} { return p.Int("count") }, want: 1},
+		{name: "no range", args: map[string]interface{}{"free": -100.0}, decode: func(p *Params) interface{} { return p.Float("free") }, want: -100.0},
+		{name: "bool", args: map[string]interface{}{"on": false}, decode: func(p *Params) interface{} { return p.Bool("on") }, want: false},
+		{name: "not a bool", args: map[string]interface{}{"on": "yes"}, decode: func(p *Params) interface{} { return p.Bool("on") }, want: false, wantErr: "expected true or false"},
+		{name: "string", args: map[string]interface{}{"label": "abc"}, decode: func(p *Params) interface{} { return p.String("label") }, want: "abc"},
+		{name: "enum option", args: map[string]interface{}{"direction": "right"}, decode: func(p *Params) interface{} { return p.Enum("direction") }, want: "right"},
+		{name: "enum outside the options", args: map[string]interface{}{"direction": "up"}, decode: func(p *Params) interface{} { return p.Enum("direction") }, want: "", wantErr: "must be one of left, right"},
+		{name: "color", args: map[string]interface{}{"color": "#FF8000"}, decode: func(p *Params) interface{} { c, ok := p.Color("color"); return colorResult{c, ok} }, want: colorResult{dmx.Lamp{R: 255, G: 128}, true}},
+		{name: "color without hash", args: map[string]interface{}{"color": "00ff7f"}, decode: func(p *Params) interface{} { c, ok := p.Color("color"); return colorResult{c, ok} }, want: colorResult{dmx.Lamp{G: 255, B: 127}, true}},
+		{name: "empty color", decode: func(p *Params) interface{} { c, ok := p.Color("color"); return colorResult{c, ok} }, want: colorResult{}},
+		{name: "invalid color", args: map[string]interface{}{"color": "#GG0000"}, decode: func(p *Params) interface{} { c, ok := p.Color("color"); return colorResult{c, ok} }, want: colorResult{}, wantErr: "parameter 'color'"},
+		{name: "color not a string", args: map[string]interface{}{"color": 5.0}, decode: func(p *Params) interface{} { c, ok := p.Color("color"); return colorResult{c, ok} }, want: colorResult{}, wantErr: "expected a hex color string"},
+		{name: "unknown parameter", decode: func(p *Params) interface{} { return p.Float("speed") }, want: 0.0, wantErr: "parameter 'speed': unknown parameter"},
+		{name: "wrong data type", decode: func(p *Params) interface{} { return p.Float("label") }, want: 0.0, wantErr: "is a string parameter, not float64 or int"},
+		{
+			name: "first error wins",
+			args: map[string]interface{}{"count": 2.5, "direction": "up"},
+			decode: func(p *Params) interface{} {
+				return []interface{}{p.Int("count"), p.Enum("direction"), p.Float("ratio")}
+			},
+			want:    []interface{}{0, "", 0.0}, // Nothing is decoded after an error
+			wantErr: "parameter 'count'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := NewParams("paramsTest", test.args)
+			got := test.decode(p)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+			err := p.Err()
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("error = %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParamsUnknownEffect(t *testing.T) {
+	p := NewParams("noSuchEffect", nil)
+	if p.Float("ratio") != 0 || p.Err() == nil {
+		t.Errorf("expected an error for an effect without metadata, got %v", p.Err())
+	}
+}
+
+func TestSetParametersUpdatesChangedArgsInPlace(t *testing.T) {
+	effect, err := NewCyberfall(map[string]interface{}{"speed": 2.0, "trail_length": 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := effect.(*Cyberfall)
+	c.lampStates = []float64{3, -1}
+
+	if err := c.SetParameters(map[string]interface{}{"density": 0.75}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Density != 0.75 {
+		t.Errorf("density = %v, want 0.75", c.Density)
+	}
+	if c.Speed != 2 || c.TrailLength != 8 {
+		t.Errorf("unchanged args were reset: speed %v, trail length %d", c.Speed, c.TrailLength)
+	}
+	if len(c.lampStates) != 2 || c.lampStates[0] != 3 {
+		t.Errorf("running state was reset: %v", c.lampStates)
+	}
+
+	// An invalid arg leaves all parameters as they were
+	if err := c.SetParameters(map[string]interface{}{"speed": 1.0, "trail_length": 2.5}); err == nil {
+		t.Fatal("expected an error for a fractional trail length")
+	}
+	if c.Speed != 2 || c.TrailLength != 8 {
+		t.Errorf("failed update changed args: speed %v, trail length %d", c.Speed, c.TrailLength)
+	}
+}
+
+func TestTwinkleSetParametersKeepsGenerator(t *testing.T) {
+	effect, err := NewTwinkle(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := effect.(*Twinkle)
+	generator := tw.generator
+	if err := tw.SetParameters(map[string]interface{}{"percentage": 2.0}); err != nil {
+		t.Fatal(err)
+	}
+	if tw.Percentage != 1 {
+		t.Errorf("percentage = %v, want 1 (clamped)", tw.Percentage)
+	}
+	if tw.generator != generator {
+		t.Error("SetParameters replaced the random generator")
+	}
+}
@@ -43,6 +43,20 @@ func GetEffectMetadata(name string) (types.EffectMetadata, bool) {
 	return metadata, ok
 }
 
+// FindParameter returns the metadata of a parameter of an effect type.
+func FindParameter(effectType, name string) (types.ParameterMetadata, bool) {
+	metadata, ok := GetEffectMetadata(effectType)
+	if !ok {
+		return types.ParameterMetadata{}, false
+	}
+	for _, param := range metadata.Parameters {
+		if param.InternalName == name {
+			return param, true
+		}
+	}
+	return types.ParameterMetadata{}, false
+}
+
 // GetAvailableEffects returns a slice of all registered effect names.
 func GetAvailableEffects() []string {
 	names := make([]string, 0, len(effectRegistry))
@@ -1,7 +1,6 @@
 package effects
 
 import (
-	"fmt"
 	"godmx/dmx"
 	"godmx/types"
 )
@@ -14,7 +13,8 @@ Parameters:
   - InternalName: direction
     DisplayName: Direction
     Description: The direction to shift the lamps ('left' or 'right').
-    DataType: string
+    DataType: enum
+    Options: [left, right]
     DefaultValue: "left"
   - InternalName: speed
     DisplayName: Speed
@@ -33,8 +33,9 @@ func init() {
 				InternalName: "direction",
 				DisplayName:  "Direction",
 				Description:  "The direction to shift the lamps ('left' or 'right').",
-				DataType:     "string",
+				DataType:     "enum",
 				DefaultValue: "left",
+				Options:      []string{"left", "right"},
 			},
 			{
 				InternalName: "speed",
@@ -55,19 +56,31 @@ type Shift struct {
 
 // NewShift creates a new Shift effect.
 func NewShift(args map[string]interface{}) (types.Effect, error) {
-	direction, ok := args["direction"].(string)
-	if !ok {
-		return nil, fmt.Errorf("shift effect: missing or invalid 'direction' parameter")
+	p := NewParams("shift", args)
+	s := &Shift{Direction: p.Enum("direction"), Speed: p.Float("speed")}
+	if err := p.Err(); err != nil {
+		return nil, err
 	}
-	if direction != "left" && direction != "right" {
-		return nil, fmt.Errorf("shift effect: invalid direction '%s'. Must be 'left' or 'right'", direction)
+	return s, nil
+}
+
+// SetParameters updates the changed parameters in place.
+func (s *Shift) SetParameters(args map[string]interface{}) error {
+	p := NewParams("shift", args)
+	updated := *s
+	for name := range args {
+		switch name {
+		case "direction":
+			updated.Direction = p.Enum(name)
+		case "speed":
+			updated.Speed = p.Float(name)
+		}
 	}
-	speed, ok := args["speed"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("shift effect: missing or invalid 'speed' parameter")
+	if err := p.Err(); err != nil {
+		return err
 	}
-
-	return &Shift{Direction: direction, Speed: speed}, nil
+	*s = updated
+	return nil
 }
 
 // Process applies the shift effect to the lamps.
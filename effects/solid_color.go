@@ -7,39 +7,64 @@ import (
 
 /*
 Effect Name: Solid Color
-Description: Sets all lamps to a single color defined by global Color1.
+Description: Sets all lamps to a single color, its own color or global Color1.
 Tags: [color_source]
-Parameters: []
+Parameters:
+  - InternalName: color
+    DisplayName: Color
+    Description: The color of the lamps, e.g. "#FF8000". Empty uses global Color1.
+    DataType: color
+    DefaultValue: ""
 */
 func init() {
 	RegisterEffect("solidColor", NewSolidColor)
 	RegisterEffectMetadata("solidColor", types.EffectMetadata{
 		HumanReadableName: "Solid Color",
-		Description:       "Sets all lamps to a single color defined by global Color1.",
+		Description:       "Sets all lamps to a single color, its own color or global Color1.",
 		Tags:              []string{"color_source"},
-		Parameters:        []types.ParameterMetadata{},
+		Parameters: []types.ParameterMetadata{
+			{
+				InternalName: "color",
+				DisplayName:  "Color",
+				Description:  "The color of the lamps, e.g. \"#FF8000\". Empty uses global Color1.",
+				DataType:     "color",
+				DefaultValue: "",
+			},
+		},
 	})
 }
 
-// SolidColor sets all lamps to a single color from global parameters.
+// SolidColor sets all lamps to a single color.
 type SolidColor struct {
-	// No fields needed, color comes from globals
+	Color *dmx.Lamp // Own color, nil to use global Color1
 }
 
 // NewSolidColor creates a new SolidColor effect.
 func NewSolidColor(args map[string]interface{}) (types.Effect, error) {
-	return &SolidColor{}, nil
+	p := NewParams("solidColor", args)
+	s := &SolidColor{}
+	if color, ok := p.Color("color"); ok {
+		s.Color = &color
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
-// Process applies the solid color to the lamps using globals.Color1.
+// Process applies the solid color to the lamps, using globals.Color1 unless the effect has its own color.
 func (s *SolidColor) Process(lamps []dmx.Lamp, globals *types.OrchestratorGlobals, channelMapping string, numChannelsPerLamp int) {
+	color := globals.Color1
+	if s.Color != nil {
+		color = *s.Color
+	}
 	for i := range lamps {
-		lamps[i].R = globals.Color1.R
-		lamps[i].G = globals.Color1.G
-		lamps[i].B = globals.Color1.B
+		lamps[i].R = color.R
+		lamps[i].G = color.G
+		lamps[i].B = color.B
 		// Only set W if the channel mapping is RGBW, otherwise set to 0
 		if numChannelsPerLamp == 4 && channelMapping == "RGBW" {
-			lamps[i].W = globals.Color1.W
+			lamps[i].W = color.W
 		} else {
 			lamps[i].W = 0
 		}
@@ -1,7 +1,6 @@
 package effects
 
 import (
-	"fmt"
 	"godmx/dmx"
 	"godmx/types"
 	"math/rand"
@@ -46,14 +45,15 @@ type Twinkle struct {
 	Percentage        float64
 	source            rand.Source
 	generator         *rand.Rand
-	lastBeatTriggered bool // New: Flag to track if twinkle was triggered on the current beat
+	lastBeat          int64 // Beat count of the last twinkle, so it triggers once per beat
 }
 
 // NewTwinkle creates a new Twinkle effect.
 func NewTwinkle(args map[string]interface{}) (types.Effect, error) {
-	percentage, ok := args["percentage"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("twinkle effect: missing or invalid 'percentage' parameter")
+	p := NewParams("twinkle", args)
+	percentage := p.Float("percentage")
+	if err := p.Err(); err != nil {
+		return nil, err
 	}
 
 	src := rand.NewSource(time.Now().UnixNano())
@@ -63,15 +63,31 @@ func NewTwinkle(args map[string]interface{}) (types.Effect, error) {
 		Percentage:        percentage,
 		source:            src,
 		generator:         gen,
-		lastBeatTriggered: false,
+		lastBeat:          -1,
 	}, nil
 }
 
+// SetParameters updates the changed parameters in place, keeping the random generator and beat state.
+func (t *Twinkle) SetParameters(args map[string]interface{}) error {
+	p := NewParams("twinkle", args)
+	updated := *t
+	for name := range args {
+		switch name {
+		case "percentage":
+			updated.Percentage = p.Float(name)
+		}
+	}
+	if err := p.Err(); err != nil {
+		return err
+	}
+	*t = updated
+	return nil
+}
+
 // Process applies the twinkle effect to the lamps.
 func (t *Twinkle) Process(lamps []dmx.Lamp, globals *types.OrchestratorGlobals, channelMapping string, numChannelsPerLamp int) {
-	// Trigger twinkle only once per beat, when BeatProgress crosses a threshold (e.g., 0.0)
-	// and it hasn't been triggered yet for this beat.
-	if globals.BeatProgress < 0.1 && !t.lastBeatTriggered { // Trigger at the beginning of the beat
+	// Trigger twinkle only once per beat, on the first tick of a new beat
+	if globals.Beat != t.lastBeat {
 		numToTwinkle := int(float64(len(lamps)) * t.Percentage)
 
 		// Create a permutation of lamp indices and pick the first `numToTwinkle`.
@@ -86,9 +102,6 @@ func (t *Twinkle) Process(lamps []dmx.Lamp, globals *types.OrchestratorGlobals,
 				lamps[lampi] = dmx.Lamp{R: 255, G: 255, B: 255, W: 255} // Default to RGBW white
 			}
 		}
-		t.lastBeatTriggered = true // Mark as triggered for this beat
-	} else if globals.BeatProgress >= 0.1 {
-		// Reset the flag once we've passed the trigger threshold for the current beat
-		t.lastBeatTriggered = false
+		t.lastBeat = globals.Beat // Mark as triggered for this beat
 	}
 }
@@ -2,7 +2,4 @@ module godmx
 
 go 1.24.5
 
-require (
-	github.com/RickHulzinga/go-simple-artnet v1.2.0
-	gitlab.com/gomidi/midi/v2 v2.3.16
-)
+require gitlab.com/gomidi/midi/v2 v2.3.16
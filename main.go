@@ -1,8 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"godmx/artnet"
 	"godmx/config"
 	"godmx/orchestrator"
 	"godmx/outputs"
@@ -12,6 +14,7 @@ import (
 	"time"
 	"godmx/effects"
 	"godmx/midi"
+	"godmx/osc"
 )
 
 func main() {
@@ -21,6 +24,10 @@ func main() {
 	webPort := flag.Int("web-port", 8080, "Port for the web UI")
 	eventName := flag.String("event", "", "Name of an event to trigger on startup")
 	docs := flag.Bool("docs", false, "Generate documentation for effects in EFFECTS.md")
+	discover := flag.Bool("artnet-discover", false, "Discover Art-Net nodes on the network, list them and exit")
+	watch := flag.Bool("watch", true, "Reload the configuration file when it changes")
+	validate := flag.Bool("validate", false, "Validate the configuration file, list errors and warnings and exit")
+	schemaPath := flag.String("schema", "", "Write the JSON Schema of the configuration file to this path (- for stdout) and exit")
 	flag.Parse()
 
 	// Generate documentation if -docs flag is present
@@ -34,6 +41,35 @@ func main() {
 		return
 	}
 
+	// List Art-Net nodes if -artnet-discover flag is present
+	if *discover {
+		fmt.Println("Discovering Art-Net nodes...")
+		nodes, err := artnet.Discover(3 * time.Second)
+		if err != nil {
+			fmt.Printf("Error discovering Art-Net nodes: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Found %d Art-Net node(s).\n", len(nodes))
+		for _, node := range nodes {
+			fmt.Printf("  %s  %-18s %s (MAC %s, port-addresses %v)\n", node.IP, node.ShortName, node.LongName, node.MAC, node.PortAddresses)
+		}
+		return
+	}
+
+	// Write the JSON Schema if -schema flag is present
+	if *schemaPath != "" {
+		if err := writeConfigSchema(*schemaPath); err != nil {
+			fmt.Printf("Error writing JSON Schema: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Validate the configuration if -validate flag is present
+	if *validate {
+		os.Exit(validateConfig(*configPath))
+	}
+
 	fmt.Println("Starting GoDMX...")
 
 	// Load configuration
@@ -53,60 +89,27 @@ func main() {
 	color2, _ := utils.ParseHexColor(cfg.Globals.Color2)
 	orch.SetColor2(color2)
 
-	// --- Build Chains from config ---
-	for i := range cfg.Chains {
-		chainConfig := &cfg.Chains[i]
-
-		// Create Output for the chain
-		var output orchestrator.Output
-		switch chainConfig.Output.Type {
-		case "artnet":
-			ip, ok := chainConfig.Output.Args["ip"].(string)
-			if !ok {
-				fmt.Printf("ArtNet output 'ip' argument missing or invalid for chain %s.\n", chainConfig.ID)
-				return
-			}
-			artNetOutput, err := outputs.NewArtNetOutput(ip, *debug, chainConfig.Output.ChannelMapping, chainConfig.Output.NumChannelsPerLamp)
-			if err != nil {
-				fmt.Printf("Error creating Art-Net output for chain %s: %v\n", chainConfig.ID, err)
-				return
-			}
-			output = artNetOutput
-		case "ddp":
-			ip, ok := chainConfig.Output.Args["ip"].(string)
-			if !ok {
-				fmt.Printf("DDP output 'ip' argument missing or invalid for chain %s.\n", chainConfig.ID)
-				return
-			}
-			ddpOutput, err := outputs.NewDDPOutput(ip, *debug, chainConfig.Output.ChannelMapping, chainConfig.Output.NumChannelsPerLamp)
-			if err != nil {
-				fmt.Printf("Error creating DDP output for chain %s: %v\n", chainConfig.ID, err)
-				return
-			}
-			output = ddpOutput
-		case "govee":
-			goveeOutput, err := outputs.NewGoveeOutput(chainConfig.Output.Govee, chainConfig.Output.ChannelMapping, chainConfig.Output.NumChannelsPerLamp)
-			if err != nil {
-				fmt.Printf("Error creating Govee output for chain %s: %v\n", chainConfig.ID, err)
-				return
-			}
-			output = goveeOutput
-		default:
-			fmt.Printf("Unknown output type: %s for chain %s.\n", chainConfig.Output.Type, chainConfig.ID)
-			return
-		}
+	// Start the beat clock before any chain ticks
+	orch.StartClock()
 
-		// Create and add the chain
-		chain := orchestrator.NewChain(chainConfig, orch, output)
-		orch.AddChain(chain)
-		chain.StartLoop()
+	// --- Build shared outputs and chains from config ---
+	orch.SetConfigPath(*configPath)
+	err = orch.Start(func(outputConfig config.OutputConfig) (orchestrator.Output, error) {
+		return createOutput(outputConfig, *debug)
+	})
+	if err != nil {
+		fmt.Printf("Error starting chains: %v\n", err)
+		return
+	}
+	if *watch {
+		orch.WatchConfig()
 	}
 
 	fmt.Printf("Checking MIDI triggers. Count: %d\n", len(cfg.Triggers))
-	// Initialize and start MIDI controller if triggers are configured
-	if len(cfg.Triggers) > 0 {
+	// Initialize and start MIDI controller if triggers, master controls, mappings, MIDI modulators or MIDI clock are configured
+	if len(cfg.Triggers) > 0 || cfg.MidiMaster != nil || len(cfg.MidiMappings) > 0 || hasMidiModulators(cfg) || cfg.Globals.ClockSource == config.ClockSourceMidi {
 		fmt.Println("MIDI triggers found. Initializing MIDI controller...")
-		midiController, err := midi.NewMidiController(orch, cfg.Triggers, cfg.MidiPortName)
+		midiController, err := midi.NewMidiController(orch, cfg.Triggers, cfg.MidiMaster, cfg.MidiMappings, cfg.MidiPortName)
 		if err != nil {
 			fmt.Printf("Error initializing MIDI controller: %v\n", err)
 			// Continue without MIDI, or exit? For now, continue.
@@ -121,8 +124,18 @@ func main() {
 		}
 	}
 
+	// Start receiving OSC for OSC modulators
+	if cfg.OSCPort != 0 {
+		oscReceiver := osc.NewReceiver(orch, cfg.OSCPort)
+		if err := oscReceiver.Start(); err != nil {
+			fmt.Printf("Error starting OSC receiver: %v\n", err)
+		} else {
+			defer oscReceiver.Stop()
+		}
+	}
+
 	// Start the web UI server
-	webui.StartWebServer(orch, cfg, *webPort)
+	webui.StartWebServer(orch, *webPort)
 
 	fmt.Println("Orchestrator running.")
 
@@ -144,3 +157,96 @@ func main() {
 	}
 }
 
+// writeConfigSchema writes the JSON Schema of the configuration file to path, or to stdout for "-".
+func writeConfigSchema(path string) error {
+	data, err := json.MarshalIndent(orchestrator.ConfigSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("JSON Schema written to %s.\n", path)
+	return nil
+}
+
+// validateConfig validates a configuration file without modifying it, prints the
+// issues and returns the exit code: 1 if there are errors, 0 otherwise.
+func validateConfig(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Error reading configuration: %v\n", err)
+		return 1
+	}
+	cfg, err := config.ParseConfig(data)
+	if err != nil {
+		fmt.Printf("Error parsing configuration: %v\n", err)
+		return 1
+	}
+
+	issues := orchestrator.ValidateConfig(cfg)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	errors, warnings := len(issues.Errors()), len(issues.Warnings())
+	fmt.Printf("%s: %d error(s), %d warning(s)\n", path, errors, warnings)
+	if errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// hasMidiModulators reports whether a configuration has modulators driven by MIDI input.
+func hasMidiModulators(cfg *config.Config) bool {
+	for _, modulator := range cfg.Modulators {
+		if modulator.Type == "midi" {
+			return true
+		}
+	}
+	return false
+}
+
+// createOutput creates the output described by an output configuration.
+func createOutput(outputConfig config.OutputConfig, debug bool) (orchestrator.Output, error) {
+	switch outputConfig.Type {
+	case "artnet":
+		ip, ok := outputConfig.Args["ip"].(string)
+		if !ok {
+			return nil, fmt.Errorf("ArtNet output 'ip' argument missing or invalid")
+		}
+		artNetOutput, err := outputs.NewArtNetOutput(ip, outputConfig.ArtNet, debug, outputConfig.ChannelMapping, outputConfig.NumChannelsPerLamp)
+		if err != nil {
+			return nil, fmt.Errorf("error creating Art-Net output: %w", err)
+		}
+		return artNetOutput, nil
+	case "ddp":
+		ip, ok := outputConfig.Args["ip"].(string)
+		if !ok {
+			return nil, fmt.Errorf("DDP output 'ip' argument missing or invalid")
+		}
+		ddpOutput, err := outputs.NewDDPOutput(ip, debug, outputConfig.ChannelMapping, outputConfig.NumChannelsPerLamp)
+		if err != nil {
+			return nil, fmt.Errorf("error creating DDP output: %w", err)
+		}
+		return ddpOutput, nil
+	case "sacn":
+		sacnOutput, err := outputs.NewSACNOutput(outputConfig.SACN, debug, outputConfig.ChannelMapping, outputConfig.NumChannelsPerLamp)
+		if err != nil {
+			return nil, fmt.Errorf("error creating sACN output: %w", err)
+		}
+		return sacnOutput, nil
+	case "govee":
+		goveeOutput, err := outputs.NewGoveeOutput(outputConfig.Govee, outputConfig.ChannelMapping, outputConfig.NumChannelsPerLamp)
+		if err != nil {
+			return nil, fmt.Errorf("error creating Govee output: %w", err)
+		}
+		return goveeOutput, nil
+	default:
+		return nil, fmt.Errorf("unknown output type: %s", outputConfig.Type)
+	}
+}
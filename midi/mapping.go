@@ -0,0 +1,214 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+
+	"godmx/config"
+	"godmx/effects"
+	"godmx/orchestrator"
+	"godmx/utils"
+)
+
+const (
+	// pickupTolerance is how close (as a fraction of the range) a control must
+	// come to the current value before a soft takeover mapping picks it up.
+	pickupTolerance = 0.02
+	// pitchBendMax is the highest absolute pitch bend value.
+	pitchBendMax = 16383
+)
+
+// mapping is a MIDI mapping with its resolved range and soft takeover state.
+type mapping struct {
+	config.MidiMappingConfig
+	min, max float64
+	isInt    bool    // The target only takes whole numbers
+	pickedUp bool    // The control has reached the current value and controls the target
+	hasLast  bool    // last is set
+	last     float64 // Last value of the control, mapped onto the range
+	sent     float64 // Last value set on the target
+}
+
+// newMapping validates a mapping config and resolves its range. The default range
+// of an effect parameter comes from the effect's metadata.
+func newMapping(orch *orchestrator.Orchestrator, cfg config.MidiMappingConfig) (*mapping, error) {
+	switch cfg.MessageType {
+	case "cc", "note_on", "pitch_bend":
+	default:
+		return nil, fmt.Errorf("invalid message_type '%s', must be cc, note_on or pitch_bend", cfg.MessageType)
+	}
+	switch cfg.Curve {
+	case "", "linear", "exponential", "logarithmic":
+	default:
+		return nil, fmt.Errorf("invalid curve '%s', must be linear, exponential or logarithmic", cfg.Curve)
+	}
+
+	m := &mapping{MidiMappingConfig: cfg}
+	var defaultMin, defaultMax interface{}
+	switch cfg.Target {
+	case "bpm":
+		defaultMin, defaultMax = 60.0, 180.0
+	case "intensity":
+		defaultMin, defaultMax = 0.0, 255.0
+		m.isInt = true
+	case "color1_hue", "color2_hue":
+		defaultMin, defaultMax = 0.0, 360.0
+	case "effect_param":
+		var effectType string
+		var findErr error
+		orch.ReadConfig(func(c *config.Config) {
+			effect, err := c.FindEffect(cfg.ChainID, cfg.EffectID)
+			if err != nil {
+				findErr = err
+				return
+			}
+			effectType = effect.Type
+		})
+		if findErr != nil {
+			return nil, findErr
+		}
+		metadata, _ := effects.GetEffectMetadata(effectType)
+		found := false
+		for _, param := range metadata.Parameters {
+			if param.InternalName == cfg.Param {
+				defaultMin, defaultMax = param.MinValue, param.MaxValue
+				m.isInt = param.DataType == "int"
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("effect '%s' has no parameter '%s'", cfg.EffectID, cfg.Param)
+		}
+	default:
+		return nil, fmt.Errorf("invalid target '%s', must be effect_param, bpm, intensity, color1_hue or color2_hue", cfg.Target)
+	}
+
+	var ok bool
+	if m.min, ok = rangeValue(cfg.Min, defaultMin); !ok {
+		return nil, fmt.Errorf("target '%s' has no default range, 'min' is required", cfg.Target)
+	}
+	if m.max, ok = rangeValue(cfg.Max, defaultMax); !ok {
+		return nil, fmt.Errorf("target '%s' has no default range, 'max' is required", cfg.Target)
+	}
+	return m, nil
+}
+
+// rangeValue returns the configured value, or the default if it isn't configured.
+func rangeValue(configured *float64, defaultValue interface{}) (float64, bool) {
+	if configured != nil {
+		return *configured, true
+	}
+	switch v := defaultValue.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// matches reports whether a MIDI message is handled by this mapping.
+func (m *mapping) matches(messageType string, number int) bool {
+	return m.MessageType == messageType && (messageType == "pitch_bend" || m.Number == number)
+}
+
+// scale maps a control position (0-1) onto the target range along the curve.
+func (m *mapping) scale(position float64) float64 {
+	switch m.Curve {
+	case "exponential":
+		position = position * position
+	case "logarithmic":
+		position = math.Sqrt(position)
+	}
+	value := m.min + (m.max-m.min)*position
+	if m.isInt {
+		value = math.Round(value)
+	}
+	return value
+}
+
+// apply sets the target to the control position (0-1). With pickup, the control
+// is ignored until it reaches or crosses the current value of the target.
+func (m *mapping) apply(orch *orchestrator.Orchestrator, position float64) error {
+	value := m.scale(position)
+
+	if m.Pickup {
+		if current, ok := m.current(orch); ok {
+			tolerance := math.Abs(m.max-m.min) * pickupTolerance
+			if m.pickedUp && math.Abs(current-m.sent) > tolerance {
+				// The target was changed by something else, pick it up again
+				m.pickedUp = false
+			}
+			if !m.pickedUp {
+				crossed := m.hasLast && (m.last-current)*(value-current) <= 0
+				m.last, m.hasLast = value, true
+				if !crossed && math.Abs(value-current) > tolerance {
+					return nil
+				}
+				m.pickedUp = true
+			}
+		}
+	}
+
+	m.sent = value
+	return m.set(orch, value)
+}
+
+// current returns the current value of the target.
+func (m *mapping) current(orch *orchestrator.Orchestrator) (float64, bool) {
+	globals := orch.GetGlobals()
+	switch m.Target {
+	case "bpm":
+		return globals.BPM, true
+	case "intensity":
+		return float64(globals.Intensity), true
+	case "color1_hue", "color2_hue":
+		color := globals.Color1
+		if m.Target == "color2_hue" {
+			color = globals.Color2
+		}
+		h, _, _ := utils.RgbToHsv(color.R, color.G, color.B)
+		return h * 360, true
+	case "effect_param":
+		value, err := orch.GetEffectParam(m.ChainID, m.EffectID, m.Param)
+		if err != nil {
+			return 0, false
+		}
+		return rangeValue(nil, value)
+	}
+	return 0, false
+}
+
+// set sets the target to value.
+func (m *mapping) set(orch *orchestrator.Orchestrator, value float64) error {
+	switch m.Target {
+	case "bpm":
+		orch.SetBPM(value)
+	case "intensity":
+		orch.SetIntensity(int(value))
+	case "color1_hue", "color2_hue":
+		globals := orch.GetGlobals()
+		color := globals.Color1
+		if m.Target == "color2_hue" {
+			color = globals.Color2
+		}
+		// Keep saturation and brightness, a grey or black color becomes fully saturated
+		_, s, v := utils.RgbToHsv(color.R, color.G, color.B)
+		if s == 0 {
+			s = 1
+		}
+		if v == 0 {
+			v = 1
+		}
+		color.R, color.G, color.B = utils.HsvToRgb(value/360, s, v)
+		if m.Target == "color2_hue" {
+			orch.SetColor2(color)
+		} else {
+			orch.SetColor1(color)
+		}
+	case "effect_param":
+		return orch.SetEffectParam(m.ChainID, m.EffectID, m.Param, value)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package midi
+
+import (
+	"math"
+	"testing"
+
+	"godmx/config"
+	"godmx/orchestrator"
+)
+
+// newMappingOrchestrator returns an orchestrator at 120 BPM with a chain holding a
+// dim and a cyberfall effect.
+func newMappingOrchestrator() *orchestrator.Orchestrator {
+	return orchestrator.NewOrchestrator(&config.Config{
+		Globals: config.GlobalsConfig{BPM: 120, Color1: "#FF0000", Color2: "#0000FF", BeatsPerBar: 4, BarsPerPhrase: 4},
+		Chains: []config.ChainConfig{{
+			ID: "main",
+			Effects: []config.EffectConfig{
+				{ID: "dim", Type: "dim", Args: map[string]interface{}{"percentage": 0.5}},
+				{ID: "rain", Type: "cyberfall", Args: map[string]interface{}{}},
+			},
+		}},
+	})
+}
+
+func float(v float64) *float64 { return &v }
+
+func TestMappingScale(t *testing.T) {
+	orch := newMappingOrchestrator()
+	tests := []struct {
+		name     string
+		config   config.MidiMappingConfig
+		position float64
+		want     float64
+	}{
+		{name: "linear bottom", config: config.MidiMappingConfig{Target: "bpm"}, position: 0, want: 60},
+		{name: "linear middle", config: config.MidiMappingConfig{Target: "bpm"}, position: 0.5, want: 120},
+		{name: "linear top", config: config.MidiMappingConfig{Target: "bpm", Curve: "linear"}, position: 1, want: 180},
+		{name: "exponential", config: config.MidiMappingConfig{Target: "bpm", Curve: "exponential"}, position: 0.5, want: 90},
+		{name: "exponential top", config: config.MidiMappingConfig{Target: "bpm", Curve: "exponential"}, position: 1, want: 180},
+		{name: "logarithmic", config: config.MidiMappingConfig{Target: "bpm", Curve: "logarithmic"}, position: 0.25, want: 120},
+		{name: "logarithmic bottom", config: config.MidiMappingConfig{Target: "bpm", Curve: "logarithmic"}, position: 0, want: 60},
+		{name: "configured range", config: config.MidiMappingConfig{Target: "bpm", Min: float(100), Max: float(140)}, position: 0.25, want: 110},
+		{name: "inverted range", config: config.MidiMappingConfig{Target: "bpm", Min: float(180), Max: float(60)}, position: 0.25, want: 150},
+		{name: "inverted exponential", config: config.MidiMappingConfig{Target: "bpm", Min: float(180), Max: float(60), Curve: "exponential"}, position: 0.5, want: 150},
+		{name: "int rounds half up", config: config.MidiMappingConfig{Target: "intensity"}, position: 0.5, want: 128},
+		{name: "int rounds down", config: config.MidiMappingConfig{Target: "intensity"}, position: 0.3, want: 77},
+		{name: "int inverted", config: config.MidiMappingConfig{Target: "intensity", Min: float(255), Max: float(0)}, position: 0.5, want: 128},
+		{name: "hue", config: config.MidiMappingConfig{Target: "color1_hue"}, position: 0.5, want: 180},
+		{name: "effect param range", config: config.MidiMappingConfig{Target: "effect_param", ChainID: "main", EffectID: "dim", Param: "percentage"}, position: 0.3, want: 0.3},
+		{name: "int effect param", config: config.MidiMappingConfig{Target: "effect_param", ChainID: "main", EffectID: "rain", Param: "max_brightness"}, position: 0.5, want: 128},
+		{name: "int effect param with range", config: config.MidiMappingConfig{Target: "effect_param", ChainID: "main", EffectID: "rain", Param: "trail_length", Max: float(10)}, position: 0.33, want: 3},
+	}
+	for _, test := range tests {
+		test.config.MessageType = "cc"
+		m, err := newMapping(orch, test.config)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := m.scale(test.position); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: scale(%v) = %v, want %v", test.name, test.position, got, test.want)
+		}
+	}
+}
+
+func TestNewMappingInvalid(t *testing.T) {
+	orch := newMappingOrchestrator()
+	tests := map[string]config.MidiMappingConfig{
+		"message type":          {MessageType: "program_change", Target: "bpm"},
+		"curve":                 {MessageType: "cc", Target: "bpm", Curve: "cubic"},
+		"target":                {MessageType: "cc", Target: "tempo"},
+		"unknown effect":        {MessageType: "cc", Target: "effect_param", ChainID: "main", EffectID: "missing", Param: "percentage"},
+		"unknown parameter":     {MessageType: "cc", Target: "effect_param", ChainID: "main", EffectID: "dim", Param: "speed"},
+		"parameter without max": {MessageType: "cc", Target: "effect_param", ChainID: "main", EffectID: "rain", Param: "speed"},
+	}
+	for name, cfg := range tests {
+		if _, err := newMapping(orch, cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMappingPickup(t *testing.T) {
+	// The BPM range is 60-180, the BPM of 120 is at position 0.5
+	tests := []struct {
+		name      string
+		positions []float64
+		want      []float64 // BPM after each position
+	}{
+		{
+			name:      "crossing from below",
+			positions: []float64{0.1, 0.3, 0.6, 0.7},
+			want:      []float64{120, 120, 132, 144},
+		},
+		{
+			name:      "crossing from above",
+			positions: []float64{0.9, 0.4, 0.3},
+			want:      []float64{120, 108, 96},
+		},
+		{
+			name:      "staying above",
+			positions: []float64{0.9, 0.8, 0.6},
+			want:      []float64{120, 120, 120},
+		},
+		{
+			name:      "first position at the current value",
+			positions: []float64{0.51, 0.6},
+			want:      []float64{121.2, 132},
+		},
+		{
+			name:      "landing on the current value",
+			positions: []float64{0.2, 0.5, 0.4},
+			want:      []float64{120, 120, 108},
+		},
+	}
+	for _, test := range tests {
+		orch := newMappingOrchestrator()
+		m, err := newMapping(orch, config.MidiMappingConfig{MessageType: "cc", Target: "bpm", Pickup: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, position := range test.positions {
+			if err := m.apply(orch, position); err != nil {
+				t.Fatal(err)
+			}
+			if got := orch.GetGlobals().BPM; math.Abs(got-test.want[i]) > 1e-9 {
+				t.Errorf("%s: BPM after position %v = %v, want %v", test.name, position, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestMappingPickupAfterExternalChange(t *testing.T) {
+	orch := newMappingOrchestrator()
+	m, err := newMapping(orch, config.MidiMappingConfig{MessageType: "cc", Target: "bpm", Pickup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, position := range []float64{0.5, 0.7} {
+		if err := m.apply(orch, position); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Tap tempo or another control moves the BPM away, the control has to pick it up again
+	orch.SetBPM(90)
+	for _, step := range []struct {
+		position float64
+		want     float64
+	}{
+		{position: 0.75, want: 90},
+		{position: 0.3, want: 90},
+		{position: 0.2, want: 84}, // Crossed 90
+		{position: 0.25, want: 90},
+	} {
+		if err := m.apply(orch, step.position); err != nil {
+			t.Fatal(err)
+		}
+		if got := orch.GetGlobals().BPM; math.Abs(got-step.want) > 1e-9 {
+			t.Errorf("BPM after position %v = %v, want %v", step.position, got, step.want)
+		}
+	}
+}
+
+func TestMappingWithoutPickup(t *testing.T) {
+	orch := newMappingOrchestrator()
+	m, err := newMapping(orch, config.MidiMappingConfig{MessageType: "cc", Target: "bpm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.apply(orch, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := orch.GetGlobals().BPM; got != 60 {
+		t.Errorf("BPM = %v, want 60 at once", got)
+	}
+}
@@ -3,6 +3,7 @@ package midi
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"godmx/orchestrator"
 	"godmx/config"
@@ -15,15 +16,29 @@ import (
 type MidiController struct {
 	orch *orchestrator.Orchestrator
 	triggers []config.MidiTriggerConfig
+	master *config.MidiMasterConfig
+	mappings []*mapping
 	stopListen func()
 	midiPortName string
+	unhandled map[midi.Type]bool // Types of unhandled messages logged already, only used by handleMessage
 }
 
-// NewMidiController creates a new MidiController.
-func NewMidiController(orch *orchestrator.Orchestrator, triggers []config.MidiTriggerConfig, midiPortName string) (*MidiController, error) {
+// NewMidiController creates a new MidiController. Invalid mappings are skipped.
+func NewMidiController(orch *orchestrator.Orchestrator, triggers []config.MidiTriggerConfig, master *config.MidiMasterConfig, mappingConfigs []config.MidiMappingConfig, midiPortName string) (*MidiController, error) {
+	var mappings []*mapping
+	for i, mappingConfig := range mappingConfigs {
+		m, err := newMapping(orch, mappingConfig)
+		if err != nil {
+			log.Printf("Skipping MIDI mapping %d: %v\n", i, err)
+			continue
+		}
+		mappings = append(mappings, m)
+	}
 	return &MidiController{
 		orch: orch,
 		triggers: triggers,
+		master: master,
+		mappings: mappings,
 		midiPortName: midiPortName,
 	},
 	nil
@@ -39,26 +54,11 @@ func (mc *MidiController) Start() error {
 	log.Printf("Found MIDI input device: %s\n", in.String())
 
 
-	// Listen for MIDI messages
+	// Listen for MIDI messages. Without UseTimeCode the driver also drops timing
+	// clock, so MIDI clock would never arrive.
 	mc.stopListen, err = midi.ListenTo(in, func(msg midi.Message, timestampms int32) {
-		var bt []byte
-		var ch, key, vel uint8
-		switch {
-		case msg.GetSysEx(&bt):
-			log.Printf("got sysex: % X\n", bt)
-		case msg.GetNoteStart(&ch, &key, &vel):
-			log.Printf("starting note %s on channel %v with velocity %v\n", midi.Note(key), ch, vel)
-			mc.matchAndTrigger("note_on", int64(key), int64(vel))
-		case msg.GetNoteEnd(&ch, &key):
-			log.Printf("ending note %s on channel %v\n", midi.Note(key), ch)
-			mc.matchAndTrigger("note_off", int64(key), 0) // Velocity is 0 for note off
-		case msg.GetControlChange(&ch, &key, &vel):
-			log.Printf("MIDI CC: Controller=%d, Value=%d\n", key, vel)
-			mc.matchAndTrigger("cc", int64(key), int64(vel))
-		default:
-			log.Printf("Unhandled MIDI event: % X\n", msg.Bytes())
-		}
-	}, midi.UseSysEx()) // UseSysEx to enable SysEx messages
+		mc.handleMessage(msg, time.Now())
+	}, midi.UseSysEx(), midi.UseTimeCode())
 
 	if err != nil {
 		return fmt.Errorf("failed to start MIDI listener: %w", err)
@@ -69,6 +69,56 @@ func (mc *MidiController) Start() error {
 	return nil
 }
 
+// handleMessage handles a MIDI message received at now.
+func (mc *MidiController) handleMessage(msg midi.Message, now time.Time) {
+	var ch, key, vel uint8
+	var bend int16
+	var absBend uint16
+	switch {
+	case msg.Is(midi.TimingClockMsg):
+		// 24 pulses per quarter note, too many to log
+		mc.orch.ClockPulse(now)
+	case msg.Is(midi.StartMsg):
+		log.Println("MIDI clock start")
+		mc.orch.ClockStart()
+	case msg.Is(midi.StopMsg):
+		log.Println("MIDI clock stop")
+		mc.orch.ClockStop()
+	case msg.Is(midi.ContinueMsg):
+		log.Println("MIDI clock continue")
+		mc.orch.ClockContinue()
+	case msg.IsOneOf(midi.MTCMsg, midi.ActiveSenseMsg, midi.SysExMsg):
+		// Time code, active sensing and SysEx arrive constantly from many devices, ignore them quietly
+	case msg.GetNoteStart(&ch, &key, &vel):
+		log.Printf("starting note %s on channel %v with velocity %v\n", midi.Note(key), ch, vel)
+		mc.handleMaster("note_on", key, vel)
+		mc.handleMappings("note_on", int(key), float64(vel)/127)
+		mc.orch.SetModulatorInput("note_on", int(key), float64(vel)/127)
+		mc.matchAndTrigger("note_on", int64(key), int64(vel))
+	case msg.GetNoteEnd(&ch, &key):
+		log.Printf("ending note %s on channel %v\n", midi.Note(key), ch)
+		mc.matchAndTrigger("note_off", int64(key), 0) // Velocity is 0 for note off
+	case msg.GetControlChange(&ch, &key, &vel):
+		log.Printf("MIDI CC: Controller=%d, Value=%d\n", key, vel)
+		mc.handleMaster("cc", key, vel)
+		mc.handleMappings("cc", int(key), float64(vel)/127)
+		mc.orch.SetModulatorInput("cc", int(key), float64(vel)/127)
+		mc.matchAndTrigger("cc", int64(key), int64(vel))
+	case msg.GetPitchBend(&ch, &bend, &absBend):
+		mc.handleMappings("pitch_bend", 0, float64(absBend)/pitchBendMax)
+		mc.orch.SetModulatorInput("pitch_bend", 0, float64(absBend)/pitchBendMax)
+	default:
+		// Log each type once, a device repeating it would flood the log
+		if !mc.unhandled[msg.Type()] {
+			if mc.unhandled == nil {
+				mc.unhandled = make(map[midi.Type]bool)
+			}
+			mc.unhandled[msg.Type()] = true
+			log.Printf("Unhandled MIDI event: % X (further %s messages are not logged)\n", msg.Bytes(), msg.Type())
+		}
+	}
+}
+
 // matchAndTrigger checks if a MIDI event matches any configured trigger and triggers the event.
 func (mc *MidiController) matchAndTrigger(messageType string, number int64, value int64) {
 	for _, trigger := range mc.triggers {
@@ -83,6 +133,36 @@ func (mc *MidiController) matchAndTrigger(messageType string, number int64, valu
 	}
 }
 
+// handleMaster applies MIDI messages bound to the grand master intensity and blackout.
+func (mc *MidiController) handleMaster(messageType string, number uint8, value uint8) {
+	if mc.master == nil {
+		return
+	}
+	switch messageType {
+	case "cc":
+		if mc.master.IntensityCC != nil && *mc.master.IntensityCC == int(number) {
+			mc.orch.SetIntensity(int(value) * 255 / 127)
+		}
+	case "note_on":
+		if mc.master.BlackoutNote != nil && *mc.master.BlackoutNote == int(number) {
+			blackout := mc.orch.ToggleBlackout()
+			log.Printf("MIDI blackout toggled: %t\n", blackout)
+		}
+	}
+}
+
+// handleMappings applies a MIDI control position (0-1) to all mappings of the control.
+func (mc *MidiController) handleMappings(messageType string, number int, position float64) {
+	for _, m := range mc.mappings {
+		if !m.matches(messageType, number) {
+			continue
+		}
+		if err := m.apply(mc.orch, position); err != nil {
+			log.Printf("MIDI mapping to '%s' failed: %v\n", m.Target, err)
+		}
+	}
+}
+
 // Stop terminates the MIDI input stream.
 func (mc *MidiController) Stop() {
 	if mc.stopListen != nil {
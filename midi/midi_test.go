@@ -0,0 +1,70 @@
+package midi
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"godmx/config"
+	"godmx/orchestrator"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+func TestTimingClockSetsBPM(t *testing.T) {
+	cfg := &config.Config{Globals: config.GlobalsConfig{
+		BPM:           120,
+		BeatsPerBar:   4,
+		BarsPerPhrase: 4,
+		ClockSource:   config.ClockSourceMidi,
+	}}
+	orch := orchestrator.NewOrchestrator(cfg)
+	mc, err := NewMidiController(orch, nil, nil, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bpm := 140.0
+	interval := time.Duration(float64(time.Minute) / bpm / 24)
+	now := time.Now()
+	mc.handleMessage(midi.Message{0xFA}, now) // Start
+	for i := 0; i < 2*24; i++ {
+		now = now.Add(interval)
+		mc.handleMessage(midi.Message{0xF8}, now)
+	}
+
+	globals := orch.GetGlobals()
+	if math.Abs(globals.BPM-bpm) > 0.1 {
+		t.Errorf("BPM = %v, want %v", globals.BPM, bpm)
+	}
+	// The first pulse after Start is the downbeat, 47 pulses later is almost 2 beats on
+	if want := 47.0 / 24; math.Abs(globals.BeatTime-want) > 1e-9 {
+		t.Errorf("BeatTime = %v, want %v", globals.BeatTime, want)
+	}
+}
+
+func TestUnhandledMessagesAreLoggedOnce(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	orch := orchestrator.NewOrchestrator(&config.Config{Globals: config.GlobalsConfig{BPM: 120, BeatsPerBar: 4, BarsPerPhrase: 4}})
+	mc, err := NewMidiController(orch, nil, nil, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := 0; i < 8; i++ {
+		mc.handleMessage(midi.Message{0xF1, byte(i << 4)}, now)                 // MTC quarter frame
+		mc.handleMessage(midi.Message{0xFE}, now)                               // Active sensing
+		mc.handleMessage(midi.Message{0xF0, 0x7E, 0x7F, 0x06, 0x01, 0xF7}, now) // SysEx
+		mc.handleMessage(midi.Message{0xC0, byte(i)}, now)                      // Program change
+	}
+	if got := strings.Count(logged.String(), "\n"); got != 1 || !strings.Contains(logged.String(), "C0 00") {
+		t.Errorf("expected the first program change to be logged only, got:\n%s", logged.String())
+	}
+}
@@ -5,7 +5,7 @@ type ActionParameter struct {
 	InternalName string      `json:"internal_name"`
 	DisplayName  string      `json:"display_name"`
 	Description  string      `json:"description"`
-	DataType     string      `json:"data_type"` // e.g., "string", "float64", "int", "bool"
+	DataType     string      `json:"data_type"` // e.g., "string", "float64", "int", "bool", "color", "any"
 	DefaultValue interface{} `json:"default_value,omitempty"`
 	Options      []string    `json:"options,omitempty"` // For enum-like parameters
 }
@@ -44,14 +44,126 @@ var ActionSchemas = map[string]ActionSchema{
 			{InternalName: "enabled", DisplayName: "Enabled", Description: "Whether the effect should be enabled or disabled.", DataType: "bool", DefaultValue: true},
 		},
 	},
+	"set_effect_param": {
+		HumanReadableName: "Set Effect Parameter",
+		Description:       "Changes a single arg of an effect in a chain while it keeps running.",
+		Parameters: []ActionParameter{
+			{InternalName: "chain_id", DisplayName: "Chain ID", Description: "The ID of the chain containing the effect.", DataType: "string"},
+			{InternalName: "effect_id", DisplayName: "Effect ID", Description: "The ID of the effect to change.", DataType: "string"},
+			{InternalName: "param", DisplayName: "Parameter", Description: "The name of the arg to change, e.g. percentage.", DataType: "string"},
+			{InternalName: "value", DisplayName: "Value", Description: "The new value of the arg as JSON, e.g. 0.3, true or \"#FF8000\".", DataType: "any"},
+		},
+	},
+	"move_effect": {
+		HumanReadableName: "Move Effect",
+		Description:       "Moves an effect to another position in its chain, changing the order effects are rendered and blended in.",
+		Parameters: []ActionParameter{
+			{InternalName: "chain_id", DisplayName: "Chain ID", Description: "The ID of the chain containing the effect.", DataType: "string"},
+			{InternalName: "effect_id", DisplayName: "Effect ID", Description: "The ID of the effect to move.", DataType: "string"},
+			{InternalName: "position", DisplayName: "Position", Description: "The new index of the effect, 0 for the first. Indexes past the end move it to the end.", DataType: "int", DefaultValue: 0},
+		},
+	},
+	"toggle_chain": {
+		HumanReadableName: "Toggle Chain",
+		Description:       "Enables or disables a whole chain. A disabled chain outputs black.",
+		Parameters: []ActionParameter{
+			{InternalName: "chain_id", DisplayName: "Chain ID", Description: "The ID of the chain to toggle.", DataType: "string"},
+			{InternalName: "enabled", DisplayName: "Enabled", Description: "Whether the chain should be enabled or disabled.", DataType: "bool", DefaultValue: true},
+		},
+	},
+	"set_chain_tickrate": {
+		HumanReadableName: "Set Chain Tick Rate",
+		Description:       "Changes how many frames per second a chain renders and sends.",
+		Parameters: []ActionParameter{
+			{InternalName: "chain_id", DisplayName: "Chain ID", Description: "The ID of the chain.", DataType: "string"},
+			{InternalName: "tick_rate", DisplayName: "Tick Rate", Description: "Frames per second.", DataType: "int", DefaultValue: 60},
+		},
+	},
 	"set_global": {
 		HumanReadableName: "Set Global Parameter",
-		Description:       "Sets a global parameter (like BPM, Color1, Color2, Intensity).",
+		Description:       "Sets a global parameter (like BPM, Color1, Color2, Intensity, Blackout).",
 		Parameters: []ActionParameter{
 			{InternalName: "bpm", DisplayName: "BPM", Description: "Global Beats Per Minute.", DataType: "float64", DefaultValue: 120.0},
-			{InternalName: "intensity", DisplayName: "Intensity", Description: "Global intensity (0-255).", DataType: "int", DefaultValue: 255},
-			{InternalName: "color1", DisplayName: "Color 1", Description: "Global Color 1 (hex string, e.g., #FF0000).", DataType: "string", DefaultValue: "#FF0000"},
-			{InternalName: "color2", DisplayName: "Color 2", Description: "Global Color 2 (hex string, e.g., #0000FF).", DataType: "string", DefaultValue: "#0000FF"},
+			{InternalName: "intensity", DisplayName: "Intensity", Description: "Grand master intensity (0-255), applied after all effects.", DataType: "int", DefaultValue: 255},
+			{InternalName: "blackout", DisplayName: "Blackout", Description: "Forces all outputs to black while enabled.", DataType: "bool", DefaultValue: false},
+			{InternalName: "clock_source", DisplayName: "Clock Source", Description: "What drives the beat, the internal clock or MIDI clock.", DataType: "string", DefaultValue: "internal", Options: []string{"internal", "midi"}},
+			{InternalName: "color1", DisplayName: "Color 1", Description: "Global Color 1 (hex string, e.g., #FF0000).", DataType: "color", DefaultValue: "#FF0000"},
+			{InternalName: "color2", DisplayName: "Color 2", Description: "Global Color 2 (hex string, e.g., #0000FF).", DataType: "color", DefaultValue: "#0000FF"},
+		},
+	},
+	"tap_tempo": {
+		HumanReadableName: "Tap Tempo",
+		Description:       "Registers a tap on the beat. After a few taps the BPM follows the tapped tempo and the beat phase snaps to every tap.",
+		Parameters:        []ActionParameter{},
+	},
+	"resync_beat": {
+		HumanReadableName: "Resync Beat",
+		Description:       "Moves the beat phase so the current moment is the start of a beat, bar (downbeat) or phrase.",
+		Parameters: []ActionParameter{
+			{InternalName: "align", DisplayName: "Align To", Description: "What the current moment becomes the start of.", DataType: "string", DefaultValue: "bar", Options: []string{"beat", "bar", "phrase"}},
+		},
+	},
+	"trigger_event": {
+		HumanReadableName: "Trigger Event",
+		Description:       "Triggers another event, to reuse its actions.",
+		Parameters: []ActionParameter{
+			{InternalName: "event", DisplayName: "Event", Description: "The name of the event to trigger.", DataType: "string"},
+		},
+	},
+	"random_event": {
+		HumanReadableName: "Random Event",
+		Description:       "Triggers one event picked at random from a list.",
+		Parameters: []ActionParameter{
+			{InternalName: "events", DisplayName: "Events", Description: "The names of the events to pick from.", DataType: "list"},
+		},
+	},
+	"wait": {
+		HumanReadableName: "Wait",
+		Description:       "Holds back the following actions of the event by its delay and quantization, to build sequences.",
+		Parameters:        []ActionParameter{},
+	},
+	"save_scene": {
+		HumanReadableName: "Save Scene",
+		Description:       "Saves the current look, the effects of all chains and the globals, as a named scene in the config.",
+		Parameters: []ActionParameter{
+			{InternalName: "name", DisplayName: "Scene", Description: "The name to save the scene as. An existing scene with this name is replaced.", DataType: "string"},
+		},
+	},
+	"recall_scene": {
+		HumanReadableName: "Recall Scene",
+		Description:       "Restores the effects and globals of a saved scene, optionally crossfading to it.",
+		Parameters: []ActionParameter{
+			{InternalName: "name", DisplayName: "Scene", Description: "The name of the scene to recall.", DataType: "string"},
+			{InternalName: "fade", DisplayName: "Fade Time", Description: "Seconds the old and new look are crossfaded, 0 to switch at once.", DataType: "float64", DefaultValue: 0.0},
+		},
+	},
+	"cue_go": {
+		HumanReadableName: "Cue GO",
+		Description:       "Fires the next cue of a cue list, the first one if the list isn't running.",
+		Parameters: []ActionParameter{
+			{InternalName: "cue_list", DisplayName: "Cue List", Description: "The name of the cue list.", DataType: "string"},
+		},
+	},
+	"cue_back": {
+		HumanReadableName: "Cue BACK",
+		Description:       "Fires the cue before the current one of a cue list.",
+		Parameters: []ActionParameter{
+			{InternalName: "cue_list", DisplayName: "Cue List", Description: "The name of the cue list.", DataType: "string"},
+		},
+	},
+	"cue_jump": {
+		HumanReadableName: "Jump to Cue",
+		Description:       "Fires a cue of a cue list by its name.",
+		Parameters: []ActionParameter{
+			{InternalName: "cue_list", DisplayName: "Cue List", Description: "The name of the cue list.", DataType: "string"},
+			{InternalName: "cue", DisplayName: "Cue", Description: "The name of the cue to fire.", DataType: "string"},
+		},
+	},
+	"cue_stop": {
+		HumanReadableName: "Cue Stop",
+		Description:       "Stops a cue list. The look stays, the next GO starts over with the first cue.",
+		Parameters: []ActionParameter{
+			{InternalName: "cue_list", DisplayName: "Cue List", Description: "The name of the cue list.", DataType: "string"},
 		},
 	},
 }
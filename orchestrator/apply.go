@@ -0,0 +1,254 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/utils"
+	"reflect"
+)
+
+// OutputFactory creates the output for an output config.
+type OutputFactory func(outputConfig config.OutputConfig) (Output, error)
+
+// chainPlan describes how a chain of a new configuration is brought up.
+type chainPlan struct {
+	config         *config.ChainConfig
+	chain          *Chain // Running chain to keep, nil to create a new one
+	outputChanged  bool   // The chain needs a new output
+	effectsChanged bool   // The chain's effects need a rebuild
+	output         Output
+}
+
+// Start creates the shared outputs and chains of the configuration and starts the
+// chains. factory creates all outputs, also those of configurations applied later.
+func (o *Orchestrator) Start(factory OutputFactory) error {
+	o.applyMutex.Lock()
+	o.factory = factory
+	o.applyMutex.Unlock()
+
+	o.configMutex.Lock()
+	cfg := o.config
+	o.configMutex.Unlock()
+	return o.applyConfig(cfg, nil)
+}
+
+// ApplyConfig replaces the running configuration. Chains that didn't change keep
+// running untouched, chains whose effects changed rebuild them (keeping the state of
+// effects with the same ID), outputs whose settings changed are recreated and chains
+// are added or removed. If the configuration is invalid or an output can't be
+// created, the running configuration is kept.
+func (o *Orchestrator) ApplyConfig(newCfg *config.Config) error {
+	return o.applyConfig(newCfg, nil)
+}
+
+// applyConfig is ApplyConfig, chains whose effects changed crossfade to them if fade
+// is set.
+func (o *Orchestrator) applyConfig(newCfg *config.Config, fade *sceneFade) error {
+	issues := ValidateConfig(newCfg)
+	if err := issues.Err(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	for _, warning := range issues.Warnings() {
+		fmt.Printf("Config %s\n", warning)
+	}
+
+	o.applyMutex.Lock()
+	defer o.applyMutex.Unlock()
+	if o.factory == nil {
+		return fmt.Errorf("orchestrator not started")
+	}
+
+	// Plan the changes against the running configuration
+	o.configMutex.Lock()
+	oldCfg := o.config
+	oldGlobals := oldCfg.Globals
+	midiChanged := !midiSettingsEqual(oldCfg, newCfg)
+	running := make(map[string]*Chain)
+	for _, chain := range o.chains {
+		running[chain.ID] = chain
+	}
+	oldShared := make(map[string]config.SharedOutputConfig)
+	for _, shared := range oldCfg.SharedOutputs {
+		oldShared[shared.ID] = shared
+	}
+
+	mergers := make(map[string]*OutputMerger)
+	var sharedToCreate []config.SharedOutputConfig
+	for _, shared := range newCfg.SharedOutputs {
+		if old, ok := oldShared[shared.ID]; ok && o.mergers[shared.ID] != nil && reflect.DeepEqual(old, shared) {
+			mergers[shared.ID] = o.mergers[shared.ID]
+		} else {
+			sharedToCreate = append(sharedToCreate, shared)
+		}
+	}
+
+	plans := make([]chainPlan, len(newCfg.Chains))
+	for i := range newCfg.Chains {
+		chainCfg := &newCfg.Chains[i]
+		plan := &plans[i]
+		plan.config = chainCfg
+
+		// The lamp count is fixed for a running chain, it is replaced if it changes
+		chain := running[chainCfg.ID]
+		if chain == nil || len(chain.lamps) != chainCfg.NumLamps {
+			plan.outputChanged = true
+			continue
+		}
+		old := chain.config
+		plan.chain = chain
+		plan.effectsChanged = !reflect.DeepEqual(old.Effects, chainCfg.Effects) || !reflect.DeepEqual(old.Segments, chainCfg.Segments)
+		if chainCfg.OutputID == "" {
+			plan.outputChanged = old.OutputID != "" || !reflect.DeepEqual(old.Output, chainCfg.Output)
+		} else {
+			// Priorities are part of the merger input
+			plan.outputChanged = old.OutputID != chainCfg.OutputID || old.Priority != chainCfg.Priority || mergers[chainCfg.OutputID] == nil
+		}
+	}
+	o.configMutex.Unlock()
+
+	// Create the new outputs before touching anything, so a failing output keeps the old show running
+	var created []Output
+	var newMergers []*OutputMerger
+	fail := func(err error) error {
+		for _, output := range created {
+			output.Close()
+		}
+		for _, merger := range newMergers {
+			merger.closeIfUnused()
+		}
+		return err
+	}
+	for _, shared := range sharedToCreate {
+		output, err := o.factory(shared.Output)
+		if err != nil {
+			return fail(fmt.Errorf("error creating shared output %s: %w", shared.ID, err))
+		}
+		merger, err := NewOutputMerger(shared.ID, output, shared.MergeMode)
+		if err != nil {
+			output.Close()
+			return fail(fmt.Errorf("error creating shared output %s: %w", shared.ID, err))
+		}
+		mergers[shared.ID] = merger
+		newMergers = append(newMergers, merger)
+	}
+	for i := range plans {
+		plan := &plans[i]
+		if !plan.outputChanged {
+			continue
+		}
+		if plan.config.OutputID != "" {
+			plan.output = mergers[plan.config.OutputID].Input(plan.config.ID, plan.config.Priority)
+		} else {
+			output, err := o.factory(plan.config.Output)
+			if err != nil {
+				return fail(fmt.Errorf("error creating output for chain %s: %w", plan.config.ID, err))
+			}
+			plan.output = output
+		}
+		created = append(created, plan.output)
+	}
+
+	// Switch over to the new configuration
+	o.configMutex.Lock()
+	oldMergers := o.mergers
+	o.config = newCfg
+	o.mergers = mergers
+	kept := make(map[*Chain]bool)
+	var chains, started []*Chain
+	for _, plan := range plans {
+		if plan.chain != nil {
+			kept[plan.chain] = true
+			chains = append(chains, plan.chain)
+			continue
+		}
+		chain := NewChain(plan.config, o, plan.output)
+		chains = append(chains, chain)
+		started = append(started, chain)
+	}
+	o.chains = chains
+	o.configMutex.Unlock()
+	o.modulators.update(newCfg.Modulators)
+	o.cues.update(newCfg.CueLists)
+
+	updated := 0
+	for _, plan := range plans {
+		if plan.chain == nil {
+			continue
+		}
+		if plan.outputChanged || plan.effectsChanged {
+			updated++
+		}
+		plan.chain.reconfigure(plan.config, resolveOutputConfig(newCfg, plan.config), plan.output, plan.effectsChanged, fade)
+	}
+	stopped := 0
+	for _, chain := range running {
+		if !kept[chain] {
+			chain.Stop()
+			stopped++
+		}
+	}
+	for _, chain := range started {
+		chain.StartLoop()
+	}
+	for id, merger := range oldMergers {
+		if mergers[id] != merger {
+			merger.closeIfUnused()
+		}
+	}
+
+	if oldCfg != newCfg {
+		o.applyGlobals(oldGlobals, newCfg.Globals)
+	}
+	fmt.Printf("Config applied: %d chain(s) started, %d updated, %d stopped\n", len(started), updated, stopped)
+	if oldCfg.OSCPort != newCfg.OSCPort {
+		fmt.Println("OSC port changed, it takes effect after a restart.")
+	}
+	if midiChanged {
+		fmt.Println("MIDI settings changed, they take effect after a restart.")
+	}
+	return nil
+}
+
+// midiSettingsEqual reports whether two configurations have the same MIDI settings.
+func midiSettingsEqual(a, b *config.Config) bool {
+	return a.MidiPortName == b.MidiPortName &&
+		reflect.DeepEqual(a.Triggers, b.Triggers) &&
+		reflect.DeepEqual(a.MidiMaster, b.MidiMaster) &&
+		reflect.DeepEqual(a.MidiMappings, b.MidiMappings)
+}
+
+// applyGlobals applies the globals that differ between two configurations.
+func (o *Orchestrator) applyGlobals(old, new config.GlobalsConfig) {
+	if old.BPM != new.BPM {
+		o.SetBPM(new.BPM)
+	}
+	if old.Color1 != new.Color1 {
+		if color, err := utils.ParseHexColor(new.Color1); err == nil {
+			o.SetColor1(color)
+		}
+	}
+	if old.Color2 != new.Color2 {
+		if color, err := utils.ParseHexColor(new.Color2); err == nil {
+			o.SetColor2(color)
+		}
+	}
+	if new.Intensity != nil && (old.Intensity == nil || *old.Intensity != *new.Intensity) {
+		o.SetIntensity(*new.Intensity)
+	}
+	if old.BeatsPerBar != new.BeatsPerBar || old.BarsPerPhrase != new.BarsPerPhrase {
+		o.SetTimeSignature(new.BeatsPerBar, new.BarsPerPhrase)
+	}
+	if old.ClockSource != new.ClockSource {
+		o.SetClockSource(new.ClockSource)
+	}
+}
+
+// resolveOutputConfig returns the output config a chain sends with, its own or the shared output's.
+func resolveOutputConfig(cfg *config.Config, chainCfg *config.ChainConfig) *config.OutputConfig {
+	if chainCfg.OutputID != "" {
+		if shared, err := cfg.FindSharedOutput(chainCfg.OutputID); err == nil {
+			return &shared.Output
+		}
+	}
+	return &chainCfg.Output
+}
@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/dmx"
+	"godmx/effects"
+	"godmx/types"
+)
+
+// blendFunc combines a channel of the frame with the channel of an effect's output.
+type blendFunc func(frame, effect uint8) uint8
+
+// blendFuncs are the blend modes of effects.
+var blendFuncs = map[string]blendFunc{
+	"normal":   func(frame, effect uint8) uint8 { return effect },
+	"add":      func(frame, effect uint8) uint8 { return uint8(min(255, int(frame)+int(effect))) },
+	"multiply": func(frame, effect uint8) uint8 { return uint8(int(frame) * int(effect) / 255) },
+	"screen":   func(frame, effect uint8) uint8 { return uint8(255 - (255-int(frame))*(255-int(effect))/255) },
+	"max":      func(frame, effect uint8) uint8 { return max(frame, effect) },
+	"min":      func(frame, effect uint8) uint8 { return min(frame, effect) },
+	"subtract": func(frame, effect uint8) uint8 { return uint8(max(0, int(frame)-int(effect))) },
+}
+
+// effectLayer composites an effect's output onto the chain's frame. Effects without
+// a blend mode or opacity work on the frame in place.
+type effectLayer struct {
+	blend   blendFunc // nil to process in place
+	opacity float64
+	// Color sources render onto black, so only their own colors are blended. With
+	// normal blending they render onto the frame, which keeps lamps they don't set.
+	onBlack bool
+}
+
+// newEffectLayer returns the layer of an effect from its blend mode and opacity.
+func newEffectLayer(effectCfg *config.EffectConfig) (effectLayer, error) {
+	mode := effectCfg.Blend
+	if mode == "" {
+		mode = "normal"
+	}
+	blend, ok := blendFuncs[mode]
+	if !ok {
+		return effectLayer{}, fmt.Errorf("effect '%s': unknown blend mode '%s'", effectCfg.ID, mode)
+	}
+	opacity := 1.0
+	if effectCfg.Opacity != nil {
+		opacity = max(0, min(1, *effectCfg.Opacity))
+	}
+	if mode == "normal" && opacity == 1 {
+		return effectLayer{}, nil
+	}
+
+	colorSource := false
+	if tags, ok := effects.GetEffectTags(effectCfg.Type); ok {
+		for _, tag := range tags {
+			colorSource = colorSource || tag == "color_source"
+		}
+	}
+	return effectLayer{blend: blend, opacity: opacity, onBlack: colorSource && mode != "normal"}, nil
+}
+
+// process runs an effect on lamps. A blended effect renders into buffer, which is
+// then composited onto lamps. buffer is reused between calls.
+func (l *effectLayer) process(effect types.Effect, lamps []dmx.Lamp, buffer *[]dmx.Lamp, globals *types.OrchestratorGlobals, channelMapping string, numChannelsPerLamp int) {
+	if l.blend == nil {
+		effect.Process(lamps, globals, channelMapping, numChannelsPerLamp)
+		return
+	}
+	if cap(*buffer) < len(lamps) {
+		*buffer = make([]dmx.Lamp, len(lamps))
+	}
+	layer := (*buffer)[:len(lamps)]
+	if l.onBlack {
+		clear(layer)
+	} else {
+		copy(layer, lamps)
+	}
+	effect.Process(layer, globals, channelMapping, numChannelsPerLamp)
+
+	for i := range lamps {
+		lamps[i] = dmx.Lamp{
+			R: l.composite(lamps[i].R, layer[i].R),
+			G: l.composite(lamps[i].G, layer[i].G),
+			B: l.composite(lamps[i].B, layer[i].B),
+			W: l.composite(lamps[i].W, layer[i].W),
+		}
+	}
+}
+
+// composite blends a channel and mixes the result with the frame by the opacity.
+func (l *effectLayer) composite(frame, effect uint8) uint8 {
+	blended := l.blend(frame, effect)
+	return uint8(float64(frame) + (float64(blended)-float64(frame))*l.opacity + 0.5)
+}
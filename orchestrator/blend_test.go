@@ -0,0 +1,125 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"godmx/dmx"
+	"godmx/types"
+	"testing"
+)
+
+func TestBlendFuncs(t *testing.T) {
+	tests := []struct {
+		mode          string
+		frame, effect uint8
+		want          uint8
+	}{
+		{"normal", 100, 50, 50},
+		{"normal", 100, 0, 0},
+		{"add", 100, 50, 150},
+		{"add", 200, 100, 255},
+		{"add", 255, 255, 255},
+		{"multiply", 255, 128, 128},
+		{"multiply", 128, 128, 64},
+		{"multiply", 200, 0, 0},
+		{"screen", 0, 128, 128},
+		{"screen", 128, 128, 192},
+		{"screen", 255, 10, 255},
+		{"max", 100, 50, 100},
+		{"max", 50, 100, 100},
+		{"min", 100, 50, 50},
+		{"min", 50, 100, 50},
+		{"subtract", 100, 30, 70},
+		{"subtract", 30, 100, 0},
+		{"subtract", 255, 255, 0},
+	}
+	for _, test := range tests {
+		if got := blendFuncs[test.mode](test.frame, test.effect); got != test.want {
+			t.Errorf("%s(%d, %d) = %d, want %d", test.mode, test.frame, test.effect, got, test.want)
+		}
+	}
+}
+
+// fillEffect sets all lamps to a color.
+type fillEffect dmx.Lamp
+
+func (f fillEffect) Process(lamps []dmx.Lamp, globals *types.OrchestratorGlobals, channelMapping string, numChannelsPerLamp int) {
+	for i := range lamps {
+		lamps[i] = dmx.Lamp(f)
+	}
+}
+
+func TestEffectLayerComposite(t *testing.T) {
+	opacity := func(o float64) *float64 { return &o }
+	tests := []struct {
+		name   string
+		effect config.EffectConfig
+		frame  dmx.Lamp
+		fill   dmx.Lamp
+		want   dmx.Lamp
+	}{
+		{
+			name:   "normal in place",
+			effect: config.EffectConfig{Type: "dim"},
+			frame:  dmx.Lamp{R: 200, G: 100},
+			fill:   dmx.Lamp{B: 50},
+			want:   dmx.Lamp{B: 50},
+		},
+		{
+			name:   "normal at half opacity",
+			effect: config.EffectConfig{Type: "dim", Opacity: opacity(0.5)},
+			frame:  dmx.Lamp{R: 200, G: 100},
+			fill:   dmx.Lamp{B: 50},
+			want:   dmx.Lamp{R: 100, G: 50, B: 25},
+		},
+		{
+			name:   "add",
+			effect: config.EffectConfig{Type: "solidColor", Blend: "add"},
+			frame:  dmx.Lamp{R: 200, G: 100},
+			fill:   dmx.Lamp{R: 100, B: 50},
+			want:   dmx.Lamp{R: 255, G: 100, B: 50},
+		},
+		{
+			name:   "multiply at quarter opacity",
+			effect: config.EffectConfig{Type: "solidColor", Blend: "multiply", Opacity: opacity(0.25)},
+			frame:  dmx.Lamp{R: 200, G: 100},
+			fill:   dmx.Lamp{R: 0, G: 255},
+			want:   dmx.Lamp{R: 150, G: 100},
+		},
+		{
+			name:   "opacity clamped to 1",
+			effect: config.EffectConfig{Type: "solidColor", Blend: "subtract", Opacity: opacity(2)},
+			frame:  dmx.Lamp{R: 200, G: 100},
+			fill:   dmx.Lamp{R: 50, G: 150},
+			want:   dmx.Lamp{R: 150},
+		},
+		{
+			name:   "opacity 0 keeps the frame",
+			effect: config.EffectConfig{Type: "solidColor", Blend: "screen", Opacity: opacity(0)},
+			frame:  dmx.Lamp{R: 200, G: 100},
+			fill:   dmx.Lamp{R: 255, G: 255, B: 255},
+			want:   dmx.Lamp{R: 200, G: 100},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layer, err := newEffectLayer(&test.effect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lamps := []dmx.Lamp{test.frame, test.frame}
+			var buffer []dmx.Lamp
+			layer.process(fillEffect(test.fill), lamps, &buffer, &types.OrchestratorGlobals{}, "RGB", 3)
+			for i, got := range lamps {
+				if got != test.want {
+					t.Errorf("lamp %d = %v, want %v", i, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEffectLayerUnknownBlend(t *testing.T) {
+	if _, err := newEffectLayer(&config.EffectConfig{ID: "x", Type: "dim", Blend: "overlay"}); err == nil {
+		t.Error("expected an error for an unknown blend mode")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"godmx/effects"
 	
 	"godmx/types"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -17,14 +18,37 @@ type Chain struct {
 	Priority     int
 	TickRate     int // FPS
 	Effects      []types.Effect
+	instances    []effectInstance // Config each instance in Effects was created from
 	Output       Output
 	lamps        []dmx.Lamp // Internal frame buffer for this chain
+	outputLamps  []dmx.Lamp // Frame buffer with the grand master applied, sent to the output
+	viewLamps    []dmx.Lamp // Buffer for effects working on lamps that aren't consecutive
+	layerLamps   []dmx.Lamp // Buffer blended effects render into
 	orchestrator *Orchestrator // Reference to the parent orchestrator
 	config       *config.ChainConfig
+	outputConfig *config.OutputConfig // The chain's own output or the shared output it sends to
 	isDirty      bool
+	pendingArgs  map[string]map[string]interface{} // New args per effect ID, applied on the next tick
+	nextOutput   Output // Replaces Output on the next tick
+	pendingFade  *sceneFade // Crossfade to start with the next rebuild
+	crossfade    *crossfade // Running crossfade from the effects before a scene recall, nil if there is none
+	enabled      bool          // A disabled chain sends black
+	tickRateSet  chan struct{} // Signals the loop that TickRate changed
+	stop         chan struct{}
+	stopOnce     sync.Once
 	mutex        sync.Mutex
 }
 
+// effectInstance is the config an effect instance was created from.
+type effectInstance struct {
+	id         string
+	effectType string
+	args       map[string]interface{} // Copy of the config args
+	view       lampView               // Lamps the effect works on
+	layer      effectLayer            // How the effect's output is combined with the frame
+	modulation *effectModulation      // Args bound to modulators, nil if there are none
+}
+
 // NewChain creates a new Chain instance.
 func NewChain(cfg *config.ChainConfig, orch *Orchestrator, output Output) *Chain {
 	c := &Chain{
@@ -36,7 +60,11 @@ func NewChain(cfg *config.ChainConfig, orch *Orchestrator, output Output) *Chain
 		config:       cfg,
 		Output:       output,
 		isDirty:      true, // Start dirty to force initial build
+		enabled:      cfg.Enabled == nil || *cfg.Enabled,
+		tickRateSet:  make(chan struct{}, 1),
+		stop:         make(chan struct{}),
 	}
+	c.outputConfig = resolveOutputConfig(orch.config, cfg)
 	return c
 }
 
@@ -47,35 +75,39 @@ func (c *Chain) SetDirty(dirty bool) {
 	c.isDirty = dirty
 }
 
-// rebuildEffectsFromConfig clears the current effects and rebuilds them from the config.
+// rebuildEffectsFromConfig rebuilds the effects from the config. The new effects
+// replace the current ones only if all of them could be created, otherwise the chain
+// keeps running with its current effects.
 func (c *Chain) rebuildEffectsFromConfig() error {
 	fmt.Printf("Rebuilding effects for chain '%s'...\n", c.ID)
-	c.Effects = []types.Effect{}
+
+	// Keep the running instances by ID, so unchanged effects keep their state
+	previous := make(map[string]int)
+	for i, instance := range c.instances {
+		if instance.id != "" {
+			previous[instance.id] = i
+		}
+	}
+	previousEffects, previousInstances := c.Effects, c.instances
+	fade := c.pendingFade
+	c.pendingFade = nil
+	if fade != nil {
+		// The outgoing effects keep running during the fade, so none of them is reused
+		previous = nil
+	}
+	newEffects := []types.Effect{}
+	var newInstances []effectInstance
+	var updates []effectUpdate // Args of reused effects, applied if the rebuild succeeds
+
+	// The chain config is shared with the orchestrator, which modifies it from actions
+	c.orchestrator.configMutex.Lock()
+	defer c.orchestrator.configMutex.Unlock()
 
 	activeGroups := make(map[string]bool) // To track which groups already have an active effect
 
 	for i := range c.config.Effects {
 		effectConfig := &c.config.Effects[i] // Get a pointer to modify the original config
 
-		// Get effect metadata for parameter augmentation
-		metadata, ok := effects.GetEffectMetadata(effectConfig.Type)
-		if !ok {
-			return fmt.Errorf("unknown effect type: %s", effectConfig.Type)
-		}
-
-		// Augment effectConfig.Args with default values from metadata
-		augmentedArgs := make(map[string]interface{})
-		// Copy existing args first
-		for k, v := range effectConfig.Args {
-			augmentedArgs[k] = v
-		}
-
-		for _, param := range metadata.Parameters {
-			if _, exists := augmentedArgs[param.InternalName]; !exists {
-				augmentedArgs[param.InternalName] = param.DefaultValue
-			}
-		}
-
 		// Apply group rules: only one effect per group can be enabled
 		if effectConfig.Group != "" {
 			if activeGroups[effectConfig.Group] {
@@ -95,29 +127,195 @@ func (c *Chain) rebuildEffectsFromConfig() error {
 
 		// Only create the effect instance if it's enabled after group rules
 		if effectConfig.Enabled == nil || *effectConfig.Enabled {
-			constructor, ok := effects.GetEffectConstructor(effectConfig.Type)
-			if !ok {
-				return fmt.Errorf("unknown effect type: %s", effectConfig.Type)
+			view, err := newLampView(c.config, effectConfig)
+			if err != nil {
+				return err
 			}
-
-			effect, err := constructor(augmentedArgs)
+			layer, err := newEffectLayer(effectConfig)
 			if err != nil {
-				return fmt.Errorf("error creating effect '%s': %w", effectConfig.Type, err)
+				return err
+			}
+			instance := effectInstance{id: effectConfig.ID, effectType: effectConfig.Type, args: copyArgs(effectConfig.Args), view: view, layer: layer}
+			instance.modulation = newEffectModulation(effectConfig.Type, instance.args)
+			var effect types.Effect
+			if i, ok := previous[effectConfig.ID]; ok && previousInstances[i].effectType == effectConfig.Type {
+				delete(previous, effectConfig.ID) // Never share an instance between duplicate IDs
+				effect = previousEffects[i]
+				if setter, ok := effect.(types.ParameterSetter); ok {
+					// Changed in place only once all effects were created, see below
+					changed, err := changedArgs(previousInstances[i], instance.args)
+					if err != nil {
+						return err
+					}
+					if len(changed) > 0 {
+						updates = append(updates, effectUpdate{setter: setter, effectType: effectConfig.Type, args: changed})
+					}
+				} else if err := updateEffect(&effect, previousInstances[i], instance.args); err != nil {
+					return err
+				}
+			} else {
+				var err error
+				if effect, err = newEffect(effectConfig.Type, effectConfig.Args); err != nil {
+					return err
+				}
 			}
-			c.Effects = append(c.Effects, effect)
+			if _, ok := effect.(types.ParameterSetter); !ok && instance.modulation != nil {
+				fmt.Printf("  - Effect '%s' can't change its args while running, its modulators are ignored\n", effectConfig.ID)
+			}
+			newEffects = append(newEffects, effect)
+			newInstances = append(newInstances, instance)
+		}
+	}
+	for _, update := range updates {
+		if err := update.setter.SetParameters(update.args); err != nil {
+			return fmt.Errorf("error updating effect '%s': %w", update.effectType, err)
+		}
+	}
+	if fade != nil {
+		c.crossfade = newCrossfade(fade, previousEffects, previousInstances, c.lamps, c.crossfade, time.Now())
+	}
+	c.Effects, c.instances = newEffects, newInstances
+	c.pendingArgs = nil // The config already has the latest args
+	return nil
+}
+
+// newEffect creates an effect instance from its args.
+func newEffect(effectType string, args map[string]interface{}) (types.Effect, error) {
+	augmentedArgs, err := augmentArgs(effectType, args)
+	if err != nil {
+		return nil, err
+	}
+
+	constructor, ok := effects.GetEffectConstructor(effectType)
+	if !ok {
+		return nil, fmt.Errorf("unknown effect type: %s", effectType)
+	}
+
+	effect, err := constructor(augmentedArgs)
+	if err != nil {
+		return nil, fmt.Errorf("error creating effect '%s': %w", effectType, err)
+	}
+	return effect, nil
+}
+
+// augmentArgs returns a copy of args with default values from the metadata for missing
+// args and args bound to modulators, whose values are set on every tick.
+func augmentArgs(effectType string, args map[string]interface{}) (map[string]interface{}, error) {
+	// Get effect metadata for parameter augmentation
+	metadata, ok := effects.GetEffectMetadata(effectType)
+	if !ok {
+		return nil, fmt.Errorf("unknown effect type: %s", effectType)
+	}
+
+	augmentedArgs := copyArgs(args)
+	for _, param := range metadata.Parameters {
+		value, exists := augmentedArgs[param.InternalName]
+		if _, bound := config.AsModulatorBinding(value); !exists || bound {
+			augmentedArgs[param.InternalName] = param.DefaultValue
+		}
+	}
+	return augmentedArgs, nil
+}
+
+// copyArgs returns a shallow copy of an args map.
+func copyArgs(args map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		copied[k] = v
+	}
+	return copied
+}
+
+// effectUpdate holds the changed args of a running effect that is updated in place.
+type effectUpdate struct {
+	setter     types.ParameterSetter
+	effectType string
+	args       map[string]interface{}
+}
+
+// updateEffect applies new args to a running effect. Effects implementing
+// types.ParameterSetter are updated in place, others are recreated.
+func updateEffect(effect *types.Effect, instance effectInstance, args map[string]interface{}) error {
+	if reflect.DeepEqual(instance.args, args) {
+		return nil
+	}
+	if setter, ok := (*effect).(types.ParameterSetter); ok {
+		changed, err := changedArgs(instance, args)
+		if err != nil {
+			return err
 		}
+		if err := setter.SetParameters(changed); err != nil {
+			return fmt.Errorf("error updating effect '%s': %w", instance.effectType, err)
+		}
+		return nil
+	}
+	recreated, err := newEffect(instance.effectType, args)
+	if err != nil {
+		return err
 	}
-	c.isDirty = false
+	*effect = recreated
 	return nil
 }
 
+// changedArgs returns the args that differ between a running effect and args, removed
+// args with their default value. They are checked against the effect's parameters,
+// so SetParameters won't reject them.
+func changedArgs(instance effectInstance, args map[string]interface{}) (map[string]interface{}, error) {
+	augmentedArgs, err := augmentArgs(instance.effectType, args)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]interface{})
+	for name, value := range augmentedArgs {
+		if reflect.DeepEqual(instance.args[name], args[name]) {
+			continue
+		}
+		if param, ok := effects.FindParameter(instance.effectType, name); ok {
+			if err := config.CheckEffectArg(param, value); err != nil {
+				return nil, fmt.Errorf("effect '%s': %w", instance.id, err)
+			}
+		}
+		changed[name] = value
+	}
+	return changed, nil
+}
 
+// setEffectArgs queues new args for a running effect. They are applied at the start
+// of the next tick, so an effect never changes while it processes a frame.
+func (c *Chain) setEffectArgs(effectID string, args map[string]interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pendingArgs == nil {
+		c.pendingArgs = make(map[string]map[string]interface{})
+	}
+	c.pendingArgs[effectID] = args
+}
+
+// applyPendingArgs applies the queued args to the running effects. Must be called with the mutex held.
+func (c *Chain) applyPendingArgs() {
+	for effectID, args := range c.pendingArgs {
+		for i := range c.instances {
+			if c.instances[i].id != effectID {
+				continue
+			}
+			if err := updateEffect(&c.Effects[i], c.instances[i], args); err != nil {
+				fmt.Printf("Chain %s: %v\n", c.ID, err)
+				continue
+			}
+			c.instances[i].args = args
+			c.instances[i].modulation = newEffectModulation(c.instances[i].effectType, args)
+		}
+	}
+	c.pendingArgs = nil
+}
 
 // EnforceGroupRules is called when an effect's enabled state is changed via an event.
 // It ensures that only one effect in a group is enabled in the config.
 func (c *Chain) EnforceGroupRules(triggeredEffectID string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.orchestrator.configMutex.Lock()
+	defer c.orchestrator.configMutex.Unlock()
 
 	var triggeredEffectConfig *config.EffectConfig
 	for i := range c.config.Effects {
@@ -149,41 +347,213 @@ func (c *Chain) EnforceGroupRules(triggeredEffectID string) {
 func (c *Chain) Tick() error {
 	c.mutex.Lock()
 	if c.isDirty {
+		c.isDirty = false // A failed rebuild is retried when the config changes again
 		if err := c.rebuildEffectsFromConfig(); err != nil {
 			c.mutex.Unlock()
 			return err // Report error but don't stop the chain
 		}
 	}
+	c.applyPendingArgs()
+	if c.nextOutput != nil {
+		// Swapped here, so the old output is never closed in the middle of a Send
+		c.Output.Close()
+		c.Output = c.nextOutput
+		c.nextOutput = nil
+	}
 	// Create a snapshot of the effects to process for this tick
 	effectsSnapshot := make([]types.Effect, len(c.Effects))
 	copy(effectsSnapshot, c.Effects)
+	instances := make([]effectInstance, len(c.instances))
+	copy(instances, c.instances)
+	output, outputConfig := c.Output, c.outputConfig
+	now := time.Now()
+	if c.crossfade != nil && c.crossfade.progress(now) >= 1 {
+		c.crossfade = nil
+	}
+	fade := c.crossfade // Only used by Tick, it needs no snapshot
+	tickRate, enabled := c.TickRate, c.enabled
 	c.mutex.Unlock()
 
-	// Update global beat progress before processing effects
-	c.orchestrator.UpdateBeatProgress()
+	source, shared := output.(*mergeSource)
+	if !enabled {
+		// The effects hold their state until the chain is enabled again
+		if len(c.outputLamps) != len(c.lamps) {
+			c.outputLamps = make([]dmx.Lamp, len(c.lamps))
+		}
+		clear(c.outputLamps)
+		c.orchestrator.publishFrame(c.ID, c.outputLamps)
+		if shared {
+			// The other chains of the shared output take over instead of black
+			source.withdraw()
+			return nil
+		}
+		return output.Send(c.outputLamps)
+	}
 
-	// Process the snapshot of effects
+	// Process the snapshot of effects with a snapshot of the globals, published by the beat clock
 	globals := c.orchestrator.GetGlobals()
-	globals.TickRate = c.TickRate
-	for _, effect := range effectsSnapshot {
-		effect.Process(c.lamps, globals, c.config.Output.ChannelMapping, c.config.Output.NumChannelsPerLamp)
+	globals.TickRate = tickRate
+	c.render(effectsSnapshot, instances, c.lamps, &globals, outputConfig, now)
+	frame := c.lamps
+	if fade != nil {
+		frame = c.renderFade(fade, c.lamps, globals, outputConfig, now)
 	}
 
 	// Send to output
-	return c.Output.Send(c.lamps)
+	frame = c.applyMaster(frame, &globals)
+	c.orchestrator.publishFrame(c.ID, frame)
+	if shared {
+		return source.sendFrame(frame, tickRate)
+	}
+	return output.Send(frame)
+}
+
+// render processes effects on a frame.
+func (c *Chain) render(effects []types.Effect, instances []effectInstance, frame []dmx.Lamp, globals *types.OrchestratorGlobals, outputConfig *config.OutputConfig, now time.Time) {
+	for i, effect := range effects {
+		if modulation := instances[i].modulation; modulation != nil {
+			modulation.apply(effect, &instances[i], &c.orchestrator.modulators, globals, now)
+		}
+		view, layer := &instances[i].view, &instances[i].layer
+		lamps := view.lamps(frame, &c.viewLamps)
+		layer.process(effect, lamps, &c.layerLamps, globals, outputConfig.ChannelMapping, outputConfig.NumChannelsPerLamp)
+		view.writeBack(frame, lamps)
+	}
+}
+
+// renderFade renders the outgoing effects of a crossfade and blends their frame into
+// frame. A crossfade started during another one fades out from the blend of the other.
+func (c *Chain) renderFade(fade *crossfade, frame []dmx.Lamp, globals types.OrchestratorGlobals, outputConfig *config.OutputConfig, now time.Time) []dmx.Lamp {
+	fadeGlobals := fade.globals(globals)
+	c.render(fade.effects, fade.instances, fade.lamps, &fadeGlobals, outputConfig, now)
+	outgoing := fade.lamps
+	if fade.previous != nil && fade.previous.progress(now) >= 1 {
+		fade.previous = nil
+	}
+	if fade.previous != nil {
+		outgoing = c.renderFade(fade.previous, fade.lamps, globals, outputConfig, now)
+	}
+	return fade.mix(frame, outgoing, fade.progress(now))
+}
+
+// reconfigure points a running chain to its config in a new configuration. A non-nil
+// output replaces the current one on the next tick. If the effects changed and fade
+// is set, the chain crossfades to the new effects.
+func (c *Chain) reconfigure(cfg *config.ChainConfig, outputConfig *config.OutputConfig, output Output, effectsChanged bool, fade *sceneFade) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.config = cfg
+	c.Priority = cfg.Priority
+	c.outputConfig = outputConfig
+	c.enabled = cfg.Enabled == nil || *cfg.Enabled
+	c.setTickRateLocked(cfg.TickRate)
+	if output != nil {
+		if c.nextOutput != nil {
+			c.nextOutput.Close()
+		}
+		c.nextOutput = output
+	}
+	if effectsChanged {
+		c.isDirty = true
+		c.pendingFade = fade
+	}
+}
+
+// applyMaster applies the grand master intensity and blackout to a frame of the chain.
+// The result goes into a separate buffer, so effects keep working on their own frame.
+func (c *Chain) applyMaster(frame []dmx.Lamp, globals *types.OrchestratorGlobals) []dmx.Lamp {
+	if !globals.Blackout && globals.Intensity >= 255 {
+		return frame
+	}
+	if len(c.outputLamps) != len(frame) {
+		c.outputLamps = make([]dmx.Lamp, len(frame))
+	}
+	intensity := uint16(globals.Intensity)
+	if globals.Blackout {
+		intensity = 0
+	}
+	for i, lamp := range frame {
+		c.outputLamps[i] = dmx.Lamp{
+			R: uint8(uint16(lamp.R) * intensity / 255),
+			G: uint8(uint16(lamp.G) * intensity / 255),
+			B: uint8(uint16(lamp.B) * intensity / 255),
+			W: uint8(uint16(lamp.W) * intensity / 255),
+		}
+	}
+	return c.outputLamps
+}
+
+// setEnabled enables or disables the chain, a disabled chain sends black.
+func (c *Chain) setEnabled(enabled bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.enabled = enabled
+}
+
+// setTickRate changes the tick rate of the running chain.
+func (c *Chain) setTickRate(tickRate int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.setTickRateLocked(tickRate)
+}
+
+// setTickRateLocked is setTickRate for callers already holding the mutex.
+func (c *Chain) setTickRateLocked(tickRate int) {
+	if tickRate <= 0 || tickRate == c.TickRate {
+		return
+	}
+	c.TickRate = tickRate
+	select {
+	case c.tickRateSet <- struct{}{}:
+	default: // The loop wasn't woken up for an earlier change yet
+	}
+}
+
+// tickInterval returns the time between two ticks at the current tick rate.
+func (c *Chain) tickInterval() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	interval := time.Duration(1000/c.TickRate) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Millisecond // Above 1000 FPS
+	}
+	return interval
 }
 
 // StartLoop starts the chain's independent ticking loop.
 func (c *Chain) StartLoop() {
 	go func() {
-		ticker := time.NewTicker(time.Duration(1000/c.TickRate) * time.Millisecond)
+		ticker := time.NewTicker(c.tickInterval())
 		defer ticker.Stop()
-		defer c.Output.Close()
+		defer c.closeOutput()
 
-		for range ticker.C {
-			if err := c.Tick(); err != nil {
-				fmt.Printf("Chain %s error: %v\n", c.ID, err)
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-c.tickRateSet:
+				ticker.Reset(c.tickInterval())
+			case <-ticker.C:
+				if err := c.Tick(); err != nil {
+					fmt.Printf("Chain %s error: %v\n", c.ID, err)
+				}
 			}
 		}
 	}()
 }
+
+// Stop ends the chain's loop and closes its output.
+func (c *Chain) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// closeOutput closes the chain's output, including one that is waiting to replace it.
+func (c *Chain) closeOutput() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Output.Close()
+	if c.nextOutput != nil {
+		c.nextOutput.Close()
+		c.nextOutput = nil
+	}
+}
@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"godmx/effects"
+	"testing"
+)
+
+func TestFailedRebuildKeepsEffects(t *testing.T) {
+	cfg := &config.Config{
+		Globals: config.GlobalsConfig{BPM: 120, Color1: "#FF0000", Color2: "#0000FF", BeatsPerBar: 4, BarsPerPhrase: 4},
+		Chains: []config.ChainConfig{{
+			ID:       "main",
+			TickRate: 30,
+			NumLamps: 4,
+			Output:   config.OutputConfig{Type: "artnet"},
+			Effects: []config.EffectConfig{
+				{ID: "dim", Type: "dim", Args: map[string]interface{}{"percentage": 0.5}},
+			},
+		}},
+	}
+	o := NewOrchestrator(cfg)
+	chain := NewChain(&cfg.Chains[0], o, discardOutput{})
+	if err := chain.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	built := chain.Effects
+
+	// The second effect can't be created, so the rebuild must not touch the first one
+	cfg.Chains[0].Effects[0].Args["percentage"] = 0.25
+	cfg.Chains[0].Effects = append(cfg.Chains[0].Effects,
+		config.EffectConfig{ID: "broken", Type: "dim", Args: map[string]interface{}{"percentage": "half"}})
+	chain.SetDirty(true)
+	if err := chain.Tick(); err == nil {
+		t.Fatal("expected an error for the broken effect")
+	}
+	if len(chain.Effects) != 1 || chain.Effects[0] != built[0] || len(chain.instances) != 1 {
+		t.Fatalf("effects changed by the failed rebuild: %v", chain.Effects)
+	}
+	if got := chain.Effects[0].(*effects.Dim).Percentage; got != 0.5 {
+		t.Errorf("percentage = %v after the failed rebuild, want 0.5", got)
+	}
+	if got := chain.instances[0].args["percentage"]; got != 0.5 {
+		t.Errorf("instance percentage = %v after the failed rebuild, want 0.5", got)
+	}
+
+	// The chain keeps ticking with its effects instead of retrying the rebuild
+	if err := chain.Tick(); err != nil {
+		t.Errorf("tick after the failed rebuild: %v", err)
+	}
+
+	// Once the broken effect is fixed, the rebuild applies the new percentage
+	cfg.Chains[0].Effects[1].Args["percentage"] = 0.5
+	chain.SetDirty(true)
+	if err := chain.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	if got := chain.Effects[0].(*effects.Dim).Percentage; got != 0.25 {
+		t.Errorf("percentage = %v after the rebuild, want 0.25", got)
+	}
+}
@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"math"
+	"time"
+)
+
+// clockResolution is how often the beat clock advances the beat and publishes new globals.
+// It is well above any chain's tick rate, so every tick sees a fresh beat position.
+const clockResolution = 2 * time.Millisecond
+
+// StartClock starts the beat clock. It is the only place the beat position advances,
+// so all chains share the same beat phase no matter how fast they tick. Cues that
+// advance on their own and delayed actions are fired by the clock as well.
+func (o *Orchestrator) StartClock() {
+	o.mutex.Lock()
+	o.lastBeatTime = time.Now()
+	o.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(clockResolution)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			o.advanceClock(now)
+			o.advanceCues(now)
+			o.advanceSchedule(now)
+		}
+	}()
+}
+
+// advanceClock moves the beat forward by the time passed since the last update.
+// The beat advances relative to the current BPM, so BPM changes never make it jump.
+func (o *Orchestrator) advanceClock(now time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.advanceClockLocked(now)
+}
+
+// advanceClockLocked is advanceClock for callers already holding the mutex.
+func (o *Orchestrator) advanceClockLocked(now time.Time) {
+	elapsed := now.Sub(o.lastBeatTime).Seconds()
+	o.lastBeatTime = now
+	if elapsed <= 0 || o.globals.BPM <= 0 {
+		return
+	}
+
+	advance := elapsed * o.globals.BPM / 60.0
+	if limit, ok := o.externalClockLimit(now); ok && o.globals.BeatTime+advance > limit {
+		advance = math.Max(limit-o.globals.BeatTime, 0)
+	}
+	o.globals.BeatProgress += advance
+	for o.globals.BeatProgress >= 1.0 {
+		o.globals.BeatProgress -= 1.0
+		o.globals.Beat++
+	}
+	o.updateBeatPosition()
+}
+
+// setBeatTime moves the beat to an absolute beat time. Must be called with the mutex held.
+func (o *Orchestrator) setBeatTime(beatTime float64) {
+	beat := math.Floor(beatTime)
+	o.globals.Beat = int64(beat)
+	o.globals.BeatProgress = beatTime - beat
+	o.updateBeatPosition()
+}
+
+// updateBeatPosition derives the beat time, bar and phrase position from the
+// absolute beat count and the beat progress. Must be called with the mutex held.
+func (o *Orchestrator) updateBeatPosition() {
+	g := &o.globals
+	g.BeatTime = float64(g.Beat) + g.BeatProgress
+
+	beatsPerBar := int64(g.BeatsPerBar)
+	barsPerPhrase := int64(g.BarsPerPhrase)
+	if beatsPerBar < 1 || barsPerPhrase < 1 {
+		return
+	}
+
+	g.Bar = g.Beat / beatsPerBar
+	g.BarBeat = int(g.Beat % beatsPerBar)
+	g.BarProgress = (float64(g.BarBeat) + g.BeatProgress) / float64(beatsPerBar)
+
+	g.Phrase = g.Bar / barsPerPhrase
+	g.PhraseBar = int(g.Bar % barsPerPhrase)
+	g.PhraseProgress = (float64(g.PhraseBar) + g.BarProgress) / float64(barsPerPhrase)
+}
@@ -0,0 +1,140 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/dmx"
+	"godmx/effects"
+	"godmx/types"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// beatProbe is an effect that records the beat time of the globals it renders with.
+type beatProbe struct {
+	name string
+}
+
+// beatProbes holds the beat time each probe saw last, by probe name.
+var beatProbes = struct {
+	mutex sync.Mutex
+	seen  map[string]float64
+}{seen: make(map[string]float64)}
+
+func (p *beatProbe) Process(lamps []dmx.Lamp, globals *types.OrchestratorGlobals, channelMapping string, numChannelsPerLamp int) {
+	beatProbes.mutex.Lock()
+	defer beatProbes.mutex.Unlock()
+	beatProbes.seen[p.name] = globals.BeatTime
+}
+
+func init() {
+	effects.RegisterEffect("beatProbe", func(args map[string]interface{}) (types.Effect, error) {
+		name, _ := args["name"].(string)
+		return &beatProbe{name: name}, nil
+	})
+	effects.RegisterEffectMetadata("beatProbe", types.EffectMetadata{
+		HumanReadableName: "Beat Probe",
+		Parameters: []types.ParameterMetadata{
+			{InternalName: "name", DataType: "string", DefaultValue: ""},
+		},
+	})
+}
+
+// discardOutput is an Output that drops all frames.
+type discardOutput struct{}
+
+func (discardOutput) Send(lamps []dmx.Lamp) error { return nil }
+func (discardOutput) Close()                      {}
+
+func TestChainsShareBeatPhase(t *testing.T) {
+	const numChains = 4
+	cfg := &config.Config{
+		Globals: config.GlobalsConfig{BPM: 120, Color1: "#FF0000", Color2: "#0000FF", BeatsPerBar: 4, BarsPerPhrase: 4},
+		Actions: map[string][]config.ActionConfig{},
+	}
+	for i := 0; i < numChains; i++ {
+		id := fmt.Sprintf("chain%d", i)
+		cfg.Chains = append(cfg.Chains, config.ChainConfig{
+			ID:       id,
+			TickRate: 30 + 10*i, // Different tick rates must not matter
+			NumLamps: 8,
+			Output:   config.OutputConfig{Type: "artnet"},
+			Effects: []config.EffectConfig{
+				{ID: "probe", Type: "beatProbe", Args: map[string]interface{}{"name": id}},
+			},
+		})
+	}
+	o := NewOrchestrator(cfg)
+	o.SetConfigPath(filepath.Join(t.TempDir(), "config.json")) // set_global saves the config
+	chains := make([]*Chain, numChains)
+	for i := range cfg.Chains {
+		chains[i] = NewChain(&cfg.Chains[i], o, discardOutput{})
+		o.AddChain(chains[i])
+	}
+
+	// Change the globals from other goroutines while the chains tick
+	stop := make(chan struct{})
+	var background sync.WaitGroup
+	run := func(fn func(i int)) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+					fn(i)
+				}
+			}
+		}()
+	}
+	run(func(i int) { o.SetBPM(float64(100 + i%60)) })
+	run(func(i int) { o.GetGlobals() })
+	run(func(i int) {
+		action := config.ActionConfig{Type: "set_global", Params: map[string]interface{}{"bpm": float64(90 + i%30), "color1": "#00FF00"}}
+		if err := o.executeAction(action, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	now := time.Now()
+	o.advanceClock(now)
+	last := -1.0
+	for step := 0; step < 200; step++ {
+		now = now.Add(clockResolution)
+		o.advanceClock(now)
+		want := o.GetGlobals().BeatTime
+		if want < last {
+			t.Fatalf("beat time went back from %v to %v", last, want)
+		}
+		last = want
+
+		var ticks sync.WaitGroup
+		for _, chain := range chains {
+			ticks.Add(1)
+			go func() {
+				defer ticks.Done()
+				if err := chain.Tick(); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		ticks.Wait()
+
+		beatProbes.mutex.Lock()
+		for _, chain := range chains {
+			if seen := beatProbes.seen[chain.ID]; seen != want {
+				t.Errorf("step %d: chain %s saw beat time %v, want %v", step, chain.ID, seen, want)
+			}
+		}
+		beatProbes.mutex.Unlock()
+	}
+	close(stop)
+	background.Wait()
+	if last <= 0 {
+		t.Errorf("beat time didn't advance")
+	}
+}
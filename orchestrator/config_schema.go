@@ -0,0 +1,69 @@
+package orchestrator
+
+import "godmx/config"
+
+// ConfigSchema returns the JSON Schema of the configuration file, with the params
+// of every action type from ActionSchemas.
+func ConfigSchema() config.Schema {
+	actions := make(map[string]config.Schema, len(ActionSchemas))
+	for name, schema := range ActionSchemas {
+		actions[name] = actionSchema(name, schema)
+	}
+	return config.JSONSchema(actions)
+}
+
+// actionSchema describes an action of one type. chain_id and effect_id are fields
+// of the action, the other parameters go into its params.
+func actionSchema(name string, schema ActionSchema) config.Schema {
+	var required []string
+	params := config.Schema{}
+	for _, param := range schema.Parameters {
+		switch param.InternalName {
+		case "chain_id", "effect_id":
+			required = append(required, param.InternalName)
+			continue
+		}
+		params[param.InternalName] = actionParameterSchema(param)
+	}
+
+	action := config.Schema{"description": schema.Description}
+	if len(required) > 0 {
+		action["required"] = required
+	}
+	switch name {
+	case "add_effect":
+		// The params are the config of the new effect
+		action["properties"] = config.Schema{"params": config.Schema{"$ref": "#/definitions/EffectConfig"}}
+	default:
+		action["properties"] = config.Schema{"params": config.Schema{"type": "object", "properties": params}}
+	}
+	return action
+}
+
+// actionParameterSchema describes the values of an action parameter.
+func actionParameterSchema(param ActionParameter) config.Schema {
+	schema := config.Schema{
+		"title":       param.DisplayName,
+		"description": param.Description,
+	}
+	switch param.DataType {
+	case "float64":
+		schema["type"] = "number"
+	case "int":
+		schema["type"] = "integer"
+	case "bool":
+		schema["type"] = "boolean"
+	case "string":
+		schema["type"] = "string"
+	case "color":
+		schema["type"] = "string"
+		schema["pattern"] = "^#?[0-9A-Fa-f]{6}$"
+	}
+	if param.DefaultValue != nil {
+		schema["default"] = param.DefaultValue
+	}
+	if len(param.Options) > 0 {
+		schema["enum"] = param.Options
+	}
+	return schema
+}
@@ -0,0 +1,224 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/types"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// cuePlayer plays the cue lists of the running configuration.
+type cuePlayer struct {
+	mutex sync.Mutex
+	lists map[string]*cueList
+}
+
+// cueList is a cue list with its playback state.
+type cueList struct {
+	config    config.CueListConfig
+	current   int       // Index of the cue that fired last, -1 before the first
+	firedAt   time.Time // When the current cue fired
+	firedBeat float64   // Beat time when the current cue fired
+}
+
+// firedCue is a cue that fires.
+type firedCue struct {
+	list  string
+	index int
+	cue   config.CueConfig
+}
+
+// CueListStatus is the playback state of a cue list.
+type CueListStatus struct {
+	Name    string   `json:"name"`
+	Cues    []string `json:"cues"`    // Names of the cues, empty for cues without a name
+	Current int      `json:"current"` // Index of the cue that fired last, -1 before the first
+	Loop    bool     `json:"loop"`
+}
+
+// update replaces the cue lists, keeping the position of those whose config didn't change.
+func (p *cuePlayer) update(configs map[string]config.CueListConfig) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	lists := make(map[string]*cueList, len(configs))
+	for name, cfg := range configs {
+		if old, ok := p.lists[name]; ok && reflect.DeepEqual(old.config, cfg) {
+			lists[name] = old
+			continue
+		}
+		lists[name] = &cueList{config: cfg, current: -1}
+	}
+	p.lists = lists
+}
+
+// move makes a cue of a list the current one. target returns the index of the cue
+// from the list's state.
+func (p *cuePlayer) move(name string, globals *types.OrchestratorGlobals, now time.Time, target func(l *cueList) (int, error)) (firedCue, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	l, ok := p.lists[name]
+	if !ok {
+		return firedCue{}, fmt.Errorf("cue list '%s' not found", name)
+	}
+	index, err := target(l)
+	if err != nil {
+		return firedCue{}, fmt.Errorf("cue list '%s': %w", name, err)
+	}
+	l.current, l.firedAt, l.firedBeat = index, now, globals.BeatTime
+	return firedCue{list: name, index: index, cue: l.config.Cues[index]}, nil
+}
+
+// next returns the index of the cue after the current one.
+func (l *cueList) next() (int, error) {
+	next := l.current + 1
+	if next < len(l.config.Cues) {
+		return next, nil
+	}
+	if l.config.Loop && len(l.config.Cues) > 0 {
+		return 0, nil
+	}
+	return 0, fmt.Errorf("no cue after the last one")
+}
+
+// due returns the cues that advance on their own at now, and makes them current.
+func (p *cuePlayer) due(globals *types.OrchestratorGlobals, now time.Time) []firedCue {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var fired []firedCue
+	for name, l := range p.lists {
+		if l.current < 0 {
+			continue
+		}
+		cue := l.config.Cues[l.current]
+		firedAt, firedBeat := l.firedAt, l.firedBeat
+		switch cue.Advance {
+		case "time":
+			firedAt = firedAt.Add(time.Duration(cue.Duration * float64(time.Second)))
+			if now.Before(firedAt) {
+				continue
+			}
+		case "beats", "bars":
+			beats := cue.Duration
+			if cue.Advance == "bars" {
+				beats *= float64(globals.BeatsPerBar)
+			}
+			firedBeat += beats
+			if globals.BeatTime < firedBeat {
+				continue
+			}
+		default:
+			continue // Manual, only GO advances
+		}
+		next, err := l.next()
+		if err != nil {
+			continue // The last cue stays current
+		}
+		// From the moment the cue was due, so following cues don't drift
+		l.current, l.firedAt, l.firedBeat = next, firedAt, firedBeat
+		if cue.Advance == "time" {
+			l.firedBeat = globals.BeatTime
+		} else {
+			l.firedAt = now
+		}
+		fired = append(fired, firedCue{list: name, index: next, cue: l.config.Cues[next]})
+	}
+	return fired
+}
+
+// statuses returns the state of all cue lists, sorted by name.
+func (p *cuePlayer) statuses() []CueListStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	statuses := make([]CueListStatus, 0, len(p.lists))
+	for name, l := range p.lists {
+		status := CueListStatus{Name: name, Cues: make([]string, len(l.config.Cues)), Current: l.current, Loop: l.config.Loop}
+		for i, cue := range l.config.Cues {
+			status.Cues[i] = cue.Name
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// CueGo fires the next cue of a cue list, the first one if none fired yet.
+func (o *Orchestrator) CueGo(list string) error {
+	return o.moveCue(list, func(l *cueList) (int, error) {
+		return l.next()
+	})
+}
+
+// CueBack fires the cue before the current one of a cue list.
+func (o *Orchestrator) CueBack(list string) error {
+	return o.moveCue(list, func(l *cueList) (int, error) {
+		if l.current <= 0 {
+			return 0, fmt.Errorf("no cue before the first one")
+		}
+		return l.current - 1, nil
+	})
+}
+
+// CueJump fires the cue with the given name of a cue list.
+func (o *Orchestrator) CueJump(list, cue string) error {
+	return o.moveCue(list, func(l *cueList) (int, error) {
+		return l.config.FindCue(cue)
+	})
+}
+
+// CueStop stops a cue list, the next GO fires its first cue again. The look of the
+// last fired cue stays, its delayed actions that are still pending are cancelled.
+func (o *Orchestrator) CueStop(list string) error {
+	o.cues.mutex.Lock()
+	l, ok := o.cues.lists[list]
+	if ok {
+		l.current = -1
+	}
+	o.cues.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("cue list '%s' not found", list)
+	}
+	o.scheduler.cancel(cueRunName(list))
+	return nil
+}
+
+// CueLists returns the playback state of all cue lists.
+func (o *Orchestrator) CueLists() []CueListStatus {
+	return o.cues.statuses()
+}
+
+// moveCue makes a cue of a list the current one and fires it.
+func (o *Orchestrator) moveCue(list string, target func(l *cueList) (int, error)) error {
+	globals := o.GetGlobals()
+	fired, err := o.cues.move(list, &globals, time.Now(), target)
+	if err != nil {
+		return err
+	}
+	o.fireCue(fired)
+	return nil
+}
+
+// advanceCues fires the cues that are due, called by the beat clock.
+func (o *Orchestrator) advanceCues(now time.Time) {
+	globals := o.GetGlobals()
+	for _, fired := range o.cues.due(&globals, now) {
+		o.fireCue(fired)
+	}
+}
+
+// fireCue triggers the event of a cue and executes its actions. Delayed actions
+// still pending from the previous cue of the list are cancelled.
+func (o *Orchestrator) fireCue(fired firedCue) {
+	fmt.Printf("Cue list '%s': cue %d '%s'\n", fired.list, fired.index+1, fired.cue.Name)
+	if fired.cue.Event != "" {
+		o.TriggerEvent(fired.cue.Event)
+	}
+	o.runActions(cueRunName(fired.list), fired.cue.Actions, nil)
+}
+
+// cueRunName returns the name the actions of a cue list's cues are scheduled under.
+func cueRunName(list string) string {
+	return "cue_lists." + list
+}
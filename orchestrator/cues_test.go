@@ -0,0 +1,194 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"godmx/types"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// bpmAction returns a set_global action setting the BPM.
+func bpmAction(bpm float64) config.ActionConfig {
+	return config.ActionConfig{Type: "set_global", Params: map[string]interface{}{"bpm": bpm}}
+}
+
+// newCueOrchestrator starts an orchestrator without chains playing the cue lists.
+func newCueOrchestrator(t *testing.T, cueLists map[string]config.CueListConfig) *Orchestrator {
+	t.Helper()
+	cfg := &config.Config{
+		Globals:  config.GlobalsConfig{BPM: 120, Color1: "#FF0000", Color2: "#0000FF", BeatsPerBar: 4, BarsPerPhrase: 4},
+		Actions:  map[string][]config.ActionConfig{},
+		CueLists: cueLists,
+	}
+	o := NewOrchestrator(cfg)
+	o.SetConfigPath(filepath.Join(t.TempDir(), "config.json"))
+	if err := o.Start(func(config.OutputConfig) (Output, error) { return discardOutput{}, nil }); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+// currentCue returns the index of the current cue of a cue list.
+func currentCue(t *testing.T, o *Orchestrator, list string) int {
+	t.Helper()
+	for _, status := range o.CueLists() {
+		if status.Name == list {
+			return status.Current
+		}
+	}
+	t.Fatalf("cue list '%s' not found", list)
+	return 0
+}
+
+func TestCueListCommands(t *testing.T) {
+	o := newCueOrchestrator(t, map[string]config.CueListConfig{
+		"show": {Cues: []config.CueConfig{
+			{Name: "intro", Actions: []config.ActionConfig{bpmAction(100)}},
+			{Name: "verse", Actions: []config.ActionConfig{bpmAction(110)}},
+			{Name: "chorus", Actions: []config.ActionConfig{bpmAction(130)}},
+		}},
+		"loop": {Loop: true, Cues: []config.CueConfig{{Name: "a"}, {Name: "b"}}},
+	})
+
+	tests := []struct {
+		name    string
+		command func() error
+		current int     // Current cue after the command
+		bpm     float64 // BPM set by the current cue's actions
+		wantErr bool
+	}{
+		{name: "first GO fires the first cue", command: func() error { return o.CueGo("show") }, current: 0, bpm: 100},
+		{name: "GO", command: func() error { return o.CueGo("show") }, current: 1, bpm: 110},
+		{name: "BACK", command: func() error { return o.CueBack("show") }, current: 0, bpm: 100},
+		{name: "BACK before the first cue", command: func() error { return o.CueBack("show") }, current: 0, bpm: 100, wantErr: true},
+		{name: "jump", command: func() error { return o.CueJump("show", "chorus") }, current: 2, bpm: 130},
+		{name: "GO after the last cue", command: func() error { return o.CueGo("show") }, current: 2, bpm: 130, wantErr: true},
+		{name: "jump to an unknown cue", command: func() error { return o.CueJump("show", "outro") }, current: 2, bpm: 130, wantErr: true},
+		{name: "jump back", command: func() error { return o.CueJump("show", "verse") }, current: 1, bpm: 110},
+		{name: "stop", command: func() error { return o.CueStop("show") }, current: -1, bpm: 110},
+		{name: "GO after stop starts over", command: func() error { return o.CueGo("show") }, current: 0, bpm: 100},
+		{name: "unknown cue list", command: func() error { return o.CueGo("missing") }, current: 0, bpm: 100, wantErr: true},
+	}
+	for _, test := range tests {
+		err := test.command()
+		if (err != nil) != test.wantErr {
+			t.Fatalf("%s: error = %v, want error %t", test.name, err, test.wantErr)
+		}
+		if got := currentCue(t, o, "show"); got != test.current {
+			t.Errorf("%s: current cue = %d, want %d", test.name, got, test.current)
+		}
+		if got := o.GetGlobals().BPM; got != test.bpm {
+			t.Errorf("%s: BPM = %v, want %v", test.name, got, test.bpm)
+		}
+	}
+
+	// A looping list starts over after the last cue
+	for _, want := range []int{0, 1, 0} {
+		if err := o.CueGo("loop"); err != nil {
+			t.Fatal(err)
+		}
+		if got := currentCue(t, o, "loop"); got != want {
+			t.Errorf("looping list: current cue = %d, want %d", got, want)
+		}
+	}
+}
+
+func TestCueAutoAdvance(t *testing.T) {
+	start := time.Unix(1000, 0)
+	globals := &types.OrchestratorGlobals{BPM: 120, BeatsPerBar: 4, BeatTime: 10}
+	var p cuePlayer
+	p.update(map[string]config.CueListConfig{"show": {Cues: []config.CueConfig{
+		{Advance: "time", Duration: 2},
+		{Advance: "beats", Duration: 4},
+		{Advance: "bars", Duration: 1},
+		{}, // Manual
+	}}})
+	if _, err := p.move("show", globals, start, func(l *cueList) (int, error) { return l.next() }); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name     string
+		elapsed  time.Duration
+		beatTime float64
+		current  int // Current cue after due
+		fired    bool
+	}{
+		{name: "before the time", elapsed: 1999 * time.Millisecond, beatTime: 13.9, current: 0},
+		{name: "time is up", elapsed: 2 * time.Second, beatTime: 14, current: 1, fired: true},
+		{name: "before the beats", elapsed: 3 * time.Second, beatTime: 17.9, current: 1},
+		{name: "beats are up", elapsed: 4 * time.Second, beatTime: 18, current: 2, fired: true},
+		{name: "before the bar", elapsed: 5 * time.Second, beatTime: 21.9, current: 2},
+		{name: "bar is up", elapsed: 6 * time.Second, beatTime: 22, current: 3, fired: true},
+		{name: "manual cue stays", elapsed: time.Hour, beatTime: 10000, current: 3},
+	}
+	for _, step := range steps {
+		globals.BeatTime = step.beatTime
+		fired := p.due(globals, start.Add(step.elapsed))
+		if step.fired != (len(fired) == 1) {
+			t.Errorf("%s: fired %v", step.name, fired)
+		}
+		if got := p.lists["show"].current; got != step.current {
+			t.Errorf("%s: current cue = %d, want %d", step.name, got, step.current)
+		}
+	}
+}
+
+func TestCueAutoAdvanceAtTheEnd(t *testing.T) {
+	start := time.Unix(1000, 0)
+	globals := &types.OrchestratorGlobals{BeatsPerBar: 4}
+	cues := []config.CueConfig{{Advance: "time", Duration: 1}, {Advance: "time", Duration: 1}}
+	var p cuePlayer
+	p.update(map[string]config.CueListConfig{
+		"once": {Cues: cues},
+		"loop": {Cues: cues, Loop: true},
+	})
+	for _, list := range []string{"once", "loop"} {
+		if _, err := p.move(list, globals, start, func(l *cueList) (int, error) { return 1, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.due(globals, start.Add(time.Second))
+	if got := p.lists["once"].current; got != 1 {
+		t.Errorf("the last cue of a list without loop advanced to %d", got)
+	}
+	if got := p.lists["loop"].current; got != 0 {
+		t.Errorf("looping list: current cue = %d, want 0", got)
+	}
+
+	// The following cue starts counting when the previous one was due, not when due ran
+	p.due(globals, start.Add(1900*time.Millisecond))
+	if got := p.lists["loop"].current; got != 0 {
+		t.Errorf("looping list advanced early to %d", got)
+	}
+	p.due(globals, start.Add(2*time.Second))
+	if got := p.lists["loop"].current; got != 1 {
+		t.Errorf("looping list: current cue = %d, want 1", got)
+	}
+}
+
+func TestCueStopCancelsPendingActions(t *testing.T) {
+	o := newCueOrchestrator(t, map[string]config.CueListConfig{
+		"show": {Cues: []config.CueConfig{{Actions: []config.ActionConfig{
+			bpmAction(100),
+			{Type: "set_global", Params: map[string]interface{}{"bpm": 140.0}, Delay: 10},
+		}}}},
+	})
+	if err := o.CueGo("show"); err != nil {
+		t.Fatal(err)
+	}
+	if got := o.GetGlobals().BPM; got != 100 {
+		t.Fatalf("BPM = %v, want 100", got)
+	}
+	if err := o.CueStop("show"); err != nil {
+		t.Fatal(err)
+	}
+	o.advanceSchedule(time.Now().Add(time.Minute))
+	if got := o.GetGlobals().BPM; got != 100 {
+		t.Errorf("BPM = %v, the delayed action of the stopped cue list was executed", got)
+	}
+	if err := o.CueStop("missing"); err == nil {
+		t.Error("expected an error for an unknown cue list")
+	}
+}
@@ -0,0 +1,142 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"math"
+	"time"
+)
+
+const (
+	clockPPQN              = 24          // Pulses per quarter note of an external (MIDI) clock
+	externalClockTimeout   = time.Second // Without pulses for this long the beat runs freely at the last tempo
+	externalClockWindow    = clockPPQN   // Number of pulse intervals the tempo is measured over
+	externalClockSmoothing = 0.2         // Weight of a new tempo measurement against the smoothed tempo
+)
+
+// externalClock follows an external 24 PPQN clock. The pulses set the tempo and the
+// beat position, the internal clock only interpolates between two pulses.
+type externalClock struct {
+	pulseTimes   []time.Time // Recent pulses for the tempo measurement
+	pulses       int64       // Pulses since origin
+	origin       float64     // Beat time of pulse 0
+	bpm          float64     // Smoothed tempo, 0 until measured
+	stopped      bool        // Stopped by the clock master, the beat holds until Start or Continue
+	pendingStart bool        // The next pulse is the downbeat after a Start
+}
+
+// SetClockSource selects what drives the beat, the internal clock
+// (config.ClockSourceInternal) or an external MIDI clock (config.ClockSourceMidi).
+func (o *Orchestrator) SetClockSource(source string) {
+	if source == "" {
+		source = config.ClockSourceInternal
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.clockSource == source {
+		return
+	}
+	o.clockSource = source
+	o.external = externalClock{}
+}
+
+// ClockSource returns the current clock source.
+func (o *Orchestrator) ClockSource() string {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.clockSource
+}
+
+// ClockPulse registers a pulse of the external clock. Pulses are ignored unless
+// the external clock is the clock source.
+func (o *Orchestrator) ClockPulse(now time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.clockSource != config.ClockSourceMidi {
+		return
+	}
+	// Catch up to the pulse first, this is capped at the pulse position
+	o.advanceClockLocked(now)
+
+	c := &o.external
+	lost := len(c.pulseTimes) == 0 || now.Sub(c.pulseTimes[len(c.pulseTimes)-1]) > externalClockTimeout
+	if lost {
+		// First pulse or the clock came back, measure the tempo from scratch
+		c.pulseTimes = c.pulseTimes[:0]
+	}
+	c.pulseTimes = append(c.pulseTimes, now)
+	if len(c.pulseTimes) > externalClockWindow+1 {
+		c.pulseTimes = c.pulseTimes[1:]
+	}
+
+	// Measuring over a whole window averages out the jitter of single pulses
+	if n := len(c.pulseTimes); n > 1 {
+		if span := now.Sub(c.pulseTimes[0]).Seconds(); span > 0 {
+			measured := 60.0 * float64(n-1) / (span * clockPPQN)
+			if c.bpm == 0 {
+				c.bpm = measured
+			} else {
+				c.bpm += (measured - c.bpm) * externalClockSmoothing
+			}
+			o.globals.BPM = c.bpm
+		}
+	}
+
+	if c.stopped {
+		return
+	}
+	switch {
+	case c.pendingStart:
+		// Start plays from the top, so the first pulse becomes the next downbeat
+		beatsPerBar := float64(max(o.globals.BeatsPerBar, 1))
+		c.origin = math.Ceil(o.globals.BeatTime/beatsPerBar) * beatsPerBar
+		c.pulses = 0
+		c.pendingStart = false
+	case lost:
+		// Without a Start, continue from the current beat position
+		c.origin = o.globals.BeatTime
+		c.pulses = 0
+	default:
+		c.pulses++
+	}
+	o.setBeatTime(c.origin + float64(c.pulses)/clockPPQN)
+}
+
+// ClockStart handles a Start of the external clock. The next pulse is a downbeat.
+func (o *Orchestrator) ClockStart() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.external.stopped = false
+	o.external.pendingStart = true
+}
+
+// ClockStop handles a Stop of the external clock. The beat holds its position.
+func (o *Orchestrator) ClockStop() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.external.stopped = true
+}
+
+// ClockContinue handles a Continue of the external clock. The beat continues
+// from where it was stopped.
+func (o *Orchestrator) ClockContinue() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.external.stopped = false
+}
+
+// externalClockLimit returns how far the internal clock may advance the beat
+// time. ok is false if the beat may run freely. Must be called with the mutex held.
+func (o *Orchestrator) externalClockLimit(now time.Time) (limit float64, ok bool) {
+	if o.clockSource != config.ClockSourceMidi {
+		return 0, false
+	}
+	c := &o.external
+	if c.stopped || c.pendingStart {
+		return o.globals.BeatTime, true
+	}
+	if len(c.pulseTimes) == 0 || now.Sub(c.pulseTimes[len(c.pulseTimes)-1]) > externalClockTimeout {
+		return 0, false
+	}
+	// Never run ahead of the next pulse, so the beat time stays monotonic
+	return c.origin + float64(c.pulses+1)/clockPPQN, true
+}
@@ -0,0 +1,54 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/dmx"
+)
+
+// lampView is the part of a chain's frame an effect works on. Consecutive lamps are
+// processed in place, other selections are gathered into a buffer and written back.
+type lampView struct {
+	start, end int   // Consecutive lamps, if indices is nil
+	indices    []int // Lamps in the order the effect sees them
+}
+
+// newLampView resolves the lamps an effect targets, all lamps of the chain if the
+// effect has no lamp range.
+func newLampView(chainCfg *config.ChainConfig, effectCfg *config.EffectConfig) (lampView, error) {
+	if effectCfg.Lamps == nil {
+		return lampView{start: 0, end: chainCfg.NumLamps}, nil
+	}
+	indices, err := effectCfg.Lamps.Indices(chainCfg.NumLamps, chainCfg.Segments)
+	if err != nil {
+		return lampView{}, fmt.Errorf("effect '%s' lamps: %w", effectCfg.ID, err)
+	}
+	for i := range indices {
+		if indices[i] != indices[0]+i {
+			return lampView{indices: indices}, nil
+		}
+	}
+	return lampView{start: indices[0], end: indices[0] + len(indices)}, nil
+}
+
+// lamps returns the lamps of the view. buffer is reused between calls.
+func (v *lampView) lamps(frame []dmx.Lamp, buffer *[]dmx.Lamp) []dmx.Lamp {
+	if v.indices == nil {
+		return frame[v.start:v.end]
+	}
+	if cap(*buffer) < len(v.indices) {
+		*buffer = make([]dmx.Lamp, len(v.indices))
+	}
+	lamps := (*buffer)[:len(v.indices)]
+	for i, index := range v.indices {
+		lamps[i] = frame[index]
+	}
+	return lamps
+}
+
+// writeBack copies the lamps returned by lamps back into the frame.
+func (v *lampView) writeBack(frame []dmx.Lamp, lamps []dmx.Lamp) {
+	for i, index := range v.indices {
+		frame[index] = lamps[i]
+	}
+}
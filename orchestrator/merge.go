@@ -0,0 +1,271 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/dmx"
+	"sync"
+	"time"
+)
+
+// Merge modes for chains sharing an output.
+const (
+	MergeHTP      = "htp"      // Highest value per channel wins
+	MergeLTP      = "ltp"      // Latest changed value per channel wins
+	MergePriority = "priority" // Highest priority chain wins, equal priorities are merged HTP
+)
+
+// OutputMerger combines the frames of several chains onto one shared output.
+// Each chain sends to its own input; the latest frames of all inputs are merged
+// and sent to the output by the merger's own loop, at the tick rate of the
+// fastest chain, no matter how many chains send to it.
+type OutputMerger struct {
+	id      string
+	output  Output
+	mode    string
+	sources []*mergeSource
+	merged  []dmx.Lamp
+	stamp   uint64        // Increases with every frame, used to order LTP changes
+	pending chan struct{} // Signals the loop that an input has a new frame
+	done    chan struct{} // Closed with the output, ends the loop
+	closed  bool
+	mutex   sync.Mutex
+}
+
+// mergeSource is the input of a single chain into an OutputMerger.
+type mergeSource struct {
+	merger   *OutputMerger
+	chainID  string
+	priority int
+	tickRate int  // Frames per second the chain sends, 0 if unknown
+	active   bool // The chain sent a frame and isn't disabled, only active inputs are merged
+	frame    []dmx.Lamp
+	changed  [][4]uint64 // Stamp of the last change per lamp and channel (R, G, B, W)
+}
+
+// NewOutputMerger creates a new OutputMerger sending to the given output and starts
+// its loop. id names the shared output in errors.
+func NewOutputMerger(id string, output Output, mode string) (*OutputMerger, error) {
+	if mode == "" {
+		mode = MergeHTP
+	}
+	switch mode {
+	case MergeHTP, MergeLTP, MergePriority:
+	default:
+		return nil, fmt.Errorf("unknown merge mode: %s", mode)
+	}
+	m := &OutputMerger{
+		id:      id,
+		output:  output,
+		mode:    mode,
+		pending: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go m.run()
+	return m, nil
+}
+
+// run sends the merged frame whenever an input has a new frame, but at most at the
+// tick rate of the fastest active input. Frames arriving in between are merged
+// into the next send, so the output always gets the latest frames.
+func (m *OutputMerger) run() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-m.pending:
+		}
+
+		m.mutex.Lock()
+		if m.closed {
+			m.mutex.Unlock()
+			return
+		}
+		err := m.output.Send(m.merge())
+		interval := m.interval()
+		m.mutex.Unlock()
+		if err != nil {
+			fmt.Printf("Shared output %s error: %v\n", m.id, err)
+		}
+
+		select {
+		case <-m.done:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// interval returns the time between two sends, the tick interval of the fastest
+// active input. Must be called with the mutex held.
+func (m *OutputMerger) interval() time.Duration {
+	tickRate := 0
+	for _, source := range m.sources {
+		if source.active && source.tickRate > tickRate {
+			tickRate = source.tickRate
+		}
+	}
+	if tickRate <= 0 {
+		return 0
+	}
+	return time.Second / time.Duration(tickRate)
+}
+
+// signal wakes up the loop to send the merged frame.
+func (m *OutputMerger) signal() {
+	select {
+	case m.pending <- struct{}{}:
+	default: // A send is pending already, it picks up this frame
+	}
+}
+
+// close closes the shared output and ends the loop. Must be called with the mutex held.
+func (m *OutputMerger) close() {
+	if m.closed {
+		return
+	}
+	m.output.Close()
+	m.closed = true
+	close(m.done)
+}
+
+// Input returns a new Output for a chain. Its frames are merged with the frames
+// of all other inputs of this merger.
+func (m *OutputMerger) Input(chainID string, priority int) Output {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	source := &mergeSource{merger: m, chainID: chainID, priority: priority}
+	m.sources = append(m.sources, source)
+	return source
+}
+
+// Send stores the chain's frame, the merger sends it with the frames of the other
+// chains. Errors of the shared output are reported by the merger.
+func (s *mergeSource) Send(lamps []dmx.Lamp) error {
+	return s.sendFrame(lamps, 0)
+}
+
+// sendFrame is Send for a chain ticking at tickRate, which limits how often the
+// merger sends. 0 keeps the last known tick rate.
+func (s *mergeSource) sendFrame(lamps []dmx.Lamp, tickRate int) error {
+	m := s.merger
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if tickRate > 0 {
+		s.tickRate = tickRate
+	}
+	s.active = true
+	m.stamp++
+	if len(s.frame) != len(lamps) {
+		s.frame = make([]dmx.Lamp, len(lamps))
+		s.changed = make([][4]uint64, len(lamps))
+	}
+	for i, lamp := range lamps {
+		old := lampChannels(s.frame[i])
+		for c, value := range lampChannels(lamp) {
+			if value != old[c] {
+				s.changed[i][c] = m.stamp
+			}
+		}
+		s.frame[i] = lamp
+	}
+	m.signal()
+	return nil
+}
+
+// withdraw stops merging the chain's frame until it sends again, so a disabled
+// chain gives way to the other chains instead of sending black.
+func (s *mergeSource) withdraw() {
+	m := s.merger
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if s.active {
+		s.active = false
+		m.signal()
+	}
+}
+
+// Close removes the chain from the merger. The shared output is closed
+// once the last chain is gone.
+func (s *mergeSource) Close() {
+	m := s.merger
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, source := range m.sources {
+		if source == s {
+			m.sources = append(m.sources[:i], m.sources[i+1:]...)
+			break
+		}
+	}
+	if len(m.sources) == 0 {
+		m.close()
+	} else if s.active {
+		m.signal()
+	}
+}
+
+// closeIfUnused closes the shared output if no chain sends to it.
+func (m *OutputMerger) closeIfUnused() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.sources) == 0 {
+		m.close()
+	}
+}
+
+// merge combines the latest frames of all active sources according to the merge
+// mode. Must be called with the mutex held.
+func (m *OutputMerger) merge() []dmx.Lamp {
+	numLamps := 0
+	highestPriority := 0
+	anyActive := false
+	for _, source := range m.sources {
+		// The frame size stays when a chain withdraws, the other lamps go black
+		if len(source.frame) > numLamps {
+			numLamps = len(source.frame)
+		}
+		if source.active && (!anyActive || source.priority > highestPriority) {
+			highestPriority = source.priority
+			anyActive = true
+		}
+	}
+	if len(m.merged) != numLamps {
+		m.merged = make([]dmx.Lamp, numLamps)
+	}
+
+	for i := range m.merged {
+		var values [4]uint8
+		var winners [4]*mergeSource // LTP: source that changed the channel last
+		for _, source := range m.sources {
+			if !source.active || i >= len(source.frame) {
+				continue
+			}
+			if m.mode == MergePriority && source.priority < highestPriority {
+				continue
+			}
+			for c, value := range lampChannels(source.frame[i]) {
+				switch m.mode {
+				case MergeLTP:
+					winner := winners[c]
+					if winner == nil || source.changed[i][c] > winner.changed[i][c] ||
+						(source.changed[i][c] == winner.changed[i][c] && source.priority >= winner.priority) {
+						winners[c] = source
+						values[c] = value
+					}
+				default: // HTP, also between chains of equal priority
+					if value > values[c] {
+						values[c] = value
+					}
+				}
+			}
+		}
+		m.merged[i] = dmx.Lamp{R: values[0], G: values[1], B: values[2], W: values[3]}
+	}
+	return m.merged
+}
+
+// lampChannels returns the channels of a lamp as an array for per channel merging.
+func lampChannels(lamp dmx.Lamp) [4]uint8 {
+	return [4]uint8{lamp.R, lamp.G, lamp.B, lamp.W}
+}
@@ -0,0 +1,125 @@
+package orchestrator
+
+import (
+	"godmx/dmx"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingOutput is an Output that keeps the frames sent to it.
+type recordingOutput struct {
+	mutex  sync.Mutex
+	frames [][]dmx.Lamp
+	closed bool
+}
+
+func (r *recordingOutput) Send(lamps []dmx.Lamp) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.frames = append(r.frames, append([]dmx.Lamp(nil), lamps...))
+	return nil
+}
+
+func (r *recordingOutput) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closed = true
+}
+
+func (r *recordingOutput) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.frames)
+}
+
+func (r *recordingOutput) last() []dmx.Lamp {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.frames) == 0 {
+		return nil
+	}
+	return r.frames[len(r.frames)-1]
+}
+
+// waitForFrame waits until the output got a frame equal to want.
+func waitForFrame(t *testing.T, output *recordingOutput, want []dmx.Lamp) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if last := output.last(); len(last) == len(want) {
+			equal := true
+			for i := range want {
+				equal = equal && last[i] == want[i]
+			}
+			if equal {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("last frame = %v, want %v", output.last(), want)
+}
+
+func TestPriorityMergeIgnoresInactiveSources(t *testing.T) {
+	output := &recordingOutput{}
+	merger, err := NewOutputMerger("stage", output, MergePriority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := merger.Input("base", 0).(*mergeSource)
+	overlay := merger.Input("overlay", 10).(*mergeSource)
+	red := []dmx.Lamp{{R: 255}, {R: 255}}
+	blue := []dmx.Lamp{{B: 255}, {B: 255}}
+
+	// The overlay never sent a frame, the base look is output
+	base.Send(red)
+	waitForFrame(t, output, red)
+
+	overlay.Send(blue)
+	waitForFrame(t, output, blue)
+
+	// A disabled overlay gives way to the base look again
+	overlay.withdraw()
+	waitForFrame(t, output, red)
+
+	overlay.Close()
+	base.Close()
+	if !output.closed {
+		t.Error("shared output not closed after the last input")
+	}
+}
+
+func TestMergerSendsAtFastestTickRate(t *testing.T) {
+	output := &recordingOutput{}
+	merger, err := NewOutputMerger("stage", output, MergeHTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const tickRate = 50
+	sources := []*mergeSource{
+		merger.Input("a", 0).(*mergeSource),
+		merger.Input("b", 0).(*mergeSource),
+		merger.Input("c", 0).(*mergeSource),
+	}
+
+	// Three chains at 50 FPS each send 150 frames per second in total
+	start := time.Now()
+	for time.Since(start) < 500*time.Millisecond {
+		for i, source := range sources {
+			source.sendFrame([]dmx.Lamp{{R: uint8(i)}}, tickRate)
+		}
+		time.Sleep(time.Second / tickRate)
+	}
+	for _, source := range sources {
+		source.Close()
+	}
+
+	// Half a second at 50 FPS, with some slack for the scheduler
+	if sent := output.count(); sent > tickRate/2+5 {
+		t.Errorf("sent %d frames in 500ms, want at most about %d", sent, tickRate/2)
+	}
+	if want := []dmx.Lamp{{R: 2}}; output.last()[0] != want[0] {
+		t.Errorf("last frame = %v, want %v", output.last(), want)
+	}
+}
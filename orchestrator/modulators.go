@@ -0,0 +1,258 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/effects"
+	"godmx/types"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// modulatorBank holds the modulators of the running configuration and their state.
+type modulatorBank struct {
+	mutex      sync.Mutex
+	modulators map[string]*modulator
+}
+
+// modulator is a modulator with its state.
+type modulator struct {
+	config    config.ModulatorConfig
+	seed      uint64    // Makes the random values of LFOs differ between modulators
+	triggered time.Time // Last trigger of an envelope, zero if it was never triggered
+	input     float64   // Last position of a MIDI or OSC input (0-1)
+}
+
+// update replaces the modulators, keeping the state of those whose config didn't change.
+func (b *modulatorBank) update(configs map[string]config.ModulatorConfig) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	modulators := make(map[string]*modulator, len(configs))
+	for name, cfg := range configs {
+		if old, ok := b.modulators[name]; ok && reflect.DeepEqual(old.config, cfg) {
+			modulators[name] = old
+			continue
+		}
+		hash := fnv.New64a()
+		hash.Write([]byte(name))
+		modulators[name] = &modulator{config: cfg, seed: hash.Sum64()}
+	}
+	b.modulators = modulators
+}
+
+// value returns the value (0-1) of a modulator at the beat time of globals.
+func (b *modulatorBank) value(name string, globals *types.OrchestratorGlobals, now time.Time) (float64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	m, ok := b.modulators[name]
+	if !ok {
+		return 0, false
+	}
+	switch m.config.Type {
+	case "lfo":
+		return m.lfo(globals.BeatTime), true
+	case "envelope":
+		if m.triggered.IsZero() {
+			return 0, true
+		}
+		return m.envelope(now.Sub(m.triggered).Seconds()), true
+	case "midi", "osc":
+		return m.input, true
+	}
+	return 0, false
+}
+
+// trigger starts the envelopes triggered by an event.
+func (b *modulatorBank) trigger(event string, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, m := range b.modulators {
+		if m.config.Type == "envelope" && m.config.Event == event {
+			m.triggered = now
+		}
+	}
+}
+
+// setInput sets the position (0-1) of the MIDI modulators listening to a control.
+func (b *modulatorBank) setInput(messageType string, number int, position float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, m := range b.modulators {
+		if m.config.Type == "midi" && m.config.MessageType == messageType && (messageType == "pitch_bend" || m.config.Number == number) {
+			m.input = position
+		}
+	}
+}
+
+// setOSCInput sets the position (0-1) of the OSC modulators listening to an address.
+func (b *modulatorBank) setOSCInput(address string, position float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, m := range b.modulators {
+		if m.config.Type == "osc" && m.config.Address == address {
+			m.input = position
+		}
+	}
+}
+
+// lfo returns the value of an LFO at a beat time. All waveforms start at 0 on the
+// beat, except square, which starts high.
+func (m *modulator) lfo(beatTime float64) float64 {
+	beats := m.config.Beats
+	if beats <= 0 {
+		beats = 1
+	}
+	periods := beatTime/beats + m.config.Phase
+	progress := periods - math.Floor(periods)
+	switch m.config.Shape {
+	case "triangle":
+		return 1 - math.Abs(2*progress-1)
+	case "saw":
+		return progress
+	case "square":
+		if progress < 0.5 {
+			return 1
+		}
+		return 0
+	case "random":
+		// Sample and hold, the same value for the whole period
+		return randomValue(m.seed, uint64(int64(math.Floor(periods))))
+	default: // sine
+		return (1 - math.Cos(2*math.Pi*progress)) / 2
+	}
+}
+
+// envelope returns the value of an ADSR envelope the given seconds after its trigger.
+func (m *modulator) envelope(elapsed float64) float64 {
+	cfg := m.config
+	sustain := 1.0
+	if cfg.Sustain != nil {
+		sustain = *cfg.Sustain
+	}
+	if elapsed < cfg.Attack {
+		return elapsed / cfg.Attack
+	}
+	elapsed -= cfg.Attack
+	if elapsed < cfg.Decay {
+		return 1 - (1-sustain)*elapsed/cfg.Decay
+	}
+	elapsed -= cfg.Decay
+	if elapsed < cfg.Hold {
+		return sustain
+	}
+	elapsed -= cfg.Hold
+	if elapsed < cfg.Release {
+		return sustain * (1 - elapsed/cfg.Release)
+	}
+	return 0
+}
+
+// randomValue returns a pseudo random value (0-1) for a period, the same for the
+// same seed and period, so all chains see the same value (splitmix64).
+func randomValue(seed, period uint64) float64 {
+	z := seed + period*0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	z ^= z >> 31
+	return float64(z>>11) / (1 << 53)
+}
+
+// SetModulatorInput sets the position (0-1) of the MIDI modulators listening to a
+// control. number is ignored for pitch bend.
+func (o *Orchestrator) SetModulatorInput(messageType string, number int, position float64) {
+	o.modulators.setInput(messageType, number, position)
+}
+
+// SetOSCInput sets the position of the OSC modulators listening to an address.
+// Values outside of 0-1 are clamped.
+func (o *Orchestrator) SetOSCInput(address string, value float64) {
+	o.modulators.setOSCInput(address, math.Max(0, math.Min(1, value)))
+}
+
+// modulatedParam is an effect arg bound to a modulator, with the range it maps the
+// modulator's value onto.
+type modulatedParam struct {
+	modulator string
+	min, max  float64
+	isInt     bool
+}
+
+// effectModulation applies the modulators an effect's args are bound to.
+type effectModulation struct {
+	params  map[string]modulatedParam
+	values  map[string]float64 // Values applied last
+	lastErr string             // Last error, so it is printed once
+}
+
+// newEffectModulation returns the modulation of an effect's args, nil if no arg is
+// bound to a modulator. The range of a binding defaults to the parameter's range.
+func newEffectModulation(effectType string, args map[string]interface{}) *effectModulation {
+	var m *effectModulation
+	for name, value := range args {
+		binding, ok := config.AsModulatorBinding(value)
+		if !ok {
+			continue
+		}
+		param, ok := effects.FindParameter(effectType, name)
+		if !ok || (param.DataType != "float64" && param.DataType != "int") {
+			continue
+		}
+		modulated := modulatedParam{modulator: binding.Modulator, min: 0, max: 1, isInt: param.DataType == "int"}
+		if min, ok := effects.ToFloat(param.MinValue); ok {
+			modulated.min = min
+		}
+		if max, ok := effects.ToFloat(param.MaxValue); ok {
+			modulated.max = max
+		}
+		if binding.Min != nil {
+			modulated.min = *binding.Min
+		}
+		if binding.Max != nil {
+			modulated.max = *binding.Max
+		}
+		if m == nil {
+			m = &effectModulation{params: make(map[string]modulatedParam), values: make(map[string]float64)}
+		}
+		m.params[name] = modulated
+	}
+	return m
+}
+
+// apply sets the modulated args of an effect to the current values of their
+// modulators. Only the values that changed are passed to the effect.
+func (m *effectModulation) apply(effect types.Effect, instance *effectInstance, bank *modulatorBank, globals *types.OrchestratorGlobals, now time.Time) {
+	setter, ok := effect.(types.ParameterSetter)
+	if !ok {
+		return
+	}
+	changed := make(map[string]interface{})
+	for name, param := range m.params {
+		position, ok := bank.value(param.modulator, globals, now)
+		if !ok {
+			continue // Unknown modulator, the arg keeps its default value
+		}
+		value := param.min + position*(param.max-param.min)
+		if param.isInt {
+			value = math.Round(value)
+		}
+		if previous, ok := m.values[name]; !ok || previous != value {
+			m.values[name] = value
+			changed[name] = value
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	err := setter.SetParameters(changed)
+	if err != nil && err.Error() != m.lastErr {
+		fmt.Printf("Effect '%s' modulation: %v\n", instance.id, err)
+	}
+	m.lastErr = ""
+	if err != nil {
+		m.lastErr = err.Error()
+	}
+}
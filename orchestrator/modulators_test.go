@@ -0,0 +1,224 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"godmx/effects"
+	"godmx/types"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLFO(t *testing.T) {
+	tests := []struct {
+		shape string
+		phase float64
+		want  [4]float64 // At 0, 1/4, 1/2 and 3/4 of the period
+	}{
+		{shape: "sine", want: [4]float64{0, 0.5, 1, 0.5}},
+		{shape: "", want: [4]float64{0, 0.5, 1, 0.5}}, // Sine is the default
+		{shape: "triangle", want: [4]float64{0, 0.5, 1, 0.5}},
+		{shape: "saw", want: [4]float64{0, 0.25, 0.5, 0.75}},
+		{shape: "square", want: [4]float64{1, 1, 0, 0}},
+		{shape: "sine", phase: 0.25, want: [4]float64{0.5, 1, 0.5, 0}},
+		{shape: "triangle", phase: 0.25, want: [4]float64{0.5, 1, 0.5, 0}},
+		{shape: "saw", phase: 0.25, want: [4]float64{0.25, 0.5, 0.75, 0}},
+		{shape: "square", phase: 0.25, want: [4]float64{1, 0, 0, 1}},
+	}
+	for _, test := range tests {
+		m := &modulator{config: config.ModulatorConfig{Type: "lfo", Shape: test.shape, Beats: 4, Phase: test.phase}}
+		for i, want := range test.want {
+			// A period of 4 beats, starting with the second period
+			beatTime := 4 + float64(i)
+			if got := m.lfo(beatTime); math.Abs(got-want) > 1e-9 {
+				t.Errorf("%q phase %v at beat %v = %v, want %v", test.shape, test.phase, beatTime, got, want)
+			}
+		}
+	}
+
+	// The period defaults to one beat
+	m := &modulator{config: config.ModulatorConfig{Type: "lfo", Shape: "saw"}}
+	if got := m.lfo(2.25); math.Abs(got-0.25) > 1e-9 {
+		t.Errorf("saw without beats at beat 2.25 = %v, want 0.25", got)
+	}
+}
+
+func TestLFORandomHoldsForAPeriod(t *testing.T) {
+	m := &modulator{config: config.ModulatorConfig{Type: "lfo", Shape: "random", Beats: 4}, seed: 1}
+	value := m.lfo(4)
+	if value < 0 || value >= 1 {
+		t.Fatalf("value %v out of 0-1", value)
+	}
+	for _, beatTime := range []float64{4.5, 6, 7.999} {
+		if got := m.lfo(beatTime); got != value {
+			t.Errorf("value at beat %v = %v, want %v like at the start of the period", beatTime, got, value)
+		}
+	}
+	if got := m.lfo(8); got == value {
+		t.Errorf("the next period has the same value %v", got)
+	}
+	other := &modulator{config: m.config, seed: 2}
+	if got := other.lfo(4); got == value {
+		t.Errorf("a modulator with another seed has the same value %v", got)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	half := 0.5
+	adsr := config.ModulatorConfig{Type: "envelope", Attack: 1, Decay: 1, Sustain: &half, Hold: 2, Release: 2}
+	noSustain := config.ModulatorConfig{Type: "envelope", Attack: 1, Decay: 1, Hold: 1, Release: 1}
+	noAttack := config.ModulatorConfig{Type: "envelope", Sustain: &half, Decay: 1, Release: 1}
+	tests := []struct {
+		name    string
+		config  config.ModulatorConfig
+		elapsed float64
+		want    float64
+	}{
+		{name: "trigger", config: adsr, elapsed: 0, want: 0},
+		{name: "attack", config: adsr, elapsed: 0.5, want: 0.5},
+		{name: "end of attack", config: adsr, elapsed: 1, want: 1},
+		{name: "decay", config: adsr, elapsed: 1.5, want: 0.75},
+		{name: "end of decay", config: adsr, elapsed: 2, want: 0.5},
+		{name: "hold", config: adsr, elapsed: 3.9, want: 0.5},
+		{name: "end of hold", config: adsr, elapsed: 4, want: 0.5},
+		{name: "release", config: adsr, elapsed: 5, want: 0.25},
+		{name: "end of release", config: adsr, elapsed: 6, want: 0},
+		{name: "long after", config: adsr, elapsed: 100, want: 0},
+		{name: "sustain defaults to 1 in decay", config: noSustain, elapsed: 1.5, want: 1},
+		{name: "sustain defaults to 1 in hold", config: noSustain, elapsed: 2.5, want: 1},
+		{name: "release from the default sustain", config: noSustain, elapsed: 3.5, want: 0.5},
+		{name: "without attack at the peak at once", config: noAttack, elapsed: 0, want: 1},
+		{name: "without hold release after decay", config: noAttack, elapsed: 1.5, want: 0.25},
+	}
+	for _, test := range tests {
+		m := &modulator{config: test.config}
+		if got := m.envelope(test.elapsed); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: value after %vs = %v, want %v", test.name, test.elapsed, got, test.want)
+		}
+	}
+}
+
+func TestEnvelopeTrigger(t *testing.T) {
+	var bank modulatorBank
+	bank.update(map[string]config.ModulatorConfig{"swell": {Type: "envelope", Event: "hit", Attack: 2, Release: 1}})
+	start := time.Unix(1000, 0)
+	globals := &types.OrchestratorGlobals{}
+	if value, ok := bank.value("swell", globals, start); !ok || value != 0 {
+		t.Errorf("untriggered envelope = %v, %t, want 0", value, ok)
+	}
+	bank.trigger("other", start)
+	bank.trigger("hit", start)
+	if value, _ := bank.value("swell", globals, start.Add(time.Second)); value != 0.5 {
+		t.Errorf("value a second after the trigger = %v, want 0.5", value)
+	}
+}
+
+func TestNewEffectModulation(t *testing.T) {
+	binding := func(min, max interface{}) map[string]interface{} {
+		b := map[string]interface{}{"modulator": "wobble"}
+		if min != nil {
+			b["min"] = min
+		}
+		if max != nil {
+			b["max"] = max
+		}
+		return b
+	}
+	tests := []struct {
+		name       string
+		effectType string
+		args       map[string]interface{}
+		want       map[string]modulatedParam // nil for no modulation
+	}{
+		{
+			name:       "range of the parameter",
+			effectType: "dim",
+			args:       map[string]interface{}{"percentage": binding(nil, nil)},
+			want:       map[string]modulatedParam{"percentage": {modulator: "wobble", min: 0, max: 1}},
+		},
+		{
+			name:       "int parameter",
+			effectType: "cyberfall",
+			args:       map[string]interface{}{"max_brightness": binding(nil, nil)},
+			want:       map[string]modulatedParam{"max_brightness": {modulator: "wobble", min: 0, max: 255, isInt: true}},
+		},
+		{
+			name:       "parameter without a maximum",
+			effectType: "cyberfall",
+			args:       map[string]interface{}{"trail_length": binding(nil, nil)},
+			want:       map[string]modulatedParam{"trail_length": {modulator: "wobble", min: 0, max: 1, isInt: true}},
+		},
+		{
+			name:       "range of the binding",
+			effectType: "cyberfall",
+			args:       map[string]interface{}{"max_brightness": binding(50.0, 100)},
+			want:       map[string]modulatedParam{"max_brightness": {modulator: "wobble", min: 50, max: 100, isInt: true}},
+		},
+		{
+			name:       "only the minimum of the binding",
+			effectType: "dim",
+			args:       map[string]interface{}{"percentage": binding(0.2, nil)},
+			want:       map[string]modulatedParam{"percentage": {modulator: "wobble", min: 0.2, max: 1}},
+		},
+		{
+			name:       "inverted range",
+			effectType: "dim",
+			args:       map[string]interface{}{"percentage": binding(1.0, 0.0)},
+			want:       map[string]modulatedParam{"percentage": {modulator: "wobble", min: 1, max: 0}},
+		},
+		{
+			name:       "only bound args",
+			effectType: "cyberfall",
+			args:       map[string]interface{}{"speed": 2.0, "density": binding(nil, nil)},
+			want:       map[string]modulatedParam{"density": {modulator: "wobble", min: 0, max: 1}},
+		},
+		{name: "no binding", effectType: "dim", args: map[string]interface{}{"percentage": 0.5}},
+		{name: "unknown parameter", effectType: "dim", args: map[string]interface{}{"speed": binding(nil, nil)}},
+	}
+	for _, test := range tests {
+		m := newEffectModulation(test.effectType, test.args)
+		if test.want == nil {
+			if m != nil {
+				t.Errorf("%s: unexpected modulation %+v", test.name, m.params)
+			}
+			continue
+		}
+		if m == nil || !reflect.DeepEqual(m.params, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, m, test.want)
+		}
+	}
+}
+
+func TestEffectModulationRoundsInts(t *testing.T) {
+	var bank modulatorBank
+	bank.update(map[string]config.ModulatorConfig{"ramp": {Type: "lfo", Shape: "saw", Beats: 4}})
+	effect, err := effects.NewCyberfall(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance := &effectInstance{id: "rain", effectType: "cyberfall"}
+	m := newEffectModulation("cyberfall", map[string]interface{}{
+		"max_brightness": map[string]interface{}{"modulator": "ramp", "min": 0.0, "max": 3.0},
+		"density":        map[string]interface{}{"modulator": "ramp"},
+	})
+
+	for _, test := range []struct {
+		beatTime      float64
+		maxBrightness uint8
+		density       float64
+	}{
+		{beatTime: 0, maxBrightness: 0, density: 0},
+		{beatTime: 1, maxBrightness: 1, density: 0.25}, // 0.75 rounds up
+		{beatTime: 2, maxBrightness: 2, density: 0.5},  // 1.5 rounds away from zero
+		{beatTime: 2.2, maxBrightness: 2, density: 0.55},
+		{beatTime: 3, maxBrightness: 2, density: 0.75}, // 2.25 rounds down
+	} {
+		globals := &types.OrchestratorGlobals{BeatTime: test.beatTime}
+		m.apply(effect, instance, &bank, globals, time.Now())
+		c := effect.(*effects.Cyberfall)
+		if c.MaxBrightness != test.maxBrightness || math.Abs(c.Density-test.density) > 1e-9 {
+			t.Errorf("at beat %v: max brightness %d, density %v, want %d, %v", test.beatTime, c.MaxBrightness, c.Density, test.maxBrightness, test.density)
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package orchestrator
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"godmx/config"
 	"godmx/dmx"
+	"godmx/effects"
 	"godmx/types"
 	"godmx/utils"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,8 +26,22 @@ type Output interface {
 type Orchestrator struct {
 	chains       []*Chain
 	config       *config.Config
-	globals      types.OrchestratorGlobals
-	lastBeatTime time.Time
+	globals      types.OrchestratorGlobals // Current globals, advanced by the beat clock
+	lastBeatTime time.Time                 // Time of the last clock update
+	mutex        sync.RWMutex              // Guards globals and lastBeatTime
+	configMutex  sync.Mutex                // Guards config and chains
+	tapTempo     TapTempo                  // Tempo estimation for Tap, guarded by mutex
+	clockSource  string                    // What drives the beat, guarded by mutex
+	external     externalClock             // External clock state, guarded by mutex
+	mergers      map[string]*OutputMerger  // Shared outputs by ID, guarded by configMutex
+	configPath   string                    // File the configuration is saved to
+	savedConfig  [sha256.Size]byte         // Hash of the config file content last saved or loaded, guarded by configMutex
+	factory      OutputFactory             // Creates outputs, guarded by applyMutex
+	applyMutex   sync.Mutex                // Serializes Start and ApplyConfig
+	preview      framePreview              // Last frames of the chains for the web preview
+	modulators   modulatorBank             // Modulators effect args can be bound to
+	cues         cuePlayer                 // Playback state of the cue lists
+	scheduler    actionScheduler           // Delayed actions of events and cues
 }
 
 // NewOrchestrator creates a new Orchestrator instance.
@@ -32,18 +52,67 @@ func NewOrchestrator(cfg *config.Config) *Orchestrator {
 			BPM:       cfg.Globals.BPM,
 			Color1:    func() dmx.Lamp { c, _ := utils.ParseHexColor(cfg.Globals.Color1); return c }(),
 			Color2:    func() dmx.Lamp { c, _ := utils.ParseHexColor(cfg.Globals.Color2); return c }(),
+			Intensity: 255,
 		},
 		lastBeatTime: time.Now(),
+		configPath:   "config.json",
 	}
+	if cfg.Globals.Intensity != nil {
+		o.SetIntensity(*cfg.Globals.Intensity)
+	}
+	o.SetTimeSignature(cfg.Globals.BeatsPerBar, cfg.Globals.BarsPerPhrase)
+	o.SetClockSource(cfg.Globals.ClockSource)
 	return o
 }
 
 // AddChain adds a new chain to the orchestrator.
 func (o *Orchestrator) AddChain(chain *Chain) {
+	o.configMutex.Lock()
+	defer o.configMutex.Unlock()
 	o.chains = append(o.chains, chain)
 }
 
+// SetConfigPath sets the file the configuration is saved to.
+func (o *Orchestrator) SetConfigPath(path string) {
+	o.configMutex.Lock()
+	defer o.configMutex.Unlock()
+	o.configPath = path
+}
+
+// SaveConfig saves the running configuration to its file.
+func (o *Orchestrator) SaveConfig() error {
+	o.configMutex.Lock()
+	defer o.configMutex.Unlock()
+	return o.saveConfigLocked(o.config)
+}
+
+// saveConfigLocked saves cfg to the config file and remembers its content, so the
+// watcher doesn't reload the file the orchestrator wrote itself. Must be called with
+// configMutex held.
+func (o *Orchestrator) saveConfigLocked(cfg *config.Config) error {
+	data, err := config.MarshalConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(o.configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	o.savedConfig = sha256.Sum256(data)
+	return nil
+}
+
+// ReadConfig calls fn with the running configuration while no action can modify it.
+// fn must not keep references to the configuration after it returns.
+func (o *Orchestrator) ReadConfig(fn func(cfg *config.Config)) {
+	o.configMutex.Lock()
+	defer o.configMutex.Unlock()
+	fn(o.config)
+}
+
+// findChain returns the runtime chain with the given ID.
 func (o *Orchestrator) findChain(chainID string) (*Chain, error) {
+	o.configMutex.Lock()
+	defer o.configMutex.Unlock()
 	for _, chain := range o.chains {
 		if chain.ID == chainID {
 			return chain, nil
@@ -54,50 +123,103 @@ func (o *Orchestrator) findChain(chainID string) (*Chain, error) {
 
 // SetBPM sets the global BPM.
 func (o *Orchestrator) SetBPM(bpm float64) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 	o.globals.BPM = bpm
 }
 
 // SetColor1 sets the global Color1.
 func (o *Orchestrator) SetColor1(color dmx.Lamp) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 	o.globals.Color1 = color
 }
 
 // SetColor2 sets the global Color2.
 func (o *Orchestrator) SetColor2(color dmx.Lamp) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 	o.globals.Color2 = color
 }
 
-// GetGlobals returns a pointer to the orchestrator's global parameters.
-func (o *Orchestrator) GetGlobals() *types.OrchestratorGlobals {
-	return &o.globals
+// SetIntensity sets the grand master intensity (0-255).
+func (o *Orchestrator) SetIntensity(intensity int) {
+	if intensity < 0 {
+		intensity = 0
+	} else if intensity > 255 {
+		intensity = 255
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.globals.Intensity = intensity
 }
 
-// UpdateBeatProgress calculates and updates the global beat progress.
-func (o *Orchestrator) UpdateBeatProgress() {
-	elapsed := time.Since(o.lastBeatTime)
-	beatDuration := time.Duration((60.0 / o.globals.BPM) * float64(time.Second))
-	o.globals.BeatProgress = float64(elapsed) / float64(beatDuration)
-
-	if o.globals.BeatProgress >= 1.0 {
-		o.lastBeatTime = time.Now()
-		o.globals.BeatProgress = 0.0
+// SetTimeSignature sets the number of beats per bar and bars per phrase.
+// Values below 1 fall back to 4 beats per bar and 16 bars per phrase.
+func (o *Orchestrator) SetTimeSignature(beatsPerBar int, barsPerPhrase int) {
+	if beatsPerBar < 1 {
+		beatsPerBar = 4
+	}
+	if barsPerPhrase < 1 {
+		barsPerPhrase = 16
 	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.globals.BeatsPerBar = beatsPerBar
+	o.globals.BarsPerPhrase = barsPerPhrase
+	o.updateBeatPosition()
+}
+
+// SetBlackout enables or disables the blackout of all chains.
+func (o *Orchestrator) SetBlackout(blackout bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.globals.Blackout = blackout
+}
+
+// ToggleBlackout inverts the blackout state and returns the new state.
+func (o *Orchestrator) ToggleBlackout() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.globals.Blackout = !o.globals.Blackout
+	return o.globals.Blackout
 }
 
-// TriggerEvent finds an event by name in the config and executes its actions.
+// GetGlobals returns a snapshot of the orchestrator's global parameters.
+// The snapshot is a copy and never changes, it is safe to use from any goroutine.
+func (o *Orchestrator) GetGlobals() types.OrchestratorGlobals {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.globals
+}
+
+// TriggerEvent finds an event by name in the config and executes its actions. Delayed
+// actions still pending from the last trigger of the event are cancelled.
 func (o *Orchestrator) TriggerEvent(eventName string) {
+	if err := o.triggerEvent(eventName, nil); err != nil {
+		fmt.Printf("Error triggering event: %v\n", err)
+	}
+}
+
+// triggerEvent is TriggerEvent from the actions of the events in triggeredBy. An event
+// already in triggeredBy isn't triggered again, the events would trigger each other endlessly.
+func (o *Orchestrator) triggerEvent(eventName string, triggeredBy []string) error {
+	for _, name := range triggeredBy {
+		if name == eventName {
+			return fmt.Errorf("event cycle %s -> %s, not triggering '%s' again", strings.Join(triggeredBy, " -> "), eventName, eventName)
+		}
+	}
+	o.configMutex.Lock()
 	actions, ok := o.config.Actions[eventName]
+	o.configMutex.Unlock()
 	if !ok {
-		fmt.Printf("Event '%s' not found.\n", eventName)
-		return
+		return fmt.Errorf("event '%s' not found", eventName)
 	}
 
 	fmt.Printf("Triggering event '%s'\n", eventName)
-	for _, action := range actions {
-		if err := o.executeAction(action); err != nil {
-			fmt.Printf("  - Error executing action '%s': %v\n", action.Type, err)
-		}
-	}
+	o.modulators.trigger(eventName, time.Now())
+	o.runActions(eventName, actions, append(triggeredBy[:len(triggeredBy):len(triggeredBy)], eventName))
+	return nil
 }
 
 func mapToEffectConfig(params map[string]interface{}) (config.EffectConfig, error) {
@@ -112,65 +234,270 @@ func mapToEffectConfig(params map[string]interface{}) (config.EffectConfig, erro
 	return effectConfig, nil
 }
 
-// executeAction executes a single action from an event.
-func (o *Orchestrator) executeAction(action config.ActionConfig) error {
+// updateConfig calls fn with the running configuration while holding the config lock.
+// Runtime chains must be marked dirty by the caller after fn returns.
+func (o *Orchestrator) updateConfig(fn func(cfg *config.Config) error) error {
+	o.configMutex.Lock()
+	defer o.configMutex.Unlock()
+	return fn(o.config)
+}
+
+// markChainDirty marks a runtime chain for a rebuild of its effects on its next tick.
+func (o *Orchestrator) markChainDirty(chainID string) {
+	chain, err := o.findChain(chainID)
+	if err == nil {
+		chain.SetDirty(true)
+	}
+}
+
+// SetEffectParam changes a single arg of an effect at runtime, without rebuilding the
+// chain. Effects implementing types.ParameterSetter keep their running state, others
+// are recreated. The change is not saved to the config file.
+func (o *Orchestrator) SetEffectParam(chainID, effectID, param string, value interface{}) error {
+	chain, err := o.findChain(chainID)
+	if err != nil {
+		return err
+	}
+
+	var args map[string]interface{}
+	err = o.updateConfig(func(cfg *config.Config) error {
+		effectConfig, err := cfg.FindEffect(chainID, effectID)
+		if err != nil {
+			return err
+		}
+		metadata, ok := effects.FindParameter(effectConfig.Type, param)
+		if !ok {
+			return fmt.Errorf("effect '%s' has no parameter '%s'", effectID, param)
+		}
+		// Reject invalid values before they end up in the config
+		if err := config.CheckEffectArg(metadata, value); err != nil {
+			return fmt.Errorf("effect '%s': %w", effectID, err)
+		}
+		if err := cfg.SetEffectArg(chainID, effectID, param, value); err != nil {
+			return err
+		}
+		// Copy the args, the config map may change before the chain applies them
+		args = copyArgs(effectConfig.Args)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	chain.setEffectArgs(effectID, args)
+	return nil
+}
+
+// GetEffectParam returns the current value of an effect arg, or its default value if it isn't set.
+func (o *Orchestrator) GetEffectParam(chainID, effectID, param string) (interface{}, error) {
+	o.configMutex.Lock()
+	defer o.configMutex.Unlock()
+	effectConfig, err := o.config.FindEffect(chainID, effectID)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := effectConfig.Args[param]; ok {
+		return value, nil
+	}
+	metadata, ok := effects.FindParameter(effectConfig.Type, param)
+	if !ok {
+		return nil, fmt.Errorf("effect '%s' has no parameter '%s'", effectID, param)
+	}
+	return metadata.DefaultValue, nil
+}
+
+// stringList converts a list param from JSON to strings. Values that aren't strings are skipped.
+func stringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// executeAction executes a single action from an event. triggeredBy are the events
+// that triggered it, without a delay in between.
+func (o *Orchestrator) executeAction(action config.ActionConfig, triggeredBy []string) error {
 	fmt.Printf("  - Executing action: %s\n", action.Type)
 	var err error
 
 	switch action.Type {
 	case "add_effect":
-		effectConfig, err := mapToEffectConfig(action.Params)
-		if err != nil {
-			return err
+		effectConfig, mapErr := mapToEffectConfig(action.Params)
+		if mapErr != nil {
+			return mapErr
 		}
-		err = o.config.AddEffectToChain(action.ChainID, effectConfig)
+		err = o.updateConfig(func(cfg *config.Config) error {
+			return cfg.AddEffectToChain(action.ChainID, effectConfig)
+		})
 		if err == nil {
-			chain, findErr := o.findChain(action.ChainID)
-			if findErr == nil {
-				chain.SetDirty(true)
-			}
+			o.markChainDirty(action.ChainID)
 		}
 	case "remove_effect":
-		err = o.config.RemoveEffectFromChain(action.ChainID, action.EffectID)
+		err = o.updateConfig(func(cfg *config.Config) error {
+			return cfg.RemoveEffectFromChain(action.ChainID, action.EffectID)
+		})
 		if err == nil {
-			chain, findErr := o.findChain(action.ChainID)
-			if findErr == nil {
-				chain.SetDirty(true)
-			}
+			o.markChainDirty(action.ChainID)
 		}
 	case "toggle_effect":
 		enabled, ok := action.Params["enabled"].(bool)
 		if !ok {
 			return fmt.Errorf("missing or invalid 'enabled' param for toggle_effect")
 		}
-		err = o.config.ToggleEffectInChain(action.ChainID, action.EffectID, enabled)
+		err = o.updateConfig(func(cfg *config.Config) error {
+			return cfg.ToggleEffectInChain(action.ChainID, action.EffectID, enabled)
+		})
 		if err == nil {
-			chain, findErr := o.findChain(action.ChainID)
-			if findErr == nil {
-				chain.SetDirty(true)
-			}
+			o.markChainDirty(action.ChainID)
 		}
 	case "set_global":
-		for key, val := range action.Params {
-			err = o.config.SetGlobal(key, val)
-			// Also update the running orchestrator's globals
-			if err == nil {
-				o.SetBPM(o.config.Globals.BPM)
-				color1, err1 := utils.ParseHexColor(o.config.Globals.Color1)
-				if err1 == nil {
+		var globals config.GlobalsConfig
+		err = o.updateConfig(func(cfg *config.Config) error {
+			for key, val := range action.Params {
+				if err := cfg.SetGlobal(key, val); err != nil {
+					return err
+				}
+			}
+			globals = cfg.Globals
+			// Save config after modification
+			if saveErr := o.saveConfigLocked(cfg); saveErr != nil {
+				fmt.Printf("Error saving config after set_global: %v\n", saveErr)
+			}
+			return nil
+		})
+		// Also update the running orchestrator's globals. Only the changed ones,
+		// so e.g. a color change doesn't undo a tapped or MIDI clocked BPM.
+		if err == nil {
+			changed := func(key string) bool {
+				_, ok := action.Params[key]
+				return ok
+			}
+			if changed("bpm") {
+				o.SetBPM(globals.BPM)
+			}
+			if changed("color1") {
+				if color1, err1 := utils.ParseHexColor(globals.Color1); err1 == nil {
 					o.SetColor1(color1)
 				}
-				color2, err2 := utils.ParseHexColor(o.config.Globals.Color2)
-				if err2 == nil {
+			}
+			if changed("color2") {
+				if color2, err2 := utils.ParseHexColor(globals.Color2); err2 == nil {
 					o.SetColor2(color2)
 				}
 			}
+			if changed("intensity") && globals.Intensity != nil {
+				o.SetIntensity(*globals.Intensity)
+			}
+			if changed("beats_per_bar") || changed("bars_per_phrase") {
+				o.SetTimeSignature(globals.BeatsPerBar, globals.BarsPerPhrase)
+			}
+			if changed("blackout") {
+				o.SetBlackout(globals.Blackout)
+			}
+			if changed("clock_source") {
+				o.SetClockSource(globals.ClockSource)
+			}
+		}
+	case "set_effect_param":
+		// The arg is applied in place, without a rebuild of the chain
+		param, _ := action.Params["param"].(string)
+		if param == "" {
+			return fmt.Errorf("missing or invalid 'param' param for set_effect_param")
+		}
+		value, ok := action.Params["value"]
+		if !ok {
+			return fmt.Errorf("missing 'value' param for set_effect_param")
+		}
+		err = o.SetEffectParam(action.ChainID, action.EffectID, param, value)
+	case "tap_tempo":
+		bpm := o.Tap()
+		fmt.Printf("    Tapped, BPM is now %.2f\n", bpm)
+	case "resync_beat":
+		align, _ := action.Params["align"].(string)
+		if align != "" && align != "beat" && align != "bar" && align != "phrase" {
+			return fmt.Errorf("invalid 'align' param for resync_beat: %s", align)
+		}
+		o.ResyncBeat(align)
+	case "save_scene":
+		name, _ := action.Params["name"].(string)
+		if name == "" {
+			return fmt.Errorf("missing or invalid 'name' param for save_scene")
+		}
+		err = o.SaveScene(name)
+	case "recall_scene":
+		name, _ := action.Params["name"].(string)
+		if name == "" {
+			return fmt.Errorf("missing or invalid 'name' param for recall_scene")
 		}
-		// Save config after modification
+		fade, _ := action.Params["fade"].(float64)
+		err = o.RecallScene(name, time.Duration(fade*float64(time.Second)))
+	case "trigger_event":
+		event, _ := action.Params["event"].(string)
+		err = o.triggerEvent(event, triggeredBy)
+	case "random_event":
+		events := stringList(action.Params["events"])
+		if len(events) == 0 {
+			return fmt.Errorf("missing or invalid 'events' param for random_event")
+		}
+		err = o.triggerEvent(events[rand.Intn(len(events))], triggeredBy)
+	case "set_chain_tickrate":
+		tickRate, ok := action.Params["tick_rate"].(float64)
+		if !ok {
+			return fmt.Errorf("missing or invalid 'tick_rate' param for set_chain_tickrate")
+		}
+		chain, findErr := o.findChain(action.ChainID)
+		if findErr != nil {
+			return findErr
+		}
+		err = o.updateConfig(func(cfg *config.Config) error {
+			return cfg.SetChainTickRate(action.ChainID, int(tickRate))
+		})
 		if err == nil {
-			if saveErr := config.SaveConfig(o.config, "config.json"); saveErr != nil {
-				fmt.Printf("Error saving config after set_global: %v\n", saveErr)
-			}
+			chain.setTickRate(int(tickRate))
+		}
+	case "move_effect":
+		position, ok := action.Params["position"].(float64)
+		if !ok {
+			return fmt.Errorf("missing or invalid 'position' param for move_effect")
+		}
+		err = o.updateConfig(func(cfg *config.Config) error {
+			return cfg.MoveEffectInChain(action.ChainID, action.EffectID, int(position))
+		})
+		if err == nil {
+			o.markChainDirty(action.ChainID)
+		}
+	case "toggle_chain":
+		enabled, ok := action.Params["enabled"].(bool)
+		if !ok {
+			return fmt.Errorf("missing or invalid 'enabled' param for toggle_chain")
+		}
+		chain, findErr := o.findChain(action.ChainID)
+		if findErr != nil {
+			return findErr
+		}
+		err = o.updateConfig(func(cfg *config.Config) error {
+			return cfg.SetChainEnabled(action.ChainID, enabled)
+		})
+		if err == nil {
+			chain.setEnabled(enabled)
+		}
+	case "wait":
+		// Nothing to do, the scheduler holds back the actions after it
+	case "cue_go", "cue_back", "cue_jump", "cue_stop":
+		list, _ := action.Params["cue_list"].(string)
+		switch action.Type {
+		case "cue_go":
+			err = o.CueGo(list)
+		case "cue_back":
+			err = o.CueBack(list)
+		case "cue_jump":
+			cue, _ := action.Params["cue"].(string)
+			err = o.CueJump(list, cue)
+		case "cue_stop":
+			err = o.CueStop(list)
 		}
 	default:
 		err = fmt.Errorf("unknown action type: %s", action.Type)
@@ -178,4 +505,3 @@ func (o *Orchestrator) executeAction(action config.ActionConfig) error {
 
 	return err
 }
-
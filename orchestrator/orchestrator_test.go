@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"godmx/effects"
+	"strings"
+	"testing"
+)
+
+func TestSetEffectParamAction(t *testing.T) {
+	cfg := &config.Config{
+		Globals: config.GlobalsConfig{BPM: 120, Color1: "#FF0000", Color2: "#0000FF", BeatsPerBar: 4, BarsPerPhrase: 4},
+		Chains: []config.ChainConfig{{
+			ID:       "main",
+			TickRate: 30,
+			NumLamps: 4,
+			Output:   config.OutputConfig{Type: "artnet"},
+			Effects: []config.EffectConfig{
+				{ID: "dim", Type: "dim", Args: map[string]interface{}{"percentage": 0.5}},
+			},
+		}},
+	}
+	o := NewOrchestrator(cfg)
+	chain := NewChain(&cfg.Chains[0], o, discardOutput{})
+	o.AddChain(chain)
+	if err := chain.Tick(); err != nil {
+		t.Fatal(err)
+	}
+
+	action := func(params map[string]interface{}) config.ActionConfig {
+		return config.ActionConfig{Type: "set_effect_param", ChainID: "main", EffectID: "dim", Params: params}
+	}
+	if err := o.executeAction(action(map[string]interface{}{"param": "percentage", "value": 0.25}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	if got := chain.Effects[0].(*effects.Dim).Percentage; got != 0.25 {
+		t.Errorf("percentage = %v, want 0.25", got)
+	}
+
+	for name, params := range map[string]map[string]interface{}{
+		"missing param":      {"value": 0.1},
+		"missing value":      {"param": "percentage"},
+		"unknown param":      {"param": "speed", "value": 0.1},
+		"invalid value":      {"param": "percentage", "value": "half"},
+		"old style args map": {"percentage": 0.1},
+	} {
+		if err := o.executeAction(action(params), nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		issues := ValidateConfig(&config.Config{
+			Globals: cfg.Globals,
+			Chains:  cfg.Chains,
+			Actions: map[string][]config.ActionConfig{"set": {action(params)}},
+		})
+		if err := issues.Err(); err == nil || !strings.Contains(err.Error(), "actions.set[0].params") {
+			t.Errorf("%s: expected a validation error of the params, got %v", name, err)
+		}
+	}
+	if got := chain.Effects[0].(*effects.Dim).Percentage; got != 0.25 {
+		t.Errorf("percentage = %v after invalid actions, want 0.25", got)
+	}
+}
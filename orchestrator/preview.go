@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"godmx/dmx"
+	"sync"
+	"sync/atomic"
+)
+
+// ChainFrame is the last frame a chain sent to its output.
+type ChainFrame struct {
+	ChainID string
+	Lamps   []dmx.Lamp
+	Seq     uint64 // Increases with every tick, so viewers can skip unchanged frames
+}
+
+// framePreview keeps the last frame of every chain while someone watches them.
+// Chains only copy their frames while there are viewers, so the preview costs
+// nothing when unused.
+type framePreview struct {
+	viewers atomic.Int32
+	mutex   sync.Mutex
+	frames  map[string]*ChainFrame
+}
+
+// WatchFrames registers a preview viewer. The returned function unregisters it.
+func (o *Orchestrator) WatchFrames() (stop func()) {
+	o.preview.viewers.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if o.preview.viewers.Add(-1) == 0 {
+				o.preview.mutex.Lock()
+				o.preview.frames = nil
+				o.preview.mutex.Unlock()
+			}
+		})
+	}
+}
+
+// publishFrame stores a copy of a chain's frame if there are viewers.
+func (o *Orchestrator) publishFrame(chainID string, lamps []dmx.Lamp) {
+	if o.preview.viewers.Load() == 0 {
+		return
+	}
+	o.preview.mutex.Lock()
+	defer o.preview.mutex.Unlock()
+	if o.preview.frames == nil {
+		o.preview.frames = make(map[string]*ChainFrame)
+	}
+	frame, ok := o.preview.frames[chainID]
+	if !ok {
+		frame = &ChainFrame{ChainID: chainID}
+		o.preview.frames[chainID] = frame
+	}
+	frame.Lamps = append(frame.Lamps[:0], lamps...)
+	frame.Seq++
+}
+
+// Frames returns copies of the last frames of the running chains, in chain order.
+// Chains that haven't ticked since the first viewer registered are left out.
+func (o *Orchestrator) Frames() []ChainFrame {
+	o.configMutex.Lock()
+	chainIDs := make([]string, len(o.chains))
+	for i, chain := range o.chains {
+		chainIDs[i] = chain.ID
+	}
+	o.configMutex.Unlock()
+
+	o.preview.mutex.Lock()
+	defer o.preview.mutex.Unlock()
+	frames := make([]ChainFrame, 0, len(chainIDs))
+	for _, chainID := range chainIDs {
+		frame, ok := o.preview.frames[chainID]
+		if !ok {
+			continue
+		}
+		frames = append(frames, ChainFrame{
+			ChainID: frame.ChainID,
+			Lamps:   append([]dmx.Lamp(nil), frame.Lamps...),
+			Seq:     frame.Seq,
+		})
+	}
+	return frames
+}
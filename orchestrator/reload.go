@@ -0,0 +1,90 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"godmx/config"
+	"os"
+	"time"
+)
+
+const configPollInterval = time.Second // How often the config file is checked for changes
+
+// WatchConfig reloads the configuration file whenever it changes and applies it
+// to the running show. A change is only picked up once the file stopped changing
+// for one poll interval, so a half written file isn't read. An invalid file is
+// rejected and the running configuration is kept.
+func (o *Orchestrator) WatchConfig() {
+	o.configMutex.Lock()
+	path := o.configPath
+	o.configMutex.Unlock()
+
+	go func() {
+		last, _ := os.Stat(path)
+		var pending os.FileInfo // Changed file waiting to settle
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue // Editors may replace the file, it will be back on the next poll
+			}
+			if !fileChanged(last, info) {
+				pending = nil
+				continue
+			}
+			if pending == nil || fileChanged(pending, info) {
+				pending = info
+				continue
+			}
+			last, pending = info, nil
+			if err := o.ReloadConfig(); err != nil {
+				fmt.Printf("Config reload rejected, keeping the running show: %v\n", err)
+			}
+		}
+	}()
+	fmt.Printf("Watching %s for changes.\n", path)
+}
+
+// fileChanged reports whether a file changed between two stats.
+func fileChanged(before, after os.FileInfo) bool {
+	if before == nil {
+		return true
+	}
+	return !before.ModTime().Equal(after.ModTime()) || before.Size() != after.Size()
+}
+
+// ReloadConfig reads the configuration file and applies it. Nothing happens if
+// its content is what the orchestrator saved or loaded last, e.g. after
+// set_global saved the running configuration.
+func (o *Orchestrator) ReloadConfig() error {
+	o.configMutex.Lock()
+	path := o.configPath
+	o.configMutex.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	o.configMutex.Lock()
+	unchanged := sum == o.savedConfig
+	o.configMutex.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	newCfg, err := config.ParseConfig(data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Config file %s changed, reloading...\n", path)
+	if err := o.ApplyConfig(newCfg); err != nil {
+		return err
+	}
+	o.configMutex.Lock()
+	o.savedConfig = sum
+	o.configMutex.Unlock()
+	return nil
+}
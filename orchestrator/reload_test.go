@@ -0,0 +1,57 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadSkipsOwnSave(t *testing.T) {
+	cfg := &config.Config{
+		Globals: config.GlobalsConfig{BPM: 120, Color1: "#FF0000", Color2: "#0000FF", BeatsPerBar: 4, BarsPerPhrase: 4},
+		Actions: map[string][]config.ActionConfig{},
+	}
+	o := NewOrchestrator(cfg)
+	path := filepath.Join(t.TempDir(), "config.json")
+	o.SetConfigPath(path)
+	if err := o.Start(func(config.OutputConfig) (Output, error) { return discardOutput{}, nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	// set_global saves the config, runtime-only state like blackout isn't in the file
+	if err := o.executeAction(config.ActionConfig{Type: "set_global", Params: map[string]interface{}{"bpm": 128.0}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	o.ReadConfig(func(cfg *config.Config) { cfg.Globals.Blackout = true })
+	if err := o.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	o.ReadConfig(func(cfg *config.Config) {
+		if cfg != o.config || !cfg.Globals.Blackout {
+			t.Error("the saved config was reloaded")
+		}
+	})
+
+	// An edit of the file is applied
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited, err := config.ParseConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited.Globals.BPM = 90
+	if err := config.SaveConfig(edited, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	o.ReadConfig(func(cfg *config.Config) {
+		if cfg.Globals.BPM != 90 {
+			t.Errorf("BPM = %v after the edit, want 90", cfg.Globals.BPM)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/dmx"
+	"godmx/types"
+	"godmx/utils"
+	"time"
+)
+
+// SaveScene saves the current look, the effects of all chains and the running
+// globals, as a scene of the configuration and saves the configuration to its file.
+func (o *Orchestrator) SaveScene(name string) error {
+	globals := o.GetGlobals()
+	return o.updateConfig(func(cfg *config.Config) error {
+		// The running globals, a tapped BPM or a color set by MIDI is part of the look
+		sceneGlobals := cfg.Globals
+		sceneGlobals.BPM = globals.BPM
+		sceneGlobals.Color1 = utils.FormatHexColor(globals.Color1)
+		sceneGlobals.Color2 = utils.FormatHexColor(globals.Color2)
+		intensity := globals.Intensity
+		sceneGlobals.Intensity = &intensity
+		if err := cfg.SaveScene(name, sceneGlobals); err != nil {
+			return err
+		}
+		if err := o.saveConfigLocked(cfg); err != nil {
+			fmt.Printf("Error saving config after save_scene: %v\n", err)
+		}
+		return nil
+	})
+}
+
+// RecallScene applies the effects and globals of a scene. With a fade, the chains
+// whose effects change crossfade from the frames of their old effects, which keep
+// running during the fade, to the frames of the new ones. Without one, effects
+// with the same ID keep their state.
+func (o *Orchestrator) RecallScene(name string, fade time.Duration) error {
+	globals := o.GetGlobals()
+	var recalled *config.Config
+	var err error
+	o.ReadConfig(func(cfg *config.Config) {
+		recalled, err = cfg.WithScene(name)
+	})
+	if err != nil {
+		return err
+	}
+
+	var sf *sceneFade
+	if fade > 0 {
+		sf = &sceneFade{duration: fade, color1: globals.Color1, color2: globals.Color2}
+	}
+	if err := o.applyConfig(recalled, sf); err != nil {
+		return err
+	}
+
+	// Also the globals that only changed at runtime, e.g. a tapped BPM
+	if scene := recalled.Scenes[name]; scene.Globals != nil {
+		running := recalled.Globals
+		running.BPM = globals.BPM
+		running.Color1 = utils.FormatHexColor(globals.Color1)
+		running.Color2 = utils.FormatHexColor(globals.Color2)
+		intensity := globals.Intensity
+		running.Intensity = &intensity
+		o.applyGlobals(running, *scene.Globals)
+	}
+	return nil
+}
+
+// sceneFade is the crossfade of a scene recall.
+type sceneFade struct {
+	duration       time.Duration
+	color1, color2 dmx.Lamp // Global colors the outgoing effects keep rendering with
+}
+
+// crossfade renders the effects a chain had before a scene recall and blends their
+// frame with the frame of the new effects.
+type crossfade struct {
+	sceneFade
+	start     time.Time
+	effects   []types.Effect
+	instances []effectInstance
+	lamps     []dmx.Lamp // Frame of the outgoing effects
+	mixed     []dmx.Lamp // Blended frame
+	previous  *crossfade // Crossfade running when this one started, nil once it finished
+}
+
+// newCrossfade starts a crossfade from the running effects of a chain and its frame.
+// If a crossfade is still running, the new one fades out from its blended frame, so
+// the output doesn't jump.
+func newCrossfade(fade *sceneFade, effects []types.Effect, instances []effectInstance, lamps []dmx.Lamp, running *crossfade, now time.Time) *crossfade {
+	f := &crossfade{
+		sceneFade: *fade,
+		start:     now,
+		effects:   effects,
+		instances: instances,
+		lamps:     make([]dmx.Lamp, len(lamps)),
+		mixed:     make([]dmx.Lamp, len(lamps)),
+	}
+	copy(f.lamps, lamps)
+	if running != nil && running.progress(now) < 1 {
+		f.previous = running
+	}
+	return f
+}
+
+// progress returns how far the fade is at now, from 0 to 1.
+func (f *crossfade) progress(now time.Time) float64 {
+	progress := float64(now.Sub(f.start)) / float64(f.duration)
+	if progress > 1 {
+		return 1
+	}
+	return progress
+}
+
+// globals returns the globals the outgoing effects render with.
+func (f *crossfade) globals(globals types.OrchestratorGlobals) types.OrchestratorGlobals {
+	globals.Color1 = f.color1
+	globals.Color2 = f.color2
+	return globals
+}
+
+// mix blends the outgoing frame into frame, weighted by the progress of the fade.
+func (f *crossfade) mix(frame, outgoing []dmx.Lamp, progress float64) []dmx.Lamp {
+	mix := func(from, to uint8) uint8 {
+		return uint8(float64(from) + (float64(to)-float64(from))*progress + 0.5)
+	}
+	for i, lamp := range frame {
+		old := outgoing[i]
+		f.mixed[i] = dmx.Lamp{R: mix(old.R, lamp.R), G: mix(old.G, lamp.G), B: mix(old.B, lamp.B), W: mix(old.W, lamp.W)}
+	}
+	return f.mixed
+}
@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"godmx/dmx"
+	"testing"
+	"time"
+)
+
+func TestRecallDuringCrossfadeStartsFromBlend(t *testing.T) {
+	cfg := &config.Config{
+		Globals: config.GlobalsConfig{BPM: 120, Color1: "#FF0000", Color2: "#0000FF", BeatsPerBar: 4, BarsPerPhrase: 4},
+		Chains: []config.ChainConfig{{
+			ID:       "main",
+			TickRate: 30,
+			NumLamps: 2,
+			Output:   config.OutputConfig{Type: "artnet"},
+			Effects: []config.EffectConfig{
+				{ID: "color", Type: "solidColor", Args: map[string]interface{}{"color": "#FF0000"}},
+			},
+		}},
+	}
+	o := NewOrchestrator(cfg)
+	output := &recordingOutput{}
+	chain := NewChain(&cfg.Chains[0], o, output)
+	if err := chain.Tick(); err != nil {
+		t.Fatal(err)
+	}
+
+	// recall switches the effect to color, crossfading for an hour
+	recall := func(color string) {
+		cfg.Chains[0].Effects = []config.EffectConfig{
+			{ID: "color", Type: "solidColor", Args: map[string]interface{}{"color": color}},
+		}
+		chain.mutex.Lock()
+		chain.pendingFade = &sceneFade{duration: time.Hour}
+		chain.isDirty = true
+		chain.mutex.Unlock()
+		if err := chain.Tick(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	near := func(got, want dmx.Lamp) bool {
+		diff := func(a, b uint8) int { return max(int(a)-int(b), int(b)-int(a)) }
+		return diff(got.R, want.R) <= 1 && diff(got.G, want.G) <= 1 && diff(got.B, want.B) <= 1
+	}
+
+	// Red to blue, halfway through
+	recall("#0000FF")
+	chain.crossfade.start = time.Now().Add(-30 * time.Minute)
+	if err := chain.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	half := output.last()[0]
+	if !near(half, dmx.Lamp{R: 128, B: 127}) {
+		t.Fatalf("halfway frame = %v, want about {128 0 127}", half)
+	}
+
+	// Green recalled during the fade starts from the red and blue blend, not from blue
+	recall("#00FF00")
+	if got := output.last()[0]; !near(got, half) {
+		t.Errorf("frame after the second recall = %v, want about %v", got, half)
+	}
+
+	// Once the first fade is over, the second one fades out from blue
+	chain.crossfade.previous.start = time.Now().Add(-time.Hour)
+	chain.crossfade.start = time.Now().Add(-30 * time.Minute)
+	if err := chain.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	if got := output.last()[0]; !near(got, dmx.Lamp{G: 128, B: 127}) {
+		t.Errorf("frame = %v, want about {0 128 127}", got)
+	}
+	if chain.crossfade.previous != nil {
+		t.Error("the finished crossfade is still kept")
+	}
+}
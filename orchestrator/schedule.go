@@ -0,0 +1,207 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/types"
+	"math"
+	"sync"
+	"time"
+)
+
+// actionScheduler runs the actions of events and cues. Actions with a delay or a
+// quantization and the actions after a wait are executed by the beat clock once
+// they are due.
+type actionScheduler struct {
+	mutex sync.Mutex
+	runs  map[string]*actionRun // Runs with actions still to execute, by event or cue list
+}
+
+// actionRun is one run of the actions of an event or cue.
+type actionRun struct {
+	actions []config.ActionConfig
+	next    int                // Index of the first action not scheduled yet
+	at      time.Time          // When the actions from next on start
+	beat    float64            // Beat time the actions from next on start at
+	pending []*scheduledAction // Scheduled actions that aren't due yet, in order
+	wait    *scheduledAction   // Wait holding back the actions from next on, nil if there is none
+}
+
+// scheduledAction is an action waiting for its time.
+type scheduledAction struct {
+	action   config.ActionConfig
+	at       time.Time // Due at this time...
+	beat     float64   // ...and this beat time
+	quantize string    // Quantization left to apply once at has passed
+	byBeat   bool      // The due moment is given by the beat, not by at
+}
+
+// start begins a run of actions, cancelling the actions the previous run with the same
+// name still has pending. It returns the actions that are due at once.
+func (s *actionScheduler) start(name string, actions []config.ActionConfig, globals *types.OrchestratorGlobals, now time.Time) []config.ActionConfig {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cancelLocked(name)
+
+	run := &actionRun{actions: actions, at: now, beat: globals.BeatTime}
+	due := run.advance(globals, now)
+	if !run.done() {
+		if s.runs == nil {
+			s.runs = make(map[string]*actionRun)
+		}
+		s.runs[name] = run
+	}
+	return due
+}
+
+// cancel drops the actions the run with the given name still has pending.
+func (s *actionScheduler) cancel(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cancelLocked(name)
+}
+
+// cancelLocked is cancel, must be called with mutex held.
+func (s *actionScheduler) cancelLocked(name string) {
+	previous, ok := s.runs[name]
+	if !ok {
+		return
+	}
+	if cancelled := len(previous.pending) + len(previous.actions) - previous.next; cancelled > 0 {
+		fmt.Printf("  - Cancelled %d pending action(s) of '%s'\n", cancelled, name)
+	}
+	delete(s.runs, name)
+}
+
+// due returns the actions of all runs that are due at now.
+func (s *actionScheduler) due(globals *types.OrchestratorGlobals, now time.Time) []config.ActionConfig {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var due []config.ActionConfig
+	for name, run := range s.runs {
+		due = append(due, run.advance(globals, now)...)
+		if run.done() {
+			delete(s.runs, name)
+		}
+	}
+	return due
+}
+
+// advance returns the actions of the run that are due, in order, and schedules the
+// actions after waits that are over.
+func (r *actionRun) advance(globals *types.OrchestratorGlobals, now time.Time) []config.ActionConfig {
+	var due []config.ActionConfig
+	pending := r.pending[:0]
+	for _, scheduled := range r.pending {
+		if scheduled.due(globals, now) {
+			due = append(due, scheduled.action)
+		} else {
+			pending = append(pending, scheduled)
+		}
+	}
+	r.pending = pending
+
+	for {
+		if r.wait != nil {
+			if !r.wait.due(globals, now) {
+				break
+			}
+			// Continue from the moment the wait was due, so sequences don't drift
+			r.at, r.beat = now, globals.BeatTime
+			if r.wait.byBeat {
+				r.beat = r.wait.beat
+			} else {
+				r.at = r.wait.at
+			}
+			r.wait = nil
+		}
+		if r.next >= len(r.actions) {
+			break
+		}
+		scheduled := r.schedule(r.actions[r.next], globals)
+		r.next++
+		switch {
+		case scheduled.action.Type == "wait":
+			r.wait = scheduled
+		case scheduled.due(globals, now):
+			due = append(due, scheduled.action)
+		default:
+			r.pending = append(r.pending, scheduled)
+		}
+	}
+	return due
+}
+
+// done reports whether the run has no actions left.
+func (r *actionRun) done() bool {
+	return len(r.pending) == 0 && r.wait == nil && r.next >= len(r.actions)
+}
+
+// schedule returns an action scheduled after the delay from the start of the run.
+func (r *actionRun) schedule(action config.ActionConfig, globals *types.OrchestratorGlobals) *scheduledAction {
+	scheduled := &scheduledAction{action: action, at: r.at, beat: r.beat, quantize: action.Quantize}
+	switch action.DelayUnit {
+	case "beats":
+		scheduled.beat += action.Delay
+		scheduled.byBeat = true
+	case "bars":
+		scheduled.beat += action.Delay * float64(globals.BeatsPerBar)
+		scheduled.byBeat = true
+	default:
+		scheduled.at = scheduled.at.Add(time.Duration(action.Delay * float64(time.Second)))
+	}
+	return scheduled
+}
+
+// due reports whether a scheduled action is due at now. The quantization is applied
+// once the delay in seconds has passed, to the beat time at that moment.
+func (a *scheduledAction) due(globals *types.OrchestratorGlobals, now time.Time) bool {
+	if now.Before(a.at) {
+		return false
+	}
+	if a.quantize != "" {
+		a.beat = quantizeBeat(math.Max(a.beat, globals.BeatTime), a.quantize, globals)
+		a.quantize = ""
+		a.byBeat = true
+	}
+	return globals.BeatTime >= a.beat
+}
+
+// quantizeBeat returns the first beat time at or after beatTime that starts a beat,
+// bar or phrase.
+func quantizeBeat(beatTime float64, quantize string, globals *types.OrchestratorGlobals) float64 {
+	length := 1.0
+	switch quantize {
+	case "bar":
+		length = float64(globals.BeatsPerBar)
+	case "phrase":
+		length = float64(globals.BeatsPerBar * globals.BarsPerPhrase)
+	}
+	if length <= 0 {
+		return beatTime
+	}
+	return math.Ceil(beatTime/length) * length
+}
+
+// runActions executes the actions of an event or cue, the delayed ones once they
+// are due. A run with the same name that still has pending actions is cancelled.
+// triggeredBy are the events that triggered the actions, see executeAction.
+func (o *Orchestrator) runActions(name string, actions []config.ActionConfig, triggeredBy []string) {
+	globals := o.GetGlobals()
+	o.executeActions(o.scheduler.start(name, actions, &globals, time.Now()), triggeredBy)
+}
+
+// advanceSchedule executes the scheduled actions that are due, called by the beat clock.
+func (o *Orchestrator) advanceSchedule(now time.Time) {
+	globals := o.GetGlobals()
+	o.executeActions(o.scheduler.due(&globals, now), nil)
+}
+
+// executeActions executes actions in order, errors are printed.
+func (o *Orchestrator) executeActions(actions []config.ActionConfig, triggeredBy []string) {
+	for _, action := range actions {
+		if err := o.executeAction(action, triggeredBy); err != nil {
+			fmt.Printf("  - Error executing action '%s': %v\n", action.Type, err)
+		}
+	}
+}
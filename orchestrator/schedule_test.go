@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"godmx/types"
+	"testing"
+	"time"
+)
+
+func TestQuantizeBeat(t *testing.T) {
+	tests := []struct {
+		name     string
+		beatTime float64
+		quantize string
+		want     float64
+	}{
+		{name: "beat at start", beatTime: 0, quantize: "beat", want: 0},
+		{name: "beat on a beat", beatTime: 5, quantize: "beat", want: 5},
+		{name: "beat just after a beat", beatTime: 5.01, quantize: "beat", want: 6},
+		{name: "beat just before a beat", beatTime: 5.99, quantize: "beat", want: 6},
+		{name: "bar on the downbeat", beatTime: 8, quantize: "bar", want: 8},
+		{name: "bar on the last beat", beatTime: 7, quantize: "bar", want: 8},
+		{name: "bar just after the downbeat", beatTime: 8.5, quantize: "bar", want: 12},
+		{name: "phrase on the phrase", beatTime: 32, quantize: "phrase", want: 32},
+		{name: "phrase on a bar", beatTime: 36, quantize: "phrase", want: 48},
+		{name: "phrase in the first bar", beatTime: 0.25, quantize: "phrase", want: 16},
+	}
+	globals := &types.OrchestratorGlobals{BeatsPerBar: 4, BarsPerPhrase: 4}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := quantizeBeat(test.beatTime, test.quantize, globals); got != test.want {
+				t.Errorf("quantizeBeat(%v, %q) = %v, want %v", test.beatTime, test.quantize, got, test.want)
+			}
+		})
+	}
+
+	// 3/4 time with 2 bar phrases
+	waltz := &types.OrchestratorGlobals{BeatsPerBar: 3, BarsPerPhrase: 2}
+	if got := quantizeBeat(4, "bar", waltz); got != 6 {
+		t.Errorf("bar in 3/4 = %v, want 6", got)
+	}
+	if got := quantizeBeat(6, "phrase", waltz); got != 6 {
+		t.Errorf("phrase in 3/4 = %v, want 6", got)
+	}
+}
+
+func TestScheduledActionQuantizedAtBoundary(t *testing.T) {
+	now := time.Unix(1000, 0)
+	globals := &types.OrchestratorGlobals{BeatsPerBar: 4, BarsPerPhrase: 4}
+	tests := []struct {
+		name     string
+		action   config.ActionConfig
+		beatTime float64 // Beat time when the actions start
+		dueAt    float64 // First beat time the action is due at
+	}{
+		{name: "on the downbeat fires at once", action: config.ActionConfig{Quantize: "bar"}, beatTime: 8, dueAt: 8},
+		{name: "after the downbeat waits a bar", action: config.ActionConfig{Quantize: "bar"}, beatTime: 8.1, dueAt: 12},
+		{name: "on a phrase fires at once", action: config.ActionConfig{Quantize: "phrase"}, beatTime: 16, dueAt: 16},
+		{name: "delay onto a beat", action: config.ActionConfig{Delay: 2, DelayUnit: "beats", Quantize: "beat"}, beatTime: 3, dueAt: 5},
+		{name: "delay onto a bar", action: config.ActionConfig{Delay: 1, DelayUnit: "bars", Quantize: "bar"}, beatTime: 4, dueAt: 8},
+		{name: "delay past a bar", action: config.ActionConfig{Delay: 1, DelayUnit: "beats", Quantize: "bar"}, beatTime: 4, dueAt: 8},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.action.Type = "set_global"
+			var scheduler actionScheduler
+			globals.BeatTime = test.beatTime
+			due := scheduler.start("event", []config.ActionConfig{test.action}, globals, now)
+			if test.dueAt == test.beatTime {
+				if len(due) != 1 {
+					t.Fatalf("got %d due actions at beat %v, want 1", len(due), test.beatTime)
+				}
+				return
+			}
+			if len(due) != 0 {
+				t.Fatalf("action due at once, want it at beat %v", test.dueAt)
+			}
+			globals.BeatTime = test.dueAt - 0.01
+			if due := scheduler.due(globals, now); len(due) != 0 {
+				t.Fatalf("action due at beat %v, want it at %v", globals.BeatTime, test.dueAt)
+			}
+			globals.BeatTime = test.dueAt
+			if due := scheduler.due(globals, now); len(due) != 1 {
+				t.Fatalf("action not due at beat %v", test.dueAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package orchestrator
+
+import (
+	"godmx/config"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	tapTimeout          = 2 * time.Second // A longer pause between taps starts a new series
+	maxTaps             = 8               // Number of recent taps the tempo is averaged over
+	tapOutlierTolerance = 0.2             // Intervals deviating more than 20% from the median are ignored
+)
+
+// TapTempo estimates the tempo from a series of taps.
+type TapTempo struct {
+	taps []time.Time
+}
+
+// Tap registers a tap and returns the estimated BPM. ok is false until
+// there are enough taps for an estimate.
+func (t *TapTempo) Tap(now time.Time) (bpm float64, ok bool) {
+	if len(t.taps) > 0 && now.Sub(t.taps[len(t.taps)-1]) > tapTimeout {
+		t.taps = nil
+	}
+	t.taps = append(t.taps, now)
+	if len(t.taps) > maxTaps {
+		t.taps = t.taps[len(t.taps)-maxTaps:]
+	}
+	if len(t.taps) < 2 {
+		return 0, false
+	}
+
+	intervals := make([]float64, 0, len(t.taps)-1)
+	for i := 1; i < len(t.taps); i++ {
+		intervals = append(intervals, t.taps[i].Sub(t.taps[i-1]).Seconds())
+	}
+
+	// Average the intervals close to the median, so a single missed or double tap doesn't count
+	sorted := append([]float64(nil), intervals...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	sum := 0.0
+	count := 0
+	for _, interval := range intervals {
+		if math.Abs(interval-median) <= median*tapOutlierTolerance {
+			sum += interval
+			count++
+		}
+	}
+	if count == 0 || sum <= 0 {
+		return 0, false
+	}
+	return 60.0 / (sum / float64(count)), true
+}
+
+// Tap registers a tap on the beat. Once there are enough taps the BPM is set to
+// the tapped tempo, and every tap moves the beat phase onto the tap.
+func (o *Orchestrator) Tap() float64 {
+	now := time.Now()
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// An external clock sets the tempo, taps only move the phase
+	if bpm, ok := o.tapTempo.Tap(now); ok && o.clockSource != config.ClockSourceMidi {
+		o.globals.BPM = bpm
+	}
+	o.alignBeat(now, 1)
+	return o.globals.BPM
+}
+
+// ResyncBeat moves the beat phase so the current moment is the start of a beat,
+// bar or phrase, depending on align ("beat", "bar" or "phrase").
+func (o *Orchestrator) ResyncBeat(align string) {
+	now := time.Now()
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	switch align {
+	case "beat":
+		o.alignBeat(now, 1)
+	case "phrase":
+		o.alignBeat(now, int64(o.globals.BeatsPerBar*o.globals.BarsPerPhrase))
+	default: // Downbeat of a bar
+		o.alignBeat(now, int64(o.globals.BeatsPerBar))
+	}
+}
+
+// alignBeat moves the beat to the nearest multiple of beats, so a tap that is a
+// little late moves the beat back and a tap that is early moves it forward.
+// Must be called with the mutex held.
+func (o *Orchestrator) alignBeat(now time.Time, beats int64) {
+	if beats < 1 {
+		beats = 1
+	}
+	// Account for the time since the last clock update
+	o.advanceClockLocked(now)
+
+	position := o.globals.BeatTime / float64(beats)
+	o.setBeatTime(math.Round(position) * float64(beats))
+
+	// Keep the shifted phase when following an external clock
+	o.external.origin = o.globals.BeatTime - float64(o.external.pulses)/clockPPQN
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTapTempo(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []float64 // Seconds between the taps
+		wantBPM   float64
+		wantOK    bool
+	}{
+		{name: "single tap", intervals: nil, wantOK: false},
+		{name: "two taps", intervals: []float64{0.5}, wantBPM: 120, wantOK: true},
+		{name: "steady", intervals: []float64{0.5, 0.5, 0.5, 0.5}, wantBPM: 120, wantOK: true},
+		{name: "median of uneven taps", intervals: []float64{0.48, 0.52, 0.5, 0.49, 0.51}, wantBPM: 120, wantOK: true},
+		{name: "missed tap ignored", intervals: []float64{0.5, 0.5, 1.0, 0.5, 0.5}, wantBPM: 120, wantOK: true},
+		{name: "double tap ignored", intervals: []float64{0.4, 0.4, 0.1, 0.3, 0.4, 0.4}, wantBPM: 150, wantOK: true},
+		{name: "within tolerance counts", intervals: []float64{0.5, 0.5, 0.6}, wantBPM: 60 / (1.6 / 3), wantOK: true},
+		{name: "only the last taps count", intervals: []float64{1.0, 1.0, 1.0, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}, wantBPM: 120, wantOK: true},
+		{name: "pause starts a new series", intervals: []float64{0.3, 0.3, 3, 0.5}, wantBPM: 120, wantOK: true},
+		{name: "pause leaves a single tap", intervals: []float64{0.5, 0.5, 3}, wantOK: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tap TapTempo
+			now := time.Unix(1000, 0)
+			bpm, ok := tap.Tap(now)
+			for _, interval := range test.intervals {
+				now = now.Add(time.Duration(interval * float64(time.Second)))
+				bpm, ok = tap.Tap(now)
+			}
+			if ok != test.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, test.wantOK)
+			}
+			if ok && math.Abs(bpm-test.wantBPM) > 0.01 {
+				t.Errorf("BPM = %v, want %v", bpm, test.wantBPM)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package orchestrator
+
+import (
+	"fmt"
+	"godmx/config"
+	"godmx/effects"
+	"sort"
+	"strings"
+)
+
+// ValidateConfig validates a configuration including its actions, which the config
+// package can't check as the action types are defined here.
+func ValidateConfig(cfg *config.Config) config.Issues {
+	issues := cfg.Validate()
+
+	// Actions of events and cues by path prefix
+	actionLists := make(map[string][]config.ActionConfig)
+	for event, actions := range cfg.Actions {
+		actionLists["actions."+event] = actions
+	}
+	for name, list := range cfg.CueLists {
+		for i, cue := range list.Cues {
+			actionLists[fmt.Sprintf("cue_lists.%s.cues[%d].actions", name, i)] = cue.Actions
+		}
+	}
+
+	// Effects added and scenes saved by actions can be targeted by other actions
+	added := make(map[string]bool)
+	savedScenes := make(map[string]bool)
+	for _, actions := range actionLists {
+		for _, action := range actions {
+			if id, ok := action.Params["id"].(string); ok && action.Type == "add_effect" {
+				added[action.ChainID+"/"+id] = true
+			}
+			if name, ok := action.Params["name"].(string); ok && action.Type == "save_scene" {
+				savedScenes[name] = true
+			}
+		}
+	}
+
+	prefixes := make([]string, 0, len(actionLists))
+	for prefix := range actionLists {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		for i, action := range actionLists[prefix] {
+			path := fmt.Sprintf("%s[%d]", prefix, i)
+			issues = append(issues, validateAction(cfg, path, action, added, savedScenes)...)
+		}
+	}
+	return append(issues, validateEventCycles(cfg)...)
+}
+
+// validateEventCycles finds events that trigger each other, directly through
+// trigger_event actions, in a cycle that would never end. A delay, quantization or
+// wait before the trigger_event breaks the cycle, the event then repeats as a loop.
+func validateEventCycles(cfg *config.Config) config.Issues {
+	type trigger struct {
+		event string
+		path  string
+	}
+	triggers := make(map[string][]trigger)
+	events := make([]string, 0, len(cfg.Actions))
+	for event, actions := range cfg.Actions {
+		events = append(events, event)
+		for i, action := range actions {
+			delayed := action.Delay > 0 || action.Quantize != ""
+			if action.Type == "wait" && delayed {
+				break // All following actions are delayed
+			}
+			if target, ok := action.Params["event"].(string); ok && action.Type == "trigger_event" && !delayed {
+				triggers[event] = append(triggers[event], trigger{event: target, path: fmt.Sprintf("actions.%s[%d].params.event", event, i)})
+			}
+		}
+	}
+	sort.Strings(events) // Stable order of the issues
+
+	var issues config.Issues
+	visited := make(map[string]bool)
+	var stack []string
+	var visit func(event string)
+	visit = func(event string) {
+		visited[event] = true
+		stack = append(stack, event)
+		for _, t := range triggers[event] {
+			for i, name := range stack {
+				if name == t.event {
+					cycle := append(append([]string{}, stack[i:]...), t.event)
+					issues = append(issues, config.ErrorIssue(t.path, "event cycle %s, the events would trigger each other endlessly", strings.Join(cycle, " -> ")))
+				}
+			}
+			if !visited[t.event] {
+				visit(t.event)
+			}
+		}
+		stack = stack[:len(stack)-1]
+	}
+	for _, event := range events {
+		if !visited[event] {
+			visit(event)
+		}
+	}
+	return issues
+}
+
+// validateAction checks an action's type, the chain and effect it refers to and its params.
+func validateAction(cfg *config.Config, path string, action config.ActionConfig, added, savedScenes map[string]bool) config.Issues {
+	schema, ok := ActionSchemas[action.Type]
+	if !ok {
+		return config.Issues{config.ErrorIssue(path+".type", "unknown action type '%s'", action.Type)}
+	}
+
+	issues := action.ValidateTiming(path)
+	if action.Type == "wait" && action.Delay == 0 && action.Quantize == "" {
+		issues = append(issues, config.WarningIssue(path, "wait without delay or quantize does nothing"))
+	}
+	var effect *config.EffectConfig
+	if hasParameter(schema, "chain_id") {
+		if !chainExists(cfg, action.ChainID) {
+			return append(issues, config.ErrorIssue(path+".chain_id", "unknown chain '%s'", action.ChainID))
+		}
+		if hasParameter(schema, "effect_id") {
+			// An effect added at runtime is fine, but its params can't be checked
+			found, err := cfg.FindEffect(action.ChainID, action.EffectID)
+			switch {
+			case err == nil:
+				effect = found
+			case !added[action.ChainID+"/"+action.EffectID]:
+				return append(issues, config.ErrorIssue(path+".effect_id", "unknown effect '%s' in chain '%s'", action.EffectID, action.ChainID))
+			}
+		}
+	}
+
+	switch action.Type {
+	case "add_effect":
+		// The params are the config of the new effect
+		effectConfig, err := mapToEffectConfig(action.Params)
+		if err != nil {
+			return append(issues, config.ErrorIssue(path+".params", "%v", err))
+		}
+		if _, ok := effects.GetEffectMetadata(effectConfig.Type); !ok {
+			return append(issues, config.ErrorIssue(path+".params.type", "unknown effect type '%s'", effectConfig.Type))
+		}
+		if effectConfig.ID == "" {
+			issues = append(issues, config.WarningIssue(path+".params.id", "missing id, actions can't refer to the effect"))
+		}
+		return append(issues, config.ValidateEffectArgs(effectConfig.Type, effectConfig.Args, path+".params.args")...)
+	case "set_effect_param":
+		param, ok := action.Params["param"].(string)
+		if !ok || param == "" {
+			return append(issues, config.ErrorIssue(path+".params.param", "missing name of the arg to set"))
+		}
+		value, ok := action.Params["value"]
+		if !ok {
+			return append(issues, config.ErrorIssue(path+".params.value", "missing"))
+		}
+		if effect != nil {
+			if _, ok := effects.FindParameter(effect.Type, param); !ok {
+				return append(issues, config.ErrorIssue(path+".params.param", "effect '%s' has no parameter '%s'", effect.ID, param))
+			}
+			issues = append(issues, config.ValidateEffectArgs(effect.Type, map[string]interface{}{param: value}, path+".params")...)
+		}
+		return issues
+	case "set_global":
+		// Tried on a scratch config, SetGlobal knows the globals and their values
+		scratch := config.Config{Globals: cfg.Globals}
+		for key, value := range action.Params {
+			if err := scratch.SetGlobal(key, value); err != nil {
+				issues = append(issues, config.ErrorIssue(path+".params."+key, "%v", err))
+			}
+		}
+		return issues
+	case "toggle_effect":
+		if _, ok := action.Params["enabled"]; !ok {
+			issues = append(issues, config.ErrorIssue(path+".params.enabled", "missing"))
+		}
+	case "save_scene", "recall_scene":
+		name, ok := action.Params["name"].(string)
+		if !ok || name == "" {
+			issues = append(issues, config.ErrorIssue(path+".params.name", "missing scene name"))
+			break
+		}
+		// A scene saved by another action may not exist yet
+		if _, exists := cfg.Scenes[name]; action.Type == "recall_scene" && !exists && !savedScenes[name] {
+			issues = append(issues, config.ErrorIssue(path+".params.name", "unknown scene '%s'", name))
+		}
+		if fade, ok := action.Params["fade"].(float64); ok && fade < 0 {
+			issues = append(issues, config.ErrorIssue(path+".params.fade", "must not be negative"))
+		}
+	case "trigger_event":
+		event, _ := action.Params["event"].(string)
+		if _, ok := cfg.Actions[event]; !ok {
+			return append(issues, config.ErrorIssue(path+".params.event", "unknown event '%s'", event))
+		}
+	case "random_event":
+		events, ok := action.Params["events"].([]interface{})
+		if !ok || len(events) == 0 {
+			return append(issues, config.ErrorIssue(path+".params.events", "missing events to pick from"))
+		}
+		for i, value := range events {
+			if event, _ := value.(string); cfg.Actions[event] == nil {
+				issues = append(issues, config.ErrorIssue(fmt.Sprintf("%s.params.events[%d]", path, i), "unknown event '%v'", value))
+			}
+		}
+	case "set_chain_tickrate":
+		if tickRate, ok := action.Params["tick_rate"].(float64); !ok || tickRate <= 0 {
+			issues = append(issues, config.ErrorIssue(path+".params.tick_rate", "must be greater than 0"))
+		}
+	case "move_effect":
+		if position, ok := action.Params["position"].(float64); !ok || position < 0 {
+			issues = append(issues, config.ErrorIssue(path+".params.position", "missing or negative"))
+		}
+	case "toggle_chain":
+		if _, ok := action.Params["enabled"]; !ok {
+			issues = append(issues, config.ErrorIssue(path+".params.enabled", "missing"))
+		}
+	case "cue_go", "cue_back", "cue_jump", "cue_stop":
+		name, _ := action.Params["cue_list"].(string)
+		list, ok := cfg.CueLists[name]
+		if !ok {
+			return append(issues, config.ErrorIssue(path+".params.cue_list", "unknown cue list '%s'", name))
+		}
+		if action.Type == "cue_jump" {
+			cue, _ := action.Params["cue"].(string)
+			if _, err := list.FindCue(cue); err != nil {
+				return append(issues, config.ErrorIssue(path+".params.cue", "%v", err))
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(action.Params))
+	for key := range action.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // Stable order of the issues
+	for _, key := range keys {
+		value := action.Params[key]
+		param, ok := findActionParameter(schema, key)
+		if !ok {
+			issues = append(issues, config.WarningIssue(path+".params."+key, "unknown param of %s, ignored", action.Type))
+			continue
+		}
+		if message := checkActionParameter(param, value); message != "" {
+			issues = append(issues, config.ErrorIssue(path+".params."+key, "%s", message))
+		}
+	}
+	return issues
+}
+
+// chainExists reports whether the configuration has a chain with the given ID.
+func chainExists(cfg *config.Config, chainID string) bool {
+	for _, chain := range cfg.Chains {
+		if chain.ID == chainID {
+			return true
+		}
+	}
+	return false
+}
+
+// hasParameter reports whether an action type has a parameter.
+func hasParameter(schema ActionSchema, name string) bool {
+	_, ok := findActionParameter(schema, name)
+	return ok
+}
+
+// findActionParameter returns a parameter of an action type.
+func findActionParameter(schema ActionSchema, name string) (ActionParameter, bool) {
+	for _, param := range schema.Parameters {
+		if param.InternalName == name {
+			return param, true
+		}
+	}
+	return ActionParameter{}, false
+}
+
+// checkActionParameter checks a param value against its type and options. It
+// returns a message describing the problem, or "" if the value is valid.
+func checkActionParameter(param ActionParameter, value interface{}) string {
+	switch param.DataType {
+	case "float64", "int":
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Sprintf("expected a number, got %T", value)
+		}
+		if param.DataType == "int" && number != float64(int(number)) {
+			return fmt.Sprintf("expected a whole number, got %v", number)
+		}
+	case "list":
+		values, ok := value.([]interface{})
+		if !ok {
+			return fmt.Sprintf("expected a list, got %T", value)
+		}
+		for _, v := range values {
+			if _, ok := v.(string); !ok {
+				return fmt.Sprintf("expected a list of strings, got a %T in it", v)
+			}
+		}
+	case "bool":
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("expected true or false, got %T", value)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("expected a string, got %T", value)
+		}
+		if len(param.Options) == 0 {
+			return ""
+		}
+		for _, option := range param.Options {
+			if s == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("'%s' is not one of %v", s, param.Options)
+	}
+	return ""
+}
@@ -0,0 +1,226 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+)
+
+// maxPacketSize is the largest OSC packet accepted, the maximum UDP payload.
+const maxPacketSize = 65507
+
+// bundleTag starts every OSC bundle.
+var bundleTag = []byte("#bundle\x00")
+
+// Target receives the values of OSC messages, implemented by the orchestrator.
+type Target interface {
+	SetOSCInput(address string, value float64)
+}
+
+// Receiver listens for OSC messages on a UDP port and passes their values to the
+// OSC modulators.
+type Receiver struct {
+	target Target
+	port   int
+	conn   *net.UDPConn
+}
+
+// message is a single OSC message.
+type message struct {
+	address string
+	args    []interface{} // float32, int32, float64, int64, string or bool
+}
+
+// NewReceiver creates a new Receiver for a UDP port.
+func NewReceiver(target Target, port int) *Receiver {
+	return &Receiver{target: target, port: port}
+}
+
+// Start opens the UDP port and begins receiving messages.
+func (r *Receiver) Start() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: r.port})
+	if err != nil {
+		return fmt.Errorf("can't listen for OSC on port %d: %w", r.port, err)
+	}
+	r.conn = conn
+	log.Printf("Listening for OSC messages on UDP port %d...\n", r.port)
+
+	go func() {
+		buf := make([]byte, maxPacketSize)
+		for {
+			n, _, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("OSC receive error: %v\n", err)
+				}
+				return
+			}
+			r.handlePacket(buf[:n])
+		}
+	}()
+	return nil
+}
+
+// Stop closes the UDP port.
+func (r *Receiver) Stop() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+// handlePacket passes the first value of every message in a packet to the OSC
+// modulators listening to its address.
+func (r *Receiver) handlePacket(packet []byte) {
+	messages, err := parsePacket(packet)
+	if err != nil {
+		log.Printf("Invalid OSC packet: %v\n", err)
+		return
+	}
+	for _, msg := range messages {
+		value, ok := msg.value()
+		if !ok {
+			log.Printf("Unhandled OSC message %s %v\n", msg.address, msg.args)
+			continue
+		}
+		r.target.SetOSCInput(msg.address, value)
+	}
+}
+
+// value returns the first argument of a message as a number. Booleans are 0 or 1.
+func (m message) value() (float64, bool) {
+	if len(m.args) == 0 {
+		return 0, false
+	}
+	switch v := m.args[0].(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// parsePacket returns the messages of an OSC packet, a single message or a bundle.
+// Time tags of bundles are ignored, their messages apply at once.
+func parsePacket(packet []byte) ([]message, error) {
+	if !bytes.HasPrefix(packet, bundleTag) {
+		msg, err := parseMessage(packet)
+		if err != nil {
+			return nil, err
+		}
+		return []message{msg}, nil
+	}
+
+	rest := packet[len(bundleTag):]
+	if len(rest) < 8 {
+		return nil, fmt.Errorf("bundle without time tag")
+	}
+	rest = rest[8:]
+	var messages []message
+	for len(rest) > 0 {
+		if len(rest) < 4 {
+			return nil, fmt.Errorf("truncated bundle element size")
+		}
+		size := int(binary.BigEndian.Uint32(rest))
+		rest = rest[4:]
+		if size < 0 || size > len(rest) {
+			return nil, fmt.Errorf("bundle element of %d bytes exceeds the packet", size)
+		}
+		elementMessages, err := parsePacket(rest[:size])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, elementMessages...)
+		rest = rest[size:]
+	}
+	return messages, nil
+}
+
+// parseMessage parses a single OSC message.
+func parseMessage(packet []byte) (message, error) {
+	address, rest, err := readString(packet)
+	if err != nil {
+		return message{}, fmt.Errorf("address: %w", err)
+	}
+	if len(address) == 0 || address[0] != '/' {
+		return message{}, fmt.Errorf("invalid address '%s'", address)
+	}
+	msg := message{address: address}
+	if len(rest) == 0 {
+		return msg, nil // Old senders omit the type tags of messages without arguments
+	}
+
+	tags, rest, err := readString(rest)
+	if err != nil {
+		return message{}, fmt.Errorf("type tags: %w", err)
+	}
+	if len(tags) == 0 || tags[0] != ',' {
+		return message{}, fmt.Errorf("invalid type tags '%s'", tags)
+	}
+	for _, tag := range tags[1:] {
+		var arg interface{}
+		switch tag {
+		case 'f', 'i':
+			if len(rest) < 4 {
+				return message{}, fmt.Errorf("truncated argument '%c'", tag)
+			}
+			bits := binary.BigEndian.Uint32(rest)
+			if tag == 'f' {
+				arg = math.Float32frombits(bits)
+			} else {
+				arg = int32(bits)
+			}
+			rest = rest[4:]
+		case 'd', 'h':
+			if len(rest) < 8 {
+				return message{}, fmt.Errorf("truncated argument '%c'", tag)
+			}
+			bits := binary.BigEndian.Uint64(rest)
+			if tag == 'd' {
+				arg = math.Float64frombits(bits)
+			} else {
+				arg = int64(bits)
+			}
+			rest = rest[8:]
+		case 's':
+			var s string
+			if s, rest, err = readString(rest); err != nil {
+				return message{}, fmt.Errorf("string argument: %w", err)
+			}
+			arg = s
+		case 'T', 'F':
+			arg = tag == 'T'
+		default:
+			return message{}, fmt.Errorf("unsupported argument type '%c'", tag)
+		}
+		msg.args = append(msg.args, arg)
+	}
+	return msg, nil
+}
+
+// readString reads a null terminated string padded to a multiple of 4 bytes and
+// returns it with the rest of the data.
+func readString(data []byte) (string, []byte, error) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("unterminated string")
+	}
+	padded := (end + 4) &^ 3
+	if padded > len(data) {
+		return "", nil, fmt.Errorf("truncated string padding")
+	}
+	return string(data[:end]), data[padded:], nil
+}
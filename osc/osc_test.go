@@ -0,0 +1,161 @@
+package osc
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// oscString encodes a null terminated string padded to 4 bytes.
+func oscString(s string) []byte {
+	b := append([]byte(s), 0)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// oscMessage encodes a message from its address, type tags and encoded arguments.
+func oscMessage(address, tags string, args ...[]byte) []byte {
+	b := append(oscString(address), oscString(tags)...)
+	for _, arg := range args {
+		b = append(b, arg...)
+	}
+	return b
+}
+
+func float32Arg(f float32) []byte {
+	return binary.BigEndian.AppendUint32(nil, math.Float32bits(f))
+}
+
+func int32Arg(i int32) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(i))
+}
+
+func float64Arg(f float64) []byte {
+	return binary.BigEndian.AppendUint64(nil, math.Float64bits(f))
+}
+
+// oscBundle encodes a bundle of elements.
+func oscBundle(elements ...[]byte) []byte {
+	b := append(oscString("#bundle"), make([]byte, 8)...) // Time tag
+	for _, element := range elements {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(element)))
+		b = append(b, element...)
+	}
+	return b
+}
+
+func TestParsePacket(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  []byte
+		want    []message
+		wantErr bool
+	}{
+		{
+			name:   "float",
+			packet: oscMessage("/1/fader1", ",f", float32Arg(0.5)),
+			want:   []message{{address: "/1/fader1", args: []interface{}{float32(0.5)}}},
+		},
+		{
+			name:   "mixed arguments",
+			packet: oscMessage("/a", ",idsTF", int32Arg(-3), float64Arg(0.25), oscString("abc")),
+			want:   []message{{address: "/a", args: []interface{}{int32(-3), 0.25, "abc", true, false}}},
+		},
+		{
+			name:   "address padded to 8 bytes",
+			packet: oscMessage("/abcdef", ",f", float32Arg(1)),
+			want:   []message{{address: "/abcdef", args: []interface{}{float32(1)}}},
+		},
+		{
+			name:   "no type tags",
+			packet: oscString("/ping"),
+			want:   []message{{address: "/ping"}},
+		},
+		{
+			name: "nested bundle",
+			packet: oscBundle(
+				oscMessage("/x", ",f", float32Arg(0.1)),
+				oscBundle(oscMessage("/y", ",i", int32Arg(1))),
+			),
+			want: []message{
+				{address: "/x", args: []interface{}{float32(0.1)}},
+				{address: "/y", args: []interface{}{int32(1)}},
+			},
+		},
+		{name: "not an address", packet: oscMessage("x", ",f", float32Arg(1)), wantErr: true},
+		{name: "truncated argument", packet: oscMessage("/x", ",f", []byte{0, 0}), wantErr: true},
+		{name: "unterminated string", packet: []byte("/abc"), wantErr: true},
+		{name: "unknown type", packet: oscMessage("/x", ",b", int32Arg(0)), wantErr: true},
+		{name: "bundle element too large", packet: append(oscBundle(), 0, 0, 1, 0), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parsePacket(test.packet)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+// recordingTarget is a Target that passes the values it receives to a channel.
+type recordingTarget chan message
+
+func (r recordingTarget) SetOSCInput(address string, value float64) {
+	r <- message{address: address, args: []interface{}{value}}
+}
+
+func TestReceiver(t *testing.T) {
+	// Find a free port
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	target := make(recordingTarget, 4)
+	receiver := NewReceiver(target, port)
+	if err := receiver.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	packet := oscBundle(oscMessage("/fader", ",f", float32Arg(0.75)), oscMessage("/button", ",T"))
+	if _, err := conn.Write(packet); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []message{
+		{address: "/fader", args: []interface{}{0.75}},
+		{address: "/button", args: []interface{}{1.0}},
+	} {
+		select {
+		case got := <-target:
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no value received for %s", want.address)
+		}
+	}
+}
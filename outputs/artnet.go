@@ -1,63 +1,99 @@
 package outputs
 
 import (
+	"fmt"
+	"godmx/artnet"
+	"godmx/config"
 	"godmx/dmx"
-
-	"github.com/RickHulzinga/go-simple-artnet/node"
+	"net"
 )
 
 // ArtNetOutput sends DMX data to an Art-Net node.
 type ArtNetOutput struct {
-	node               *node.ArtNetNode
-	debug              bool // Added debug field
-	channelMapping     string
-	numChannelsPerLamp int
+	conn        *net.UDPConn
+	target      *net.UDPAddr
+	debug       bool // Added debug field
+	layout      universeLayout
+	portAddress uint16          // Port-address of the first universe
+	sequences   map[uint16]byte // Last ArtDmx sequence number per port-address
+	sync        bool            // Send an ArtSync after every frame
 }
 
-// NewArtNetOutput creates a new ArtNetOutput.
-func NewArtNetOutput(targetIP string, debug bool, channelMapping string, numChannelsPerLamp int) (*ArtNetOutput, error) {
-	n, err := node.NewArtNetNode(targetIP + ":6454")
+// NewArtNetOutput creates a new ArtNetOutput. A nil config starts at net 0, sub-net 0, universe 0.
+func NewArtNetOutput(targetIP string, artNetConfig *config.ArtNetOutputConfig, debug bool, channelMapping string, numChannelsPerLamp int) (*ArtNetOutput, error) {
+	if artNetConfig == nil {
+		artNetConfig = &config.ArtNetOutputConfig{}
+	}
+	if artNetConfig.Net < 0 || artNetConfig.Net > 127 {
+		return nil, fmt.Errorf("artnet output: invalid net %d, must be between 0 and 127", artNetConfig.Net)
+	}
+	if artNetConfig.Subnet < 0 || artNetConfig.Subnet > 15 {
+		return nil, fmt.Errorf("artnet output: invalid sub-net %d, must be between 0 and 15", artNetConfig.Subnet)
+	}
+	if artNetConfig.Universe < 0 || artNetConfig.Universe > 15 {
+		return nil, fmt.Errorf("artnet output: invalid universe %d, must be between 0 and 15", artNetConfig.Universe)
+	}
+	layout, err := newUniverseLayout(channelMapping, numChannelsPerLamp, artNetConfig.StartAddress, artNetConfig.NoSplit)
+	if err != nil {
+		return nil, fmt.Errorf("artnet output: %w", err)
+	}
+
+	target, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", targetIP, artnet.Port))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", nil)
 	if err != nil {
 		return nil, err
 	}
-	n.Start()
+
 	return &ArtNetOutput{
-		node:               n,
-		debug:              debug,
-		channelMapping:     channelMapping,
-		numChannelsPerLamp: numChannelsPerLamp,
+		conn:        conn,
+		target:      target,
+		debug:       debug,
+		layout:      layout,
+		portAddress: uint16(artNetConfig.Net<<8 | artNetConfig.Subnet<<4 | artNetConfig.Universe),
+		sequences:   make(map[uint16]byte),
+		sync:        !artNetConfig.DisableSync,
 	}, nil
 }
 
-// Send sends the lamp data as DMX to the Art-Net node.
+// Send sends the lamp data as ArtDmx to the Art-Net node. Lamps that don't fit into
+// the first universe continue in the following universes (port-addresses). After
+// all universes of the frame an ArtSync is sent, so the node outputs them at once.
 func (a *ArtNetOutput) Send(lamps []dmx.Lamp) error {
-	universe := a.node.GetUniverse(0)
-	for i, lamp := range lamps {
-		baseChannel := i * a.numChannelsPerLamp
-		if baseChannel+a.numChannelsPerLamp-1 < 512 { // Ensure we don't go out of bounds
-			switch a.channelMapping {
-			case "RGB":
-				universe.SetChannel(baseChannel+1, int(lamp.R))
-				universe.SetChannel(baseChannel+2, int(lamp.G))
-				universe.SetChannel(baseChannel+3, int(lamp.B))
-			case "RGBW":
-				universe.SetChannel(baseChannel+1, int(lamp.R))
-				universe.SetChannel(baseChannel+2, int(lamp.G))
-				universe.SetChannel(baseChannel+3, int(lamp.B))
-				universe.SetChannel(baseChannel+4, int(lamp.W))
-			default:
-				// Default to RGBW if mapping is unknown or not provided
-				universe.SetChannel(baseChannel+1, int(lamp.R))
-				universe.SetChannel(baseChannel+2, int(lamp.G))
-				universe.SetChannel(baseChannel+3, int(lamp.B))
-				universe.SetChannel(baseChannel+4, int(lamp.W))
-			}
+	for i, data := range a.layout.pack(lamps) {
+		portAddress := int(a.portAddress) + i
+		if portAddress > artnet.MaxPortAddress {
+			return fmt.Errorf("artnet output: port-address %d out of range", portAddress)
+		}
+
+		// Sequence numbers run from 1 to 255, 0 would disable re-ordering on the node
+		sequence := a.sequences[uint16(portAddress)]%255 + 1
+		a.sequences[uint16(portAddress)] = sequence
+
+		packet, err := artnet.BuildDmx(uint16(portAddress), sequence, data)
+		if err != nil {
+			return fmt.Errorf("artnet output: %w", err)
+		}
+		if _, err := a.conn.WriteToUDP(packet, a.target); err != nil {
+			return err
+		}
+	}
+
+	if a.sync {
+		// Nodes only accept an ArtSync from the controller that sent the ArtDmx,
+		// so it goes to the same target instead of being broadcast.
+		if _, err := a.conn.WriteToUDP(artnet.BuildSync(), a.target); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// Close stops the Art-Net node.
+// Close closes the Art-Net socket.
 func (a *ArtNetOutput) Close() {
-	a.node.Stop()
+	if a.conn != nil {
+		a.conn.Close()
+	}
 }
@@ -0,0 +1,125 @@
+package outputs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"godmx/artnet"
+	"godmx/config"
+	"godmx/dmx"
+)
+
+// newLoopbackArtNetOutput returns an Art-Net output sending to a local listener
+// instead of the Art-Net port.
+func newLoopbackArtNetOutput(t *testing.T, cfg *config.ArtNetOutputConfig) (*ArtNetOutput, *net.UDPConn) {
+	t.Helper()
+	listener, target := listenUDP(t)
+	output, err := NewArtNetOutput("127.0.0.1", cfg, false, "RGB", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(output.Close)
+	if output.target, err = net.ResolveUDPAddr("udp4", target); err != nil {
+		t.Fatal(err)
+	}
+	return output, listener
+}
+
+// checkArtDmx checks the port-address, sequence and data of an ArtDmx packet.
+func checkArtDmx(t *testing.T, packet []byte, portAddress uint16, sequence byte, data []byte) {
+	t.Helper()
+	if opCode, err := artnet.OpCode(packet); err != nil || opCode != artnet.OpDmx {
+		t.Fatalf("not an ArtDmx packet: %v % x", err, packet[:min(len(packet), 12)])
+	}
+	if got := uint16(packet[15])<<8 | uint16(packet[14]); got != portAddress {
+		t.Errorf("port-address = %#04x, want %#04x", got, portAddress)
+	}
+	if packet[12] != sequence {
+		t.Errorf("port-address %#04x: sequence = %d, want %d", portAddress, packet[12], sequence)
+	}
+	if length := binary.BigEndian.Uint16(packet[16:18]); int(length) != len(data) {
+		t.Errorf("length = %d, want %d", length, len(data))
+	}
+	if !bytes.Equal(packet[18:], data) {
+		t.Errorf("port-address %#04x: unexpected DMX data", portAddress)
+	}
+}
+
+// checkArtSync checks that a packet is an ArtSync.
+func checkArtSync(t *testing.T, packet []byte) {
+	t.Helper()
+	if !bytes.Equal(packet, artnet.BuildSync()) {
+		t.Errorf("expected an ArtSync, got % x", packet[:min(len(packet), 14)])
+	}
+}
+
+func TestArtNetOutput(t *testing.T) {
+	// Net 1, sub-net 2, universe 15: the second universe continues in sub-net 3
+	output, listener := newLoopbackArtNetOutput(t, &config.ArtNetOutputConfig{Net: 1, Subnet: 2, Universe: 15})
+
+	// 200 RGB lamps are 600 channels, spilling into a second universe
+	lamps := make([]dmx.Lamp, 200)
+	for i := range lamps {
+		lamps[i] = dmx.Lamp{R: byte(i), G: 1, B: 2}
+	}
+	universes := output.layout.pack(lamps)
+	for sequence := byte(1); sequence <= 3; sequence++ {
+		if err := output.Send(lamps); err != nil {
+			t.Fatal(err)
+		}
+		checkArtDmx(t, receive(t, listener), 0x012f, sequence, universes[0])
+		checkArtDmx(t, receive(t, listener), 0x0130, sequence, universes[1])
+		checkArtSync(t, receive(t, listener))
+	}
+}
+
+func TestArtNetOutputSequenceWraps(t *testing.T) {
+	output, listener := newLoopbackArtNetOutput(t, &config.ArtNetOutputConfig{DisableSync: true})
+	lamps := []dmx.Lamp{{R: 1}}
+	output.sequences[0] = 254
+	for _, want := range []byte{255, 1, 2} {
+		if err := output.Send(lamps); err != nil {
+			t.Fatal(err)
+		}
+		// Without sync the next packet is the ArtDmx of the next frame
+		packet := receive(t, listener)
+		if packet[12] != want {
+			t.Errorf("sequence = %d, want %d", packet[12], want)
+		}
+	}
+}
+
+func TestArtNetOutputWithoutSync(t *testing.T) {
+	output, listener := newLoopbackArtNetOutput(t, &config.ArtNetOutputConfig{DisableSync: true})
+	for i := 0; i < 2; i++ {
+		if err := output.Send([]dmx.Lamp{{R: 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for sequence := byte(1); sequence <= 2; sequence++ {
+		if opCode, _ := artnet.OpCode(receive(t, listener)); opCode != artnet.OpDmx {
+			t.Fatalf("got op code %#04x, want only ArtDmx", opCode)
+		}
+	}
+}
+
+func TestArtNetOutputPortAddressOutOfRange(t *testing.T) {
+	output, _ := newLoopbackArtNetOutput(t, &config.ArtNetOutputConfig{Net: 127, Subnet: 15, Universe: 15})
+	if err := output.Send(make([]dmx.Lamp, 200)); err == nil {
+		t.Error("expected an error for the universe after port-address 0x7fff")
+	}
+}
+
+func TestArtNetOutputInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]*config.ArtNetOutputConfig{
+		"net":      {Net: 128},
+		"sub-net":  {Subnet: 16},
+		"universe": {Universe: -1},
+	} {
+		if _, err := NewArtNetOutput("127.0.0.1", cfg, false, "RGB", 3); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
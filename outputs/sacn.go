@@ -0,0 +1,275 @@
+package outputs
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"godmx/config"
+	"godmx/dmx"
+)
+
+const (
+	// E1.31 protocol constants (ANSI E1.31-2018).
+	sacnPort                  = 5568
+	sacnDefaultPriority       = 100
+	sacnMaxPriority           = 200
+	sacnMaxUniverse           = 63999
+	sacnDefaultSourceName     = "GoDMX"
+	sacnSourceNameLen         = 64
+	sacnDataHeaderLen         = 126
+	sacnSyncPacketLen         = 49
+	sacnVectorRootData        = 0x00000004
+	sacnVectorRootExtended    = 0x00000008
+	sacnVectorFramingData     = 0x00000002
+	sacnVectorFramingSync     = 0x00000001
+	sacnVectorDMPSetProperty  = 0x02
+	sacnDMPAddressDataType    = 0xa1
+	sacnOptionStreamTerminate = 0x40
+	sacnTerminatePackets      = 3
+)
+
+// sacnPacketIdentifier is the fixed ACN packet identifier of the root layer.
+var sacnPacketIdentifier = [12]byte{'A', 'S', 'C', '-', 'E', '1', '.', '1', '7', 0x00, 0x00, 0x00}
+
+// SACNOutput sends DMX data as sACN (E1.31) via multicast or unicast.
+type SACNOutput struct {
+	conn              *net.UDPConn
+	debug             bool
+	layout            universeLayout
+	universe          uint16
+	priority          byte
+	sourceName        string
+	cid               [16]byte
+	syncUniverse      uint16
+	targets           []*net.UDPAddr // Unicast targets, empty for multicast
+	sequences         map[uint16]byte
+	syncSequence      byte
+	lastUniverseCount int
+}
+
+// NewSACNOutput creates a new SACNOutput. A nil config sends universe 1 via multicast.
+func NewSACNOutput(sacnConfig *config.SACNOutputConfig, debug bool, channelMapping string, numChannelsPerLamp int) (*SACNOutput, error) {
+	if sacnConfig == nil {
+		sacnConfig = &config.SACNOutputConfig{Universe: 1}
+	}
+
+	universe := sacnConfig.Universe
+	if universe == 0 {
+		universe = 1
+	}
+	if universe < 1 || universe > sacnMaxUniverse {
+		return nil, fmt.Errorf("sacn output: invalid universe %d, must be between 1 and %d", universe, sacnMaxUniverse)
+	}
+	if sacnConfig.SyncUniverse < 0 || sacnConfig.SyncUniverse > sacnMaxUniverse {
+		return nil, fmt.Errorf("sacn output: invalid sync universe %d, must be between 1 and %d, or 0 to disable sync", sacnConfig.SyncUniverse, sacnMaxUniverse)
+	}
+
+	priority := sacnDefaultPriority
+	if sacnConfig.Priority != nil {
+		priority = *sacnConfig.Priority // 0 is valid, the lowest priority
+	}
+	if priority < 0 || priority > sacnMaxPriority {
+		return nil, fmt.Errorf("sacn output: invalid priority %d, must be between 0 and %d", priority, sacnMaxPriority)
+	}
+
+	layout, err := newUniverseLayout(channelMapping, numChannelsPerLamp, sacnConfig.StartAddress, sacnConfig.NoSplit)
+	if err != nil {
+		return nil, fmt.Errorf("sacn output: %w", err)
+	}
+
+	sourceName := sacnConfig.SourceName
+	if sourceName == "" {
+		sourceName = sacnDefaultSourceName
+	}
+
+	var cid [16]byte
+	if sacnConfig.CID != "" {
+		parsed, err := parseCID(sacnConfig.CID)
+		if err != nil {
+			return nil, fmt.Errorf("sacn output: %w", err)
+		}
+		cid = parsed
+	} else {
+		generated, err := newCID()
+		if err != nil {
+			return nil, fmt.Errorf("sacn output: failed to generate CID: %w", err)
+		}
+		cid = generated
+	}
+
+	var targets []*net.UDPAddr
+	for _, target := range sacnConfig.Targets {
+		if !strings.Contains(target, ":") {
+			target = fmt.Sprintf("%s:%d", target, sacnPort)
+		}
+		addr, err := net.ResolveUDPAddr("udp4", target)
+		if err != nil {
+			return nil, fmt.Errorf("sacn output: failed to resolve target %s: %w", target, err)
+		}
+		targets = append(targets, addr)
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SACNOutput{
+		conn:         conn,
+		debug:        debug,
+		layout:       layout,
+		universe:     uint16(universe),
+		priority:     byte(priority),
+		sourceName:   sourceName,
+		cid:          cid,
+		syncUniverse: uint16(sacnConfig.SyncUniverse),
+		targets:      targets,
+		sequences:    make(map[uint16]byte),
+	}, nil
+}
+
+// Send sends the lamp data as E1.31 data packets, one per universe, followed by
+// a synchronization packet if a sync universe is configured.
+func (s *SACNOutput) Send(lamps []dmx.Lamp) error {
+	universes := s.layout.pack(lamps)
+	s.lastUniverseCount = len(universes)
+
+	for i, data := range universes {
+		universe := s.universe + uint16(i)
+		if universe > sacnMaxUniverse {
+			return fmt.Errorf("sacn output: universe %d out of range", universe)
+		}
+		if err := s.sendData(universe, data, 0); err != nil {
+			return err
+		}
+	}
+
+	if s.syncUniverse != 0 {
+		return s.sendSync()
+	}
+	return nil
+}
+
+// Close sends stream termination packets for all universes and closes the socket.
+func (s *SACNOutput) Close() {
+	if s.conn == nil {
+		return
+	}
+	for i := 0; i < s.lastUniverseCount; i++ {
+		universe := s.universe + uint16(i)
+		data := make([]byte, dmxUniverseSize)
+		for n := 0; n < sacnTerminatePackets; n++ {
+			s.sendData(universe, data, sacnOptionStreamTerminate)
+		}
+	}
+	s.conn.Close()
+}
+
+// sendData builds and sends a single E1.31 data packet for a universe.
+func (s *SACNOutput) sendData(universe uint16, data []byte, options byte) error {
+	s.sequences[universe]++
+	packet := s.buildDataPacket(universe, s.sequences[universe], data, options)
+	return s.write(universe, packet)
+}
+
+// sendSync sends an E1.31 synchronization packet on the sync universe.
+func (s *SACNOutput) sendSync() error {
+	s.syncSequence++
+	packet := make([]byte, sacnSyncPacketLen)
+	s.writeRootLayer(packet, sacnVectorRootExtended)
+
+	// Framing layer
+	binary.BigEndian.PutUint16(packet[38:40], 0x7000|uint16(len(packet)-38))
+	binary.BigEndian.PutUint32(packet[40:44], sacnVectorFramingSync)
+	packet[44] = s.syncSequence
+	binary.BigEndian.PutUint16(packet[45:47], s.syncUniverse)
+	// Bytes 47-48 are reserved
+
+	return s.write(s.syncUniverse, packet)
+}
+
+// buildDataPacket assembles the root, framing and DMP layers of a data packet.
+func (s *SACNOutput) buildDataPacket(universe uint16, sequence byte, data []byte, options byte) []byte {
+	packet := make([]byte, sacnDataHeaderLen+len(data))
+	s.writeRootLayer(packet, sacnVectorRootData)
+
+	// Framing layer
+	binary.BigEndian.PutUint16(packet[38:40], 0x7000|uint16(len(packet)-38))
+	binary.BigEndian.PutUint32(packet[40:44], sacnVectorFramingData)
+	copy(packet[44:44+sacnSourceNameLen-1], s.sourceName) // Null terminated
+	packet[108] = s.priority
+	binary.BigEndian.PutUint16(packet[109:111], s.syncUniverse)
+	packet[111] = sequence
+	packet[112] = options
+	binary.BigEndian.PutUint16(packet[113:115], universe)
+
+	// DMP layer
+	binary.BigEndian.PutUint16(packet[115:117], 0x7000|uint16(len(packet)-115))
+	packet[117] = sacnVectorDMPSetProperty
+	packet[118] = sacnDMPAddressDataType
+	binary.BigEndian.PutUint16(packet[119:121], 0x0000)              // First property address
+	binary.BigEndian.PutUint16(packet[121:123], 0x0001)              // Address increment
+	binary.BigEndian.PutUint16(packet[123:125], uint16(len(data)+1)) // Property value count incl. start code
+	packet[125] = 0x00                                               // DMX512 start code
+	copy(packet[sacnDataHeaderLen:], data)
+
+	return packet
+}
+
+// writeRootLayer fills in the root layer shared by data and sync packets.
+func (s *SACNOutput) writeRootLayer(packet []byte, vector uint32) {
+	binary.BigEndian.PutUint16(packet[0:2], 0x0010) // Preamble size
+	binary.BigEndian.PutUint16(packet[2:4], 0x0000) // Postamble size
+	copy(packet[4:16], sacnPacketIdentifier[:])
+	binary.BigEndian.PutUint16(packet[16:18], 0x7000|uint16(len(packet)-16))
+	binary.BigEndian.PutUint32(packet[18:22], vector)
+	copy(packet[22:38], s.cid[:])
+}
+
+// write sends a packet to the unicast targets or to the multicast group of the universe.
+func (s *SACNOutput) write(universe uint16, packet []byte) error {
+	if len(s.targets) == 0 {
+		_, err := s.conn.WriteToUDP(packet, sacnMulticastAddr(universe))
+		return err
+	}
+	for _, target := range s.targets {
+		if _, err := s.conn.WriteToUDP(packet, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sacnMulticastAddr returns the multicast address 239.255.x.y for a universe.
+func sacnMulticastAddr(universe uint16) *net.UDPAddr {
+	return &net.UDPAddr{
+		IP:   net.IPv4(239, 255, byte(universe>>8), byte(universe&0xff)),
+		Port: sacnPort,
+	}
+}
+
+// parseCID parses a UUID string (with or without dashes) into a 16 byte CID.
+func parseCID(s string) ([16]byte, error) {
+	var cid [16]byte
+	raw, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(raw) != len(cid) {
+		return cid, fmt.Errorf("invalid CID '%s', expected a UUID", s)
+	}
+	copy(cid[:], raw)
+	return cid, nil
+}
+
+// newCID generates a random version 4 UUID to identify this source.
+func newCID() ([16]byte, error) {
+	var cid [16]byte
+	if _, err := rand.Read(cid[:]); err != nil {
+		return cid, err
+	}
+	cid[6] = (cid[6] & 0x0f) | 0x40 // Version 4
+	cid[8] = (cid[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return cid, nil
+}
@@ -0,0 +1,295 @@
+package outputs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"godmx/config"
+	"godmx/dmx"
+)
+
+const testCID = "01234567-89ab-cdef-0123-456789abcdef"
+
+// listenUDP returns a UDP listener on a local port and the target address to send to.
+func listenUDP(t *testing.T) (*net.UDPConn, string) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, conn.LocalAddr().String()
+}
+
+// receive reads the next packet from the listener.
+func receive(t *testing.T, conn *net.UDPConn) []byte {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("no packet received: %v", err)
+	}
+	return buf[:n]
+}
+
+// checkRootLayer checks the root layer of a packet.
+func checkRootLayer(t *testing.T, packet []byte, vector uint32) {
+	t.Helper()
+	if got := binary.BigEndian.Uint16(packet[0:2]); got != 0x0010 {
+		t.Errorf("preamble size = %#x, want 0x0010", got)
+	}
+	if got := binary.BigEndian.Uint16(packet[2:4]); got != 0 {
+		t.Errorf("postamble size = %#x, want 0", got)
+	}
+	if !bytes.Equal(packet[4:16], sacnPacketIdentifier[:]) {
+		t.Errorf("packet identifier = %q", packet[4:16])
+	}
+	if got, want := binary.BigEndian.Uint16(packet[16:18]), 0x7000|uint16(len(packet)-16); got != want {
+		t.Errorf("root flags and length = %#x, want %#x", got, want)
+	}
+	if got := binary.BigEndian.Uint32(packet[18:22]); got != vector {
+		t.Errorf("root vector = %#x, want %#x", got, vector)
+	}
+	cid, _ := parseCID(testCID)
+	if !bytes.Equal(packet[22:38], cid[:]) {
+		t.Errorf("CID = % x, want % x", packet[22:38], cid)
+	}
+}
+
+// checkDataPacket checks the framing and DMP layers of a data packet.
+func checkDataPacket(t *testing.T, packet []byte, universe uint16, sequence, priority, options byte, data []byte) {
+	t.Helper()
+	if len(packet) != sacnDataHeaderLen+dmxUniverseSize {
+		t.Fatalf("data packet length = %d, want %d", len(packet), sacnDataHeaderLen+dmxUniverseSize)
+	}
+	checkRootLayer(t, packet, sacnVectorRootData)
+
+	// Framing layer
+	if got, want := binary.BigEndian.Uint16(packet[38:40]), 0x7000|uint16(len(packet)-38); got != want {
+		t.Errorf("framing flags and length = %#x, want %#x", got, want)
+	}
+	if got := binary.BigEndian.Uint32(packet[40:44]); got != sacnVectorFramingData {
+		t.Errorf("framing vector = %#x, want %#x", got, sacnVectorFramingData)
+	}
+	if name := string(bytes.TrimRight(packet[44:108], "\x00")); name != "Test Source" {
+		t.Errorf("source name = %q, want %q", name, "Test Source")
+	}
+	if packet[108] != priority {
+		t.Errorf("priority = %d, want %d", packet[108], priority)
+	}
+	if got := binary.BigEndian.Uint16(packet[109:111]); got != 7 {
+		t.Errorf("sync address = %d, want 7", got)
+	}
+	if packet[111] != sequence {
+		t.Errorf("universe %d: sequence = %d, want %d", universe, packet[111], sequence)
+	}
+	if packet[112] != options {
+		t.Errorf("options = %#x, want %#x", packet[112], options)
+	}
+	if got := binary.BigEndian.Uint16(packet[113:115]); got != universe {
+		t.Errorf("universe = %d, want %d", got, universe)
+	}
+
+	// DMP layer
+	if got, want := binary.BigEndian.Uint16(packet[115:117]), 0x7000|uint16(len(packet)-115); got != want {
+		t.Errorf("DMP flags and length = %#x, want %#x", got, want)
+	}
+	if packet[117] != sacnVectorDMPSetProperty || packet[118] != sacnDMPAddressDataType {
+		t.Errorf("DMP vector and address type = %#x %#x", packet[117], packet[118])
+	}
+	if first, increment := binary.BigEndian.Uint16(packet[119:121]), binary.BigEndian.Uint16(packet[121:123]); first != 0 || increment != 1 {
+		t.Errorf("first address %d and increment %d, want 0 and 1", first, increment)
+	}
+	if got := binary.BigEndian.Uint16(packet[123:125]); got != dmxUniverseSize+1 {
+		t.Errorf("property value count = %d, want %d", got, dmxUniverseSize+1)
+	}
+	if packet[125] != 0 {
+		t.Errorf("start code = %#x, want 0", packet[125])
+	}
+	if !bytes.Equal(packet[sacnDataHeaderLen:], data) {
+		t.Errorf("universe %d: unexpected DMX data", universe)
+	}
+}
+
+// checkSyncPacket checks a synchronization packet.
+func checkSyncPacket(t *testing.T, packet []byte, sequence byte) {
+	t.Helper()
+	if len(packet) != sacnSyncPacketLen {
+		t.Fatalf("sync packet length = %d, want %d", len(packet), sacnSyncPacketLen)
+	}
+	checkRootLayer(t, packet, sacnVectorRootExtended)
+	if got, want := binary.BigEndian.Uint16(packet[38:40]), 0x7000|uint16(len(packet)-38); got != want {
+		t.Errorf("framing flags and length = %#x, want %#x", got, want)
+	}
+	if got := binary.BigEndian.Uint32(packet[40:44]); got != sacnVectorFramingSync {
+		t.Errorf("framing vector = %#x, want %#x", got, sacnVectorFramingSync)
+	}
+	if packet[44] != sequence {
+		t.Errorf("sync sequence = %d, want %d", packet[44], sequence)
+	}
+	if got := binary.BigEndian.Uint16(packet[45:47]); got != 7 {
+		t.Errorf("sync universe = %d, want 7", got)
+	}
+}
+
+func TestSACNOutput(t *testing.T) {
+	listener, target := listenUDP(t)
+	priority := 0
+	output, err := NewSACNOutput(&config.SACNOutputConfig{
+		Universe:     3,
+		Priority:     &priority,
+		SourceName:   "Test Source",
+		CID:          testCID,
+		SyncUniverse: 7,
+		Targets:      []string{target},
+	}, false, "RGB", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 200 RGB lamps are 600 channels, spilling into a second universe
+	lamps := make([]dmx.Lamp, 200)
+	for i := range lamps {
+		lamps[i] = dmx.Lamp{R: byte(i), G: 1, B: 2}
+	}
+	universes := output.layout.pack(lamps)
+	for sequence := byte(1); sequence <= 2; sequence++ {
+		if err := output.Send(lamps); err != nil {
+			t.Fatal(err)
+		}
+		checkDataPacket(t, receive(t, listener), 3, sequence, 0, 0, universes[0])
+		checkDataPacket(t, receive(t, listener), 4, sequence, 0, 0, universes[1])
+		checkSyncPacket(t, receive(t, listener), sequence)
+	}
+
+	output.Close()
+	empty := make([]byte, dmxUniverseSize)
+	for _, universe := range []uint16{3, 4} {
+		for n := byte(0); n < sacnTerminatePackets; n++ {
+			checkDataPacket(t, receive(t, listener), universe, 3+n, 0, sacnOptionStreamTerminate, empty)
+		}
+	}
+}
+
+func TestSACNOutputDefaultPriority(t *testing.T) {
+	output, err := NewSACNOutput(&config.SACNOutputConfig{Universe: 1}, false, "RGB", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+	if output.priority != sacnDefaultPriority {
+		t.Errorf("priority = %d, want %d", output.priority, sacnDefaultPriority)
+	}
+	if output.syncUniverse != 0 {
+		t.Errorf("sync universe = %d, want 0 (disabled)", output.syncUniverse)
+	}
+}
+
+func TestSACNOutputInvalidConfig(t *testing.T) {
+	tooHigh := sacnMaxPriority + 1
+	for name, cfg := range map[string]*config.SACNOutputConfig{
+		"universe":      {Universe: sacnMaxUniverse + 1},
+		"priority":      {Universe: 1, Priority: &tooHigh},
+		"sync universe": {Universe: 1, SyncUniverse: -1},
+		"cid":           {Universe: 1, CID: "not a uuid"},
+	} {
+		if _, err := NewSACNOutput(cfg, false, "RGB", 3); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestUniverseLayoutPack(t *testing.T) {
+	tests := []struct {
+		name         string
+		mapping      string
+		channels     int
+		startAddress int
+		noSplit      bool
+		lamps        []dmx.Lamp
+		want         map[int]map[int]byte // Non-zero channels (0-based) per universe
+		universes    int
+	}{
+		{
+			name:      "rgb from the start",
+			mapping:   "RGB",
+			lamps:     []dmx.Lamp{{R: 1, G: 2, B: 3}, {R: 4, G: 5, B: 6}},
+			want:      map[int]map[int]byte{0: {0: 1, 1: 2, 2: 3, 3: 4, 4: 5, 5: 6}},
+			universes: 1,
+		},
+		{
+			name:      "channel order",
+			mapping:   "GRBW",
+			lamps:     []dmx.Lamp{{R: 1, G: 2, B: 3, W: 4}},
+			want:      map[int]map[int]byte{0: {0: 2, 1: 1, 2: 3, 3: 4}},
+			universes: 1,
+		},
+		{
+			name:         "start address",
+			mapping:      "RGB",
+			startAddress: 10,
+			lamps:        []dmx.Lamp{{R: 1, G: 2, B: 3}},
+			want:         map[int]map[int]byte{0: {9: 1, 10: 2, 11: 3}},
+			universes:    1,
+		},
+		{
+			name:      "unmapped channels stay 0",
+			mapping:   "RGB",
+			channels:  5,
+			lamps:     []dmx.Lamp{{R: 1, G: 2, B: 3}, {R: 4, G: 5, B: 6}},
+			want:      map[int]map[int]byte{0: {0: 1, 1: 2, 2: 3, 5: 4, 6: 5, 7: 6}},
+			universes: 1,
+		},
+		{
+			name:         "split across universes",
+			mapping:      "RGB",
+			startAddress: 511,
+			lamps:        []dmx.Lamp{{R: 1, G: 2, B: 3}, {R: 4, G: 5, B: 6}},
+			want:         map[int]map[int]byte{0: {510: 1, 511: 2}, 1: {0: 3, 1: 4, 2: 5, 3: 6}},
+			universes:    2,
+		},
+		{
+			name:         "no split",
+			mapping:      "RGB",
+			startAddress: 511,
+			noSplit:      true,
+			lamps:        []dmx.Lamp{{R: 1, G: 2, B: 3}, {R: 4, G: 5, B: 6}},
+			want:         map[int]map[int]byte{1: {0: 1, 1: 2, 2: 3, 3: 4, 4: 5, 5: 6}},
+			universes:    2,
+		},
+		{
+			name:      "exactly fills a universe",
+			mapping:   "RGBW",
+			noSplit:   true,
+			lamps:     make([]dmx.Lamp, 128),
+			want:      map[int]map[int]byte{},
+			universes: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layout, err := newUniverseLayout(test.mapping, test.channels, test.startAddress, test.noSplit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			universes := layout.pack(test.lamps)
+			if len(universes) != test.universes {
+				t.Fatalf("got %d universes, want %d", len(universes), test.universes)
+			}
+			for u, data := range universes {
+				if len(data) != dmxUniverseSize {
+					t.Errorf("universe %d has %d channels", u, len(data))
+				}
+				for channel, value := range data {
+					if want := test.want[u][channel]; value != want {
+						t.Errorf("universe %d channel %d = %d, want %d", u, channel, value, want)
+					}
+				}
+			}
+		})
+	}
+}
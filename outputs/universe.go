@@ -0,0 +1,90 @@
+package outputs
+
+import (
+	"fmt"
+	"godmx/dmx"
+)
+
+const (
+	// dmxUniverseSize is the number of channels in a single DMX512 universe.
+	dmxUniverseSize = 512
+)
+
+// universeLayout describes how lamps are laid out over consecutive DMX universes.
+type universeLayout struct {
+	channelMapping     string // Order of the color components, e.g. "RGB", "RGBW", "GRB"
+	numChannelsPerLamp int    // Channels occupied by each lamp
+	startAddress       int    // DMX address (1-512) of the first lamp in the first universe
+	noSplit            bool   // Never split a lamp across two universes
+}
+
+// newUniverseLayout creates a universeLayout, applying defaults for unset values.
+func newUniverseLayout(channelMapping string, numChannelsPerLamp int, startAddress int, noSplit bool) (universeLayout, error) {
+	if channelMapping == "" {
+		// Default to RGBW if mapping is not provided
+		channelMapping = "RGBW"
+	}
+	if numChannelsPerLamp <= 0 {
+		numChannelsPerLamp = len(channelMapping)
+	}
+	if numChannelsPerLamp > dmxUniverseSize {
+		return universeLayout{}, fmt.Errorf("invalid number of channels per lamp %d", numChannelsPerLamp)
+	}
+	if startAddress == 0 {
+		startAddress = 1
+	}
+	if startAddress < 1 || startAddress > dmxUniverseSize {
+		return universeLayout{}, fmt.Errorf("invalid start address %d, must be between 1 and %d", startAddress, dmxUniverseSize)
+	}
+	return universeLayout{
+		channelMapping:     channelMapping,
+		numChannelsPerLamp: numChannelsPerLamp,
+		startAddress:       startAddress,
+		noSplit:            noSplit,
+	}, nil
+}
+
+// lampChannel returns the value of a single color component of a lamp.
+// Unknown components (anything that is not R, G, B or W) are sent as 0.
+func lampChannel(lamp dmx.Lamp, component byte) byte {
+	switch component {
+	case 'R':
+		return lamp.R
+	case 'G':
+		return lamp.G
+	case 'B':
+		return lamp.B
+	case 'W':
+		return lamp.W
+	default:
+		return 0
+	}
+}
+
+// pack lays out the lamps as consecutive DMX channels according to the channel
+// mapping and returns one 512 channel buffer per universe, starting with the
+// first universe of the output. Lamps that don't fit into a universe continue
+// in the next one; with noSplit the remaining channels are left at 0 instead
+// and the lamp starts at the beginning of the next universe.
+func (l universeLayout) pack(lamps []dmx.Lamp) [][]byte {
+	universes := [][]byte{make([]byte, dmxUniverseSize)}
+	address := l.startAddress - 1
+
+	for _, lamp := range lamps {
+		if l.noSplit && address+l.numChannelsPerLamp > dmxUniverseSize {
+			universes = append(universes, make([]byte, dmxUniverseSize))
+			address = 0
+		}
+		for c := 0; c < l.numChannelsPerLamp; c++ {
+			if address >= dmxUniverseSize {
+				universes = append(universes, make([]byte, dmxUniverseSize))
+				address = 0
+			}
+			if c < len(l.channelMapping) {
+				universes[len(universes)-1][address] = lampChannel(lamp, l.channelMapping[c])
+			}
+			address++
+		}
+	}
+	return universes
+}
@@ -9,7 +9,21 @@ type OrchestratorGlobals struct {
 	Color2       dmx.Lamp
 	TotalLamps   int
 	TickRate     int
-	BeatProgress float64
+	BeatProgress float64 // Progress through the current beat [0,1)
+	Intensity    int     // Grand master (0-255), applied after all effects
+	Blackout     bool    // Forces all outputs to black, applied after all effects
+
+	// Beat, bar and phrase position, advanced by the orchestrator's beat clock
+	BeatTime       float64 // Beats since start (Beat + BeatProgress), increases monotonically except when tapped or resynced
+	Beat           int64   // Absolute beat count since start
+	BeatsPerBar    int     // Time signature, e.g. 4 for 4/4
+	BarBeat        int     // Beat within the current bar (0 to BeatsPerBar-1)
+	Bar            int64   // Absolute bar count since start
+	BarProgress    float64 // Progress through the current bar [0,1)
+	BarsPerPhrase  int     // Phrase length in bars, e.g. 16 or 32
+	PhraseBar      int     // Bar within the current phrase (0 to BarsPerPhrase-1)
+	Phrase         int64   // Absolute phrase count since start
+	PhraseProgress float64 // Progress through the current phrase [0,1)
 }
 
 // Effect defines the interface for all lighting effects.
@@ -17,15 +31,23 @@ type Effect interface {
 	Process(lamps []dmx.Lamp, globals *OrchestratorGlobals, channelMapping string, numChannelsPerLamp int)
 }
 
+// ParameterSetter is implemented by effects that can update their parameters in place.
+// The effect keeps its running state, so a parameter change doesn't make it jump.
+type ParameterSetter interface {
+	// SetParameters applies the changed args of the effect. Parameters missing from args keep their value.
+	SetParameters(args map[string]interface{}) error
+}
+
 // ParameterMetadata describes a single parameter for an effect.
 type ParameterMetadata struct {
 	InternalName string      `json:"internal_name"`
 	DisplayName  string      `json:"display_name"`
 	Description  string      `json:"description"`
-	DataType     string      `json:"data_type"` // e.g., "float64", "int", "string", "bool"
+	DataType     string      `json:"data_type"` // e.g., "float64", "int", "string", "bool", "color", "enum"
 	DefaultValue interface{} `json:"default_value"`
 	MinValue     interface{} `json:"min_value,omitempty"`
 	MaxValue     interface{} `json:"max_value,omitempty"`
+	Options      []string    `json:"options,omitempty"` // Allowed values of an "enum" parameter
 }
 
 // EffectMetadata holds comprehensive metadata about an effect.
@@ -33,6 +33,12 @@ func ParseHexColor(hex string) (dmx.Lamp, error) {
 	return dmx.Lamp{R: uint8(r), G: uint8(g), B: uint8(b), W: 0}, nil
 }
 
+// FormatHexColor converts a dmx.Lamp to a hex color string (e.g., "#FF8000").
+// The W channel is dropped.
+func FormatHexColor(lamp dmx.Lamp) string {
+	return fmt.Sprintf("#%02X%02X%02X", lamp.R, lamp.G, lamp.B)
+}
+
 // HsvToRgb converts an HSV color value to RGB.
 // h is from 0 to 1; s and v are from 0 to 1.
 // r, g, b are from 0 to 255.
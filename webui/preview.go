@@ -0,0 +1,107 @@
+package webui
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"godmx/orchestrator"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPreviewFPS = 20
+	maxPreviewFPS     = 60
+	previewKeepAlive  = 15 * time.Second // Comment sent on an idle stream, so proxies keep it open
+)
+
+// previewChain is a chain frame as sent to the preview page. Lamps holds
+// 8 hex digits (RGBW) per lamp.
+type previewChain struct {
+	ID    string `json:"id"`
+	Lamps string `json:"lamps"`
+}
+
+// framesHandler streams the chains' frames as server-sent events. The optional
+// fps query parameter sets the rate, frames in between are dropped.
+func framesHandler(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		fps := defaultPreviewFPS
+		if value := r.URL.Query().Get("fps"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxPreviewFPS {
+				http.Error(w, fmt.Sprintf("fps must be between 1 and %d", maxPreviewFPS), http.StatusBadRequest)
+				return
+			}
+			fps = parsed
+		}
+
+		stop := orch.WatchFrames()
+		defer stop()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ticker := time.NewTicker(time.Second / time.Duration(fps))
+		defer ticker.Stop()
+		lastSeq := make(map[string]uint64)
+		lastSent := time.Now()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+
+			frames := orch.Frames()
+			changed := len(frames) != len(lastSeq)
+			chains := make([]previewChain, len(frames))
+			seqs := make(map[string]uint64, len(frames))
+			for i, frame := range frames {
+				if lastSeq[frame.ChainID] != frame.Seq {
+					changed = true
+				}
+				seqs[frame.ChainID] = frame.Seq
+				chains[i] = previewChain{ID: frame.ChainID, Lamps: encodeLamps(frame)}
+			}
+			lastSeq = seqs
+
+			if !changed {
+				if time.Since(lastSent) > previewKeepAlive {
+					fmt.Fprint(w, ": keepalive\n\n")
+					flusher.Flush()
+					lastSent = time.Now()
+				}
+				continue
+			}
+			data, err := json.Marshal(map[string]interface{}{"chains": chains})
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+			lastSent = time.Now()
+		}
+	}
+}
+
+// encodeLamps encodes a frame as hex RGBW values.
+func encodeLamps(frame orchestrator.ChainFrame) string {
+	raw := make([]byte, 0, len(frame.Lamps)*4)
+	for _, lamp := range frame.Lamps {
+		raw = append(raw, lamp.R, lamp.G, lamp.B, lamp.W)
+	}
+	return hex.EncodeToString(raw)
+}
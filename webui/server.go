@@ -4,8 +4,12 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"godmx/artnet"
 	"godmx/config"
+	"godmx/effects"
 	"godmx/orchestrator"
+	"godmx/types"
+	"io"
 	"io/fs"
 	"log"
 	"net/http"
@@ -63,6 +67,7 @@ type ChainConfig struct {
 
 // EffectConfig represents a simplified effect configuration for JSON serialization
 type EffectConfig struct {
+	ID      string                 `json:"ID"`
 	Type    string                 `json:"Type"`
 	Args    map[string]interface{} `json:"Args"`
 	Enabled *bool                  `json:"Enabled,omitempty"`
@@ -80,25 +85,99 @@ type OutputConfig struct {
 
 
 
+// configHandler serves the running configuration. POST validates a new one,
+// applies it and saves it; an invalid one is rejected with the list of issues
+// and nothing changes.
+func configHandler(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodPost {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			newCfg, err := config.ParseConfig(body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			// Invalid configurations are rejected with the list of issues
+			issues := orchestrator.ValidateConfig(newCfg)
+			if err := issues.Err(); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{"status": "error", "message": err.Error(), "issues": issues})
+				return
+			}
+			if err := orch.ApplyConfig(newCfg); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := orch.SaveConfig(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			log.Println("Configuration updated from the web UI")
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "message": "Configuration applied and saved", "issues": issues})
+			return
+		}
+
+		orch.ReadConfig(func(cfg *config.Config) {
+			json.NewEncoder(w).Encode(cfg)
+		})
+	}
+}
+
 // StartWebServer starts the HTTP server for the web UI.
-func StartWebServer(orch *orchestrator.Orchestrator, cfg *config.Config, port int) {
+func StartWebServer(orch *orchestrator.Orchestrator, port int) {
 	// Serve static files
 		http.Handle("/static/", http.StripPrefix("/static/", &staticHandler{http.FS(content)}))
 
-	// Serve index.html
+	// Serve index.html and the editor pages
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" && r.URL.Path != "/index.html" {
+		page := r.URL.Path
+		if page == "/" {
+			page = "/index.html"
+		}
+		if path.Ext(page) != ".html" || path.Dir(page) != "/" {
 			http.NotFound(w, r)
 			return
 		}
-		indexHTML, err := fs.ReadFile(content, "web/index.html")
+		pageHTML, err := fs.ReadFile(content, "web"+page)
 		if err != nil {
-			http.Error(w, "Could not read index.html", http.StatusInternalServerError)
-			log.Printf("Error reading index.html: %v", err)
+			http.NotFound(w, r)
 			return
 		}
 		w.Header().Set("Content-Type", "text/html")
-		w.Write(indexHTML)
+		w.Write(pageHTML)
+	})
+
+	// API endpoint for the full configuration, POST validates, applies and saves a new one
+	http.HandleFunc("/api/config", configHandler(orch))
+
+	// JSON Schema of the configuration file, for editors
+	http.HandleFunc("/api/config/schema", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		json.NewEncoder(w).Encode(orchestrator.ConfigSchema())
+	})
+
+	// API endpoint for the metadata of all registered effects
+	http.HandleFunc("/api/effects/schema", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		schemas := make(map[string]types.EffectMetadata)
+		for _, name := range effects.GetAvailableEffects() {
+			if metadata, ok := effects.GetEffectMetadata(name); ok {
+				schemas[name] = metadata
+			}
+		}
+		json.NewEncoder(w).Encode(schemas)
+	})
+
+	// API endpoint for the schemas of all action types
+	http.HandleFunc("/api/actions/schema", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(orchestrator.ActionSchemas)
 	})
 
 	// API endpoint for chains
@@ -106,28 +185,60 @@ func StartWebServer(orch *orchestrator.Orchestrator, cfg *config.Config, port in
 		w.Header().Set("Content-Type", "application/json")
 		// Convert orchestrator chains to simplified ChainConfig for JSON serialization
 		var simplifiedChains []ChainConfig
-		for _, chainCfg := range cfg.Chains {
-			simplifiedOutput := OutputConfig{
-				Type:               chainCfg.Output.Type,
-				Args:               chainCfg.Output.Args,
-				ChannelMapping:     chainCfg.Output.ChannelMapping,
-				NumChannelsPerLamp: chainCfg.Output.NumChannelsPerLamp,
-			}
-			var simplifiedEffects []EffectConfig
-			for _, effectCfg := range chainCfg.Effects {
-				simplifiedEffects = append(simplifiedEffects, EffectConfig{Type: effectCfg.Type, Args: effectCfg.Args, Enabled: effectCfg.Enabled})
-			}
-			simplifiedChains = append(simplifiedChains, ChainConfig{
-				ID:        chainCfg.ID,
-				Priority:  chainCfg.Priority,
-				TickRate:  chainCfg.TickRate,
-				NumLamps:  chainCfg.NumLamps,
-				Output:    simplifiedOutput,
-				Effects:   simplifiedEffects,
-			})
-		}
-
-		json.NewEncoder(w).Encode(simplifiedChains)
+		orch.ReadConfig(func(cfg *config.Config) {
+			for _, chainCfg := range cfg.Chains {
+				simplifiedOutput := OutputConfig{
+					Type:               chainCfg.Output.Type,
+					Args:               chainCfg.Output.Args,
+					ChannelMapping:     chainCfg.Output.ChannelMapping,
+					NumChannelsPerLamp: chainCfg.Output.NumChannelsPerLamp,
+				}
+				var simplifiedEffects []EffectConfig
+				for _, effectCfg := range chainCfg.Effects {
+					simplifiedEffects = append(simplifiedEffects, EffectConfig{ID: effectCfg.ID, Type: effectCfg.Type, Args: effectCfg.Args, Enabled: effectCfg.Enabled})
+				}
+				simplifiedChains = append(simplifiedChains, ChainConfig{
+					ID:        chainCfg.ID,
+					Priority:  chainCfg.Priority,
+					TickRate:  chainCfg.TickRate,
+					NumLamps:  chainCfg.NumLamps,
+					Output:    simplifiedOutput,
+					Effects:   simplifiedEffects,
+				})
+			}
+			// Encode while holding the lock, the args maps are shared with the config
+			json.NewEncoder(w).Encode(simplifiedChains)
+		})
+	})
+
+	// Stream of the chains' rendered frames for the preview page
+	http.HandleFunc("/api/frames", framesHandler(orch))
+
+	// API endpoint to change a parameter of a running effect
+	http.HandleFunc("/api/effect/param", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var data struct {
+			ChainID  string      `json:"chain_id"`
+			EffectID string      `json:"effect_id"`
+			Param    string      `json:"param"`
+			Value    interface{} `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := orch.SetEffectParam(data.ChainID, data.EffectID, data.Param, data.Value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Effect '%s' in chain '%s': %s set to %v", data.EffectID, data.ChainID, data.Param, data.Value)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Parameter updated"})
 	})
 
 	// API endpoint for BPM
@@ -149,13 +260,78 @@ func StartWebServer(orch *orchestrator.Orchestrator, cfg *config.Config, port in
 		json.NewEncoder(w).Encode(map[string]float64{"bpm": orch.GetGlobals().BPM})
 		})
 
+	// API endpoint for tap tempo, every POST is one tap
+	http.HandleFunc("/api/tap", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]float64{"bpm": orch.Tap()})
+	})
+
+	// API endpoint to resync the beat phase to the current moment
+	http.HandleFunc("/api/resync", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var data struct {
+			Align string `json:"align"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		switch data.Align {
+		case "", "beat", "bar", "phrase":
+		default:
+			http.Error(w, fmt.Sprintf("invalid align '%s', must be beat, bar or phrase", data.Align), http.StatusBadRequest)
+			return
+		}
+		orch.ResyncBeat(data.Align)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Beat resynced"})
+	})
+
+	// API endpoint for the grand master intensity and blackout
+	http.HandleFunc("/api/master", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodPost {
+			var data struct {
+				Intensity *int  `json:"intensity"`
+				Blackout  *bool `json:"blackout"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if data.Intensity != nil {
+				orch.SetIntensity(*data.Intensity)
+				log.Printf("Master intensity updated to: %d", *data.Intensity)
+			}
+			if data.Blackout != nil {
+				orch.SetBlackout(*data.Blackout)
+				log.Printf("Blackout set to: %t", *data.Blackout)
+			}
+		}
+
+		globals := orch.GetGlobals()
+		json.NewEncoder(w).Encode(map[string]interface{}{"intensity": globals.Intensity, "blackout": globals.Blackout})
+	})
+
 	// API endpoint to list all events
 	http.HandleFunc("/api/events", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		var eventNames []string
-		for name := range cfg.Actions {
-			eventNames = append(eventNames, name)
-		}
+		orch.ReadConfig(func(cfg *config.Config) {
+			for name := range cfg.Actions {
+				eventNames = append(eventNames, name)
+			}
+		})
 		sort.Strings(eventNames) // Sort event names alphabetically
 		json.NewEncoder(w).Encode(eventNames)
 		})
@@ -180,6 +356,53 @@ func StartWebServer(orch *orchestrator.Orchestrator, cfg *config.Config, port in
 		json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Event triggered"})
 		})
 
+	// API endpoint for the cue lists, GET lists them with their current cue, POST sends
+	// a command (go, back, jump or stop) to one of them
+	http.HandleFunc("/api/cues", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var data struct {
+				CueList string `json:"cue_list"`
+				Command string `json:"command"`
+				Cue     string `json:"cue"` // Name of the cue, for jump
+			}
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var err error
+			switch data.Command {
+			case "go":
+				err = orch.CueGo(data.CueList)
+			case "back":
+				err = orch.CueBack(data.CueList)
+			case "jump":
+				err = orch.CueJump(data.CueList, data.Cue)
+			case "stop":
+				err = orch.CueStop(data.CueList)
+			default:
+				err = fmt.Errorf("invalid command '%s', must be go, back, jump or stop", data.Command)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(orch.CueLists())
+	})
+
+	// API endpoint to discover Art-Net nodes on the network
+	http.HandleFunc("/api/artnet/nodes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		nodes, err := artnet.Discover(2 * time.Second)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(nodes)
+	})
+
 	log.Printf("Web UI server starting on port %d\n", port)
 	go func() {
 		if err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil); err != nil {
@@ -0,0 +1,137 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"godmx/config"
+	"godmx/dmx"
+	"godmx/orchestrator"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// discardOutput is an Output that drops all frames.
+type discardOutput struct{}
+
+func (discardOutput) Send(lamps []dmx.Lamp) error { return nil }
+func (discardOutput) Close()                      {}
+
+// testConfigJSON is a configuration with one chain dimming its lamps to percentage.
+func testConfigJSON(percentage interface{}) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"globals": map[string]interface{}{"bpm": 120, "color1": "#FF0000", "color2": "#0000FF"},
+		"chains": []map[string]interface{}{{
+			"id":       "main",
+			"tickRate": 30,
+			"numLamps": 4,
+			"output":   map[string]interface{}{"type": "artnet", "args": map[string]interface{}{"ip": "127.0.0.1"}},
+			"effects": []map[string]interface{}{
+				{"id": "dim", "type": "dim", "args": map[string]interface{}{"percentage": percentage}},
+			},
+		}},
+		"actions": map[string]interface{}{},
+	})
+	return data
+}
+
+// newTestOrchestrator starts an orchestrator of testConfigJSON(0.5) saving to a
+// temporary file, which it returns.
+func newTestOrchestrator(t *testing.T) (*orchestrator.Orchestrator, string) {
+	t.Helper()
+	cfg, err := config.ParseConfig(testConfigJSON(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := orchestrator.NewOrchestrator(cfg)
+	path := filepath.Join(t.TempDir(), "config.json")
+	o.SetConfigPath(path)
+	if err := o.Start(func(config.OutputConfig) (orchestrator.Output, error) { return discardOutput{}, nil }); err != nil {
+		t.Fatal(err)
+	}
+	return o, path
+}
+
+// percentage returns the percentage arg of the dim effect of the running configuration.
+func percentage(o *orchestrator.Orchestrator) interface{} {
+	var value interface{}
+	o.ReadConfig(func(cfg *config.Config) {
+		value = cfg.Chains[0].Effects[0].Args["percentage"]
+	})
+	return value
+}
+
+func TestConfigHandlerGet(t *testing.T) {
+	o, _ := newTestOrchestrator(t)
+	recorder := httptest.NewRecorder()
+	configHandler(o)(recorder, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	got, err := config.ParseConfig(recorder.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Chains) != 1 || got.Chains[0].ID != "main" || got.Chains[0].Effects[0].Args["percentage"] != 0.5 {
+		t.Errorf("unexpected config: %+v", got.Chains)
+	}
+}
+
+func TestConfigHandlerPostInvalid(t *testing.T) {
+	o, path := newTestOrchestrator(t)
+	recorder := httptest.NewRecorder()
+	configHandler(o)(recorder, httptest.NewRequest(http.MethodPost, "/api/config", bytes.NewReader(testConfigJSON("half"))))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	var response struct {
+		Status string        `json:"status"`
+		Issues config.Issues `json:"issues"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != "error" {
+		t.Errorf("status = %q, want error", response.Status)
+	}
+	errors := response.Issues.Errors()
+	if len(errors) != 1 || errors[0].Path != "chains[0].effects[0].args.percentage" {
+		t.Errorf("issues = %v, want one at chains[0].effects[0].args.percentage", response.Issues)
+	}
+
+	// Nothing is applied or saved
+	if got := percentage(o); got != 0.5 {
+		t.Errorf("percentage = %v after the invalid config, want 0.5", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the invalid config was saved: %v", err)
+	}
+}
+
+func TestConfigHandlerPostValid(t *testing.T) {
+	o, path := newTestOrchestrator(t)
+	recorder := httptest.NewRecorder()
+	configHandler(o)(recorder, httptest.NewRequest(http.MethodPost, "/api/config", bytes.NewReader(testConfigJSON(0.25))))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	if got := percentage(o); got != 0.25 {
+		t.Errorf("percentage = %v, want 0.25", got)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := config.ParseConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Chains[0].Effects[0].Args["percentage"]; got != 0.25 {
+		t.Errorf("saved percentage = %v, want 0.25", got)
+	}
+}
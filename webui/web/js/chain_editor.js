@@ -7,6 +7,7 @@ document.addEventListener('DOMContentLoaded', () => {
 
     let currentConfig = {}; // In-memory representation of the config
     let effectSchemas = {}; // Stores effect schemas fetched from backend
+    const blendModes = ['normal', 'add', 'multiply', 'screen', 'max', 'min', 'subtract'];
 
     // --- API Calls ---
 
@@ -49,47 +50,65 @@ document.addEventListener('DOMContentLoaded', () => {
                 body: JSON.stringify(currentConfig)
             });
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                // Invalid configurations are rejected with the list of issues
+                const text = await response.text();
+                let message = text;
+                try {
+                    const result = JSON.parse(text);
+                    message = formatIssues(result.issues) || result.message;
+                } catch (e) {
+                    // Not JSON, e.g. a parse error
+                }
+                throw new Error(message);
             }
             const result = await response.json();
             console.log('Save Config Result:', result);
-            alert(result.message || 'Configuration saved successfully!');
+            const warnings = formatIssues(result.issues);
+            alert((result.message || 'Configuration saved successfully!') + (warnings ? `\n\n${warnings}` : ''));
         } catch (error) {
             console.error('Error saving config:', error);
-            alert('Failed to save configuration. Check console for details.');
+            alert(`Failed to save configuration:\n${error.message}`);
+        }
+    }
+
+    // One line per validation issue, e.g. "error: chains[0].tickRate: must be greater than 0"
+    function formatIssues(issues) {
+        if (!issues || issues.length === 0) {
+            return '';
         }
+        return issues.map(issue => `${issue.severity}: ${issue.path}: ${issue.message}`).join('\n');
     }
 
     // --- Rendering Functions ---
 
     function renderConfig() {
         chainsContainer.innerHTML = ''; // Clear existing chains
-        if (currentConfig.Chains) {
-            currentConfig.Chains.forEach(chain => renderChain(chain));
+        if (currentConfig.chains) {
+            currentConfig.chains.forEach(chain => renderChain(chain));
         }
     }
 
     function renderChain(chain) {
         const chainDiv = document.createElement('div');
         chainDiv.className = 'chain-item';
-        chainDiv.dataset.chainId = chain.ID;
+        chainDiv.dataset.chainId = chain.id;
         chainDiv.innerHTML = `
-            <h3>Chain: ${chain.ID}</h3>
-            <p>Priority: <input type="number" class="chain-priority" value="${chain.Priority}" data-chain-id="${chain.ID}"></p>
-            <p>TickRate: <input type="number" class="chain-tickrate" value="${chain.TickRate}" data-chain-id="${chain.ID}"></p>
-            <p>NumLamps: <input type="number" class="chain-numlamps" value="${chain.NumLamps}" data-chain-id="${chain.ID}"></p>
+            <h3>Chain: ${chain.id}</h3>
+            <p>Priority: <input type="number" class="chain-priority" value="${chain.priority}" data-chain-id="${chain.id}"></p>
+            <p>TickRate: <input type="number" class="chain-tickRate" value="${chain.tickRate}" data-chain-id="${chain.id}"></p>
+            <p>NumLamps: <input type="number" class="chain-numLamps" value="${chain.numLamps}" data-chain-id="${chain.id}"></p>
             <h4>Effects</h4>
-            <button class="add-effect" data-chain-id="${chain.ID}">Add Effect</button>
-            <div class="effects-container" data-chain-id="${chain.ID}">
+            <button class="add-effect" data-chain-id="${chain.id}">Add Effect</button>
+            <div class="effects-container" data-chain-id="${chain.id}">
                 <!-- Effects will be rendered here -->
             </div>
-            <button class="remove-chain" data-chain-id="${chain.ID}">Remove Chain</button>
+            <button class="remove-chain" data-chain-id="${chain.id}">Remove Chain</button>
         `;
         chainsContainer.appendChild(chainDiv);
 
         const effectsContainer = chainDiv.querySelector('.effects-container');
-        if (chain.Effects) {
-            chain.Effects.forEach(effect => renderEffect(effect, effectsContainer, chain.ID));
+        if (chain.effects) {
+            chain.effects.forEach(effect => renderEffect(effect, effectsContainer, chain.id));
         }
 
         // Add event listeners for chain properties
@@ -118,12 +137,12 @@ document.addEventListener('DOMContentLoaded', () => {
     function renderEffect(effect, effectsContainer, chainId) {
         const effectDiv = document.createElement('div');
         effectDiv.className = 'effect-item';
-        effectDiv.dataset.effectId = effect.ID;
+        effectDiv.dataset.effectId = effect.id;
         effectDiv.dataset.chainId = chainId;
 
         const effectTypeSelect = document.createElement('select');
         effectTypeSelect.className = 'effect-type';
-        effectTypeSelect.dataset.effectId = effect.ID;
+        effectTypeSelect.dataset.effectId = effect.id;
         effectTypeSelect.dataset.chainId = chainId;
 
         // Populate effect types from schemas
@@ -131,22 +150,26 @@ document.addEventListener('DOMContentLoaded', () => {
             const option = document.createElement('option');
             option.value = type;
             option.textContent = effectSchemas[type].human_readable_name || type;
-            if (type === effect.Type) {
+            if (type === effect.type) {
                 option.selected = true;
             }
             effectTypeSelect.appendChild(option);
         }
 
         effectDiv.innerHTML = `
-            <h5>Effect: ${effect.ID || 'New Effect'} (Type: <span class="effect-type-display">${effect.Type}</span>)</h5>
-            <p>ID: <input type="text" class="effect-id" value="${effect.ID || ''}" data-effect-id="${effect.ID}" data-chain-id="${chainId}"></p>
-            <p>Enabled: <input type="checkbox" class="effect-enabled" ${effect.Enabled ? 'checked' : ''} data-effect-id="${effect.ID}" data-chain-id="${chainId}"></p>
-            <p>Group: <input type="text" class="effect-group" value="${effect.Group || ''}" data-effect-id="${effect.ID}" data-chain-id="${chainId}"></p>
+            <h5>Effect: ${effect.id || 'New Effect'} (Type: <span class="effect-type-display">${effect.type}</span>)</h5>
+            <p>ID: <input type="text" class="effect-id" value="${effect.id || ''}" data-effect-id="${effect.id}" data-chain-id="${chainId}"></p>
+            <p>Enabled: <input type="checkbox" class="effect-enabled" ${effect.enabled !== false ? 'checked' : ''} data-effect-id="${effect.id}" data-chain-id="${chainId}"></p>
+            <p>Group: <input type="text" class="effect-group" value="${effect.group || ''}" data-effect-id="${effect.id}" data-chain-id="${chainId}"></p>
+            <p>Blend: <select class="effect-blend" data-effect-id="${effect.id}" data-chain-id="${chainId}">
+                ${blendModes.map(mode => `<option value="${mode}" ${(effect.blend || 'normal') === mode ? 'selected' : ''}>${mode}</option>`).join('')}
+            </select></p>
+            <p>Opacity: <input type="number" class="effect-opacity" min="0" max="1" step="0.05" value="${effect.opacity !== undefined ? effect.opacity : 1}" data-effect-id="${effect.id}" data-chain-id="${chainId}"></p>
             <h6>Arguments</h6>
-            <div class="effect-args-container" data-effect-id="${effect.ID}" data-chain-id="${chainId}">
+            <div class="effect-args-container" data-effect-id="${effect.id}" data-chain-id="${chainId}">
                 <!-- Args will be rendered here -->
             </div>
-            <button class="remove-effect" data-effect-id="${effect.ID}" data-chain-id="${chainId}">Remove Effect</button>
+            <button class="remove-effect" data-effect-id="${effect.id}" data-chain-id="${chainId}">Remove Effect</button>
         `;
         effectsContainer.appendChild(effectDiv);
 
@@ -157,7 +180,7 @@ document.addEventListener('DOMContentLoaded', () => {
         renderEffectArgs(effect, argsContainer, chainId);
 
         // Add event listeners for effect properties
-        effectDiv.querySelectorAll('input, select').forEach(input => {
+        effectDiv.querySelectorAll('input:not(.effect-arg-input), select:not(.effect-arg-input)').forEach(input => {
             input.addEventListener('change', (e) => {
                 const chainId = e.target.dataset.chainId;
                 const effectId = e.target.dataset.effectId;
@@ -192,19 +215,32 @@ document.addEventListener('DOMContentLoaded', () => {
 
     function renderEffectArgs(effect, argsContainer, chainId) {
         argsContainer.innerHTML = ''; // Clear existing args
-        const schema = effectSchemas[effect.Type];
-        if (!schema || !schema.args) {
+        const schema = effectSchemas[effect.type];
+        if (!schema || !schema.parameters || schema.parameters.length === 0) {
             argsContainer.innerHTML = '<p>No arguments for this effect type.</p>';
             return;
         }
 
-        for (const argName in schema.args) {
-            const argSchema = schema.args[argName];
-            const argValue = effect.Args ? effect.Args[argName] : argSchema.default_value;
+        schema.parameters.forEach(argSchema => {
+            const argName = argSchema.internal_name;
+            const argValue = effect.args && effect.args[argName] !== undefined ? effect.args[argName] : argSchema.default_value;
             const argDiv = document.createElement('div');
             argDiv.className = 'effect-arg-item';
             argDiv.innerHTML = `<label>${argSchema.display_name || argName}:</label>`;
 
+            if (argValue && typeof argValue === 'object' && argValue.modulator) {
+                // Bound to a modulator, edited in the config file
+                const boundInput = document.createElement('input');
+                boundInput.type = 'text';
+                boundInput.readOnly = true;
+                boundInput.value = `modulated by ${argValue.modulator}`;
+                boundInput.title = JSON.stringify(argValue);
+                boundInput.className = 'effect-arg-input';
+                argDiv.appendChild(boundInput);
+                argsContainer.appendChild(argDiv);
+                return;
+            }
+
             let inputElement;
             switch (argSchema.data_type) {
                 case 'string':
@@ -230,6 +266,16 @@ document.addEventListener('DOMContentLoaded', () => {
                     inputElement.type = 'checkbox';
                     inputElement.checked = argValue;
                     break;
+                case 'enum':
+                    inputElement = createOptionSelect(argSchema.options, argValue);
+                    break;
+                case 'color':
+                    inputElement = document.createElement('input');
+                    inputElement.type = 'color';
+                    inputElement.value = colorInputValue(argValue);
+                    // An empty color means the effect uses the global color
+                    inputElement.dataset.unset = argValue ? 'false' : 'true';
+                    break;
                 default:
                     inputElement = document.createElement('input');
                     inputElement.type = 'text';
@@ -237,7 +283,7 @@ document.addEventListener('DOMContentLoaded', () => {
                     break;
             }
             inputElement.dataset.chainId = chainId;
-            inputElement.dataset.effectId = effect.ID;
+            inputElement.dataset.effectId = effect.id;
             inputElement.dataset.argName = argName;
             inputElement.className = 'effect-arg-input';
 
@@ -250,20 +296,54 @@ document.addEventListener('DOMContentLoaded', () => {
                     value = e.target.checked;
                 } else if (e.target.type === 'number') {
                     value = parseFloat(e.target.value);
+                } else if (e.target.type === 'color') {
+                    value = e.target.value.toUpperCase();
+                    e.target.dataset.unset = 'false';
                 } else {
                     value = e.target.value;
                 }
                 updateEffectArg(chainId, effectId, argName, value);
             });
             argDiv.appendChild(inputElement);
+
+            if (argSchema.data_type === 'color') {
+                const clearButton = document.createElement('button');
+                clearButton.type = 'button';
+                clearButton.textContent = 'Clear';
+                clearButton.title = 'Use the global color';
+                clearButton.addEventListener('click', () => {
+                    inputElement.dataset.unset = 'true';
+                    updateEffectArg(chainId, effect.id, argName, '');
+                });
+                argDiv.appendChild(clearButton);
+            }
             argsContainer.appendChild(argDiv);
-        }
+        });
+    }
+
+    // createOptionSelect returns a dropdown with the options of an enum parameter.
+    function createOptionSelect(options, value) {
+        const select = document.createElement('select');
+        (options || []).forEach(option => {
+            const optionElement = document.createElement('option');
+            optionElement.value = option;
+            optionElement.textContent = option;
+            select.appendChild(optionElement);
+        });
+        select.value = value;
+        return select;
+    }
+
+    // colorInputValue converts a hex color like "FF8000" or "#ff8000" to the format of color inputs.
+    function colorInputValue(value) {
+        const hex = typeof value === 'string' ? value.replace(/^#/, '') : '';
+        return /^[0-9A-Fa-f]{6}$/.test(hex) ? '#' + hex.toLowerCase() : '#000000';
     }
 
     // --- Data Manipulation Functions ---
 
     function updateChainProperty(chainId, prop, value) {
-        const chain = currentConfig.Chains.find(c => c.ID === chainId);
+        const chain = currentConfig.chains.find(c => c.id === chainId);
         if (chain) {
             chain[prop] = value;
             console.log(`Updated Chain ${chainId} property ${prop} to ${value}`);
@@ -271,9 +351,9 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 
     function updateEffectProperty(chainId, effectId, prop, value) {
-        const chain = currentConfig.Chains.find(c => c.ID === chainId);
+        const chain = currentConfig.chains.find(c => c.id === chainId);
         if (chain) {
-            const effect = chain.Effects.find(e => e.ID === effectId);
+            const effect = chain.effects.find(e => e.id === effectId);
             if (effect) {
                 effect[prop] = value;
                 console.log(`Updated Effect ${effectId} property ${prop} to ${value} in Chain ${chainId}`);
@@ -282,26 +362,26 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 
     function updateEffectArg(chainId, effectId, argName, value) {
-        const chain = currentConfig.Chains.find(c => c.ID === chainId);
+        const chain = currentConfig.chains.find(c => c.id === chainId);
         if (chain) {
-            const effect = chain.Effects.find(e => e.ID === effectId);
+            const effect = chain.effects.find(e => e.id === effectId);
             if (effect) {
-                if (!effect.Args) {
-                    effect.Args = {};
+                if (!effect.args) {
+                    effect.args = {};
                 }
-                effect.Args[argName] = value;
+                effect.args[argName] = value;
                 console.log(`Updated Effect ${effectId} arg ${argName} to ${value} in Chain ${chainId}`);
             }
         }
     }
 
     function updateEffectType(chainId, effectId, newType) {
-        const chain = currentConfig.Chains.find(c => c.ID === chainId);
+        const chain = currentConfig.chains.find(c => c.id === chainId);
         if (chain) {
-            const effect = chain.Effects.find(e => e.ID === effectId);
+            const effect = chain.effects.find(e => e.id === effectId);
             if (effect) {
-                effect.Type = newType;
-                effect.Args = {}; // Clear args when type changes
+                effect.type = newType;
+                effect.args = {}; // Clear args when type changes
                 console.log(`Updated Effect ${effectId} type to ${newType} in Chain ${chainId}`);
                 // Re-render the specific effect to update its args section
                 const effectsContainer = document.querySelector(`.effects-container[data-chain-id="${chainId}"]`);
@@ -315,38 +395,38 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 
     function addChain() {
-        const newChainId = `newChain${currentConfig.Chains.length + 1}`;
+        const newChainId = `newChain${currentConfig.chains.length + 1}`;
         const newChain = {
-            ID: newChainId,
-            Priority: 0,
-            TickRate: 100,
-            NumLamps: 1,
-            Output: { Type: "artnet", Args: { "ip": "127.0.0.1" }, ChannelMapping: "RGB", NumChannelsPerLamp: 3 }, // Default output
-            Effects: []
+            id: newChainId,
+            priority: 0,
+            tickRate: 100,
+            numLamps: 1,
+            output: { type: "artnet", args: { "ip": "127.0.0.1" }, channelMapping: "RGB", numChannelsPerLamp: 3 }, // Default output
+            effects: []
         };
-        currentConfig.Chains.push(newChain);
+        currentConfig.chains.push(newChain);
         renderChain(newChain);
         console.log(`Added new chain: ${newChainId}`);
     }
 
     function removeChain(chainId) {
-        currentConfig.Chains = currentConfig.Chains.filter(c => c.ID !== chainId);
+        currentConfig.chains = currentConfig.chains.filter(c => c.id !== chainId);
         document.querySelector(`.chain-item[data-chain-id="${chainId}"]`).remove();
         console.log(`Removed chain: ${chainId}`);
     }
 
     function addEffectToChain(chainId) {
-        const chain = currentConfig.Chains.find(c => c.ID === chainId);
+        const chain = currentConfig.chains.find(c => c.id === chainId);
         if (chain) {
-            const newEffectId = `newEffect${chain.Effects.length + 1}`;
+            const newEffectId = `newEffect${chain.effects.length + 1}`;
             const newEffect = {
-                ID: newEffectId,
-                Type: Object.keys(effectSchemas)[0] || 'solid_color', // Default to first available or solid_color
-                Args: {},
-                Enabled: true,
-                Group: ''
+                id: newEffectId,
+                type: Object.keys(effectSchemas)[0] || 'solid_color', // Default to first available or solid_color
+                args: {},
+                enabled: true,
+                group: ''
             };
-            chain.Effects.push(newEffect);
+            chain.effects.push(newEffect);
             const effectsContainer = document.querySelector(`.effects-container[data-chain-id="${chainId}"]`);
             renderEffect(newEffect, effectsContainer, chainId);
             console.log(`Added new effect ${newEffectId} to chain ${chainId}`);
@@ -354,9 +434,9 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 
     function removeEffect(chainId, effectId) {
-        const chain = currentConfig.Chains.find(c => c.ID === chainId);
+        const chain = currentConfig.chains.find(c => c.id === chainId);
         if (chain) {
-            chain.Effects = chain.Effects.filter(e => e.ID !== effectId);
+            chain.effects = chain.effects.filter(e => e.id !== effectId);
             document.querySelector(`.effect-item[data-effect-id="${effectId}"][data-chain-id="${chainId}"]`).remove();
             console.log(`Removed effect ${effectId} from chain ${chainId}`);
         }
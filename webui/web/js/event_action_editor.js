@@ -8,6 +8,16 @@ document.addEventListener('DOMContentLoaded', () => {
     let currentConfig = {}; // In-memory representation of the config
     let actionSchemas = {}; // Stores action schemas fetched from backend
 
+    // Parameters stored on the action itself rather than in its params
+    const topLevelParams = ['chain_id', 'effect_id', 'delay', 'delay_unit', 'quantize'];
+
+    // When an action executes, every action type has these
+    const timingParams = [
+        { internal_name: 'delay', display_name: 'Delay', data_type: 'float64', min_value: 0 },
+        { internal_name: 'delay_unit', display_name: 'Delay Unit', data_type: 'string', default_value: 'seconds', options: ['seconds', 'beats', 'bars'] },
+        { internal_name: 'quantize', display_name: 'Quantize', data_type: 'string', options: ['', 'beat', 'bar', 'phrase'] },
+    ];
+
     // --- API Calls ---
 
     async function fetchActionSchemas() {
@@ -49,23 +59,44 @@ document.addEventListener('DOMContentLoaded', () => {
                 body: JSON.stringify(currentConfig)
             });
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                // Invalid configurations are rejected with the list of issues
+                const text = await response.text();
+                let message = text;
+                try {
+                    const result = JSON.parse(text);
+                    message = formatIssues(result.issues) || result.message;
+                } catch (e) {
+                    // Not JSON, e.g. a parse error
+                }
+                throw new Error(message);
             }
             const result = await response.json();
             console.log('Save Config Result:', result);
-            alert(result.message || 'Configuration saved successfully!');
+            const warnings = formatIssues(result.issues);
+            alert((result.message || 'Configuration saved successfully!') + (warnings ? `\n\n${warnings}` : ''));
         } catch (error) {
             console.error('Error saving config:', error);
-            alert('Failed to save configuration. Check console for details.');
+            alert(`Failed to save configuration:\n${error.message}`);
         }
     }
 
+    // One line per validation issue, e.g. "error: chains[0].tickRate: must be greater than 0"
+    function formatIssues(issues) {
+        if (!issues || issues.length === 0) {
+            return '';
+        }
+        return issues.map(issue => `${issue.severity}: ${issue.path}: ${issue.message}`).join('\n');
+    }
+
     // --- Rendering Functions ---
 
     function renderConfig() {
         eventsContainer.innerHTML = ''; // Clear existing events
-        for (const eventName in currentConfig.events) {
-            renderEvent(eventName, currentConfig.events[eventName]);
+        if (!currentConfig.actions) {
+            currentConfig.actions = {};
+        }
+        for (const eventName in currentConfig.actions) {
+            renderEvent(eventName, currentConfig.actions[eventName]);
         }
     }
 
@@ -97,11 +128,11 @@ document.addEventListener('DOMContentLoaded', () => {
         });
     }
 
-    function renderAction(action, actionsContainer, eventName) {
+    function renderAction(action, actionsContainer, eventName, index) {
         const actionDiv = document.createElement('div');
         actionDiv.className = 'action-item';
         actionDiv.dataset.eventName = eventName;
-        const actionIndex = actionsContainer.children.length; 
+        const actionIndex = index !== undefined ? index : actionsContainer.children.length;
         actionDiv.dataset.actionIndex = actionIndex;
 
         const h5Element = document.createElement('h5');
@@ -159,15 +190,20 @@ document.addEventListener('DOMContentLoaded', () => {
 
     function renderActionParams(action, paramsContainer, eventName, actionIndex) {
         paramsContainer.innerHTML = ''; // Clear existing params
-        const schema = actionSchemas[action.Type];
-        if (!schema || !schema.Parameters) {
-            paramsContainer.innerHTML = '<p>No parameters for this action type.</p>';
+        const schema = actionSchemas[action.type];
+        if (!schema) {
+            paramsContainer.innerHTML = '<p>Unknown action type.</p>';
             return;
         }
 
-        schema.Parameters.forEach(paramSchema => {
+        (schema.parameters || []).concat(timingParams).forEach(paramSchema => {
             const paramName = paramSchema.internal_name;
-            const paramValue = action.params ? action.params[paramName] : paramSchema.default_value;
+            let paramValue = paramSchema.default_value;
+            if (topLevelParams.includes(paramName)) {
+                paramValue = action[paramName] || paramSchema.default_value || '';
+            } else if (action.params && action.params[paramName] !== undefined) {
+                paramValue = action.params[paramName];
+            }
             const paramDiv = document.createElement('div');
             paramDiv.className = 'action-param-item';
             paramDiv.innerHTML = `<label>${paramSchema.display_name || paramName}:</label>`;
@@ -175,9 +211,19 @@ document.addEventListener('DOMContentLoaded', () => {
             let inputElement;
             switch (paramSchema.data_type) {
                 case 'string':
+                case 'enum':
+                    if (paramSchema.options && paramSchema.options.length > 0) {
+                        inputElement = createOptionSelect(paramSchema.options, paramValue);
+                        break;
+                    }
                     inputElement = document.createElement('input');
                     inputElement.type = 'text';
-                    inputElement.value = paramValue;
+                    inputElement.value = paramValue !== undefined ? paramValue : '';
+                    break;
+                case 'color':
+                    inputElement = document.createElement('input');
+                    inputElement.type = 'color';
+                    inputElement.value = colorInputValue(paramValue);
                     break;
                 case 'float64':
                 case 'int':
@@ -197,6 +243,18 @@ document.addEventListener('DOMContentLoaded', () => {
                     inputElement.type = 'checkbox';
                     inputElement.checked = paramValue;
                     break;
+                case 'list': // Names separated by commas, e.g. the events of random_event
+                    inputElement = document.createElement('input');
+                    inputElement.type = 'text';
+                    inputElement.value = Array.isArray(paramValue) ? paramValue.join(', ') : '';
+                    inputElement.placeholder = "name1, name2";
+                    break;
+                case 'any': // A JSON value, e.g. the value of set_effect_param
+                    inputElement = document.createElement('input');
+                    inputElement.type = 'text';
+                    inputElement.value = paramValue !== undefined ? JSON.stringify(paramValue) : '';
+                    inputElement.placeholder = '0.5, true or "#FF8000"';
+                    break;
                 case 'object': // For complex types like EffectConfig in add_effect
                     // This will require more sophisticated rendering, possibly a nested form
                     inputElement = document.createElement('textarea');
@@ -223,6 +281,17 @@ document.addEventListener('DOMContentLoaded', () => {
                     value = e.target.checked;
                 } else if (e.target.type === 'number') {
                     value = parseFloat(e.target.value);
+                } else if (e.target.type === 'color') {
+                    value = e.target.value.toUpperCase();
+                } else if (paramSchema.data_type === 'list') {
+                    value = e.target.value.split(',').map(item => item.trim()).filter(item => item !== '');
+                } else if (paramSchema.data_type === 'any') {
+                    // Text that isn't JSON, like #FF8000, is taken as a string
+                    try {
+                        value = JSON.parse(e.target.value);
+                    } catch (err) {
+                        value = e.target.value;
+                    }
                 } else if (paramSchema.data_type === 'object') {
                     try {
                         value = JSON.parse(e.target.value);
@@ -241,11 +310,35 @@ document.addEventListener('DOMContentLoaded', () => {
         });
     }
 
+    // createOptionSelect returns a dropdown with the options of an enum parameter.
+    function createOptionSelect(options, value) {
+        const select = document.createElement('select');
+        options.forEach(option => {
+            const optionElement = document.createElement('option');
+            optionElement.value = option;
+            optionElement.textContent = option;
+            select.appendChild(optionElement);
+        });
+        select.value = value;
+        return select;
+    }
+
+    // colorInputValue converts a hex color like "FF8000" or "#ff8000" to the format of color inputs.
+    function colorInputValue(value) {
+        const hex = typeof value === 'string' ? value.replace(/^#/, '') : '';
+        return /^[0-9A-Fa-f]{6}$/.test(hex) ? '#' + hex.toLowerCase() : '#000000';
+    }
+
     // --- Data Manipulation Functions ---
 
     function updateActionParam(eventName, actionIndex, paramName, value) {
-        const actions = currentConfig.events[eventName];
+        const actions = currentConfig.actions[eventName];
         if (actions && actions[actionIndex]) {
+            if (topLevelParams.includes(paramName)) {
+                actions[actionIndex][paramName] = value;
+                console.log(`Updated Event ${eventName} Action ${actionIndex} ${paramName} to ${value}`);
+                return;
+            }
             if (!actions[actionIndex].params) {
                 actions[actionIndex].params = {};
             }
@@ -255,38 +348,36 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 
     function updateActionType(eventName, actionIndex, newType) {
-        const actions = currentConfig.events[eventName];
+        const actions = currentConfig.actions[eventName];
         if (actions && actions[actionIndex]) {
-            actions[actionIndex].type = newType;
-            actions[actionIndex].params = {}; // Clear params when type changes
+            actions[actionIndex] = { type: newType, params: {} }; // Clear params when type changes
             console.log(`Updated Event ${eventName} Action ${actionIndex} type to ${newType}`);
             // Re-render the specific action to update its params section
             const actionsContainer = document.querySelector(`.actions-container[data-event-name="${eventName}"]`);
             const oldActionDiv = actionsContainer.querySelector(`.action-item[data-action-index="${actionIndex}"]`);
+            renderAction(actions[actionIndex], actionsContainer, eventName, actionIndex);
+            // renderAction appends, move the new element into the old one's place
             if (oldActionDiv) {
-                actionsContainer.removeChild(oldActionDiv);
+                actionsContainer.replaceChild(actionsContainer.lastElementChild, oldActionDiv);
             }
-            // Re-render with a new index to avoid conflicts if elements shift
-            // This is a simplified re-render, a more robust solution might re-index all actions
-            renderAction(actions[actionIndex], actionsContainer, eventName);
         }
     }
 
     function addEvent() {
-        const newEventName = `newEvent${Object.keys(currentConfig.events).length + 1}`;
-        currentConfig.events[newEventName] = [];
+        const newEventName = `newEvent${Object.keys(currentConfig.actions).length + 1}`;
+        currentConfig.actions[newEventName] = [];
         renderEvent(newEventName, []);
         console.log(`Added new event: ${newEventName}`);
     }
 
     function removeEvent(eventName) {
-        delete currentConfig.events[eventName];
+        delete currentConfig.actions[eventName];
         document.querySelector(`.event-item[data-event-name="${eventName}"]`).remove();
         console.log(`Removed event: ${eventName}`);
     }
 
     function addActionToEvent(eventName) {
-        const actions = currentConfig.events[eventName];
+        const actions = currentConfig.actions[eventName];
         if (actions) {
             const newAction = {
                 type: Object.keys(actionSchemas)[0] || 'set_global', // Default to first available or set_global
@@ -300,7 +391,7 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 
     function removeAction(eventName, actionIndex) {
-        const actions = currentConfig.events[eventName];
+        const actions = currentConfig.actions[eventName];
         if (actions && actions[actionIndex]) {
             actions.splice(actionIndex, 1);
             // Re-render all actions for this event to update indices
@@ -0,0 +1,91 @@
+// web/js/preview.js
+
+document.addEventListener('DOMContentLoaded', () => {
+    const previewContainer = document.getElementById('preview-container');
+    const fpsSelect = document.getElementById('preview-fps');
+    const statusSpan = document.getElementById('preview-status');
+
+    const lampSize = 16; // Pixels per lamp, including the gap
+    const lampGap = 2;
+
+    let source = null;
+    let canvases = {}; // Canvas per chain ID
+
+    // Returns the canvas of a chain, creating it on first use
+    function getCanvas(chainId) {
+        if (!canvases[chainId]) {
+            const chainBox = document.createElement('div');
+            chainBox.className = 'chain-box preview-chain';
+            chainBox.dataset.chainId = chainId;
+            chainBox.innerHTML = `<h2>${chainId}</h2>`;
+            const canvas = document.createElement('canvas');
+            chainBox.appendChild(canvas);
+            previewContainer.appendChild(chainBox);
+            canvases[chainId] = canvas;
+        }
+        return canvases[chainId];
+    }
+
+    // Draws a frame of hex RGBW values, the white channel is added to all colors
+    function drawFrame(canvas, lamps) {
+        const numLamps = lamps.length / 8;
+        const perRow = Math.max(1, Math.floor(previewContainer.clientWidth / lampSize) - 2);
+        const rows = Math.ceil(numLamps / perRow);
+        const width = Math.min(numLamps, perRow) * lampSize;
+        const height = rows * lampSize;
+        if (canvas.width !== width || canvas.height !== height) {
+            canvas.width = width;
+            canvas.height = height;
+        }
+
+        const ctx = canvas.getContext('2d');
+        ctx.clearRect(0, 0, width, height);
+        for (let i = 0; i < numLamps; i++) {
+            const byte = (n) => parseInt(lamps.substr(i * 8 + n * 2, 2), 16);
+            const w = byte(3);
+            const r = Math.min(255, byte(0) + w);
+            const g = Math.min(255, byte(1) + w);
+            const b = Math.min(255, byte(2) + w);
+            ctx.fillStyle = `rgb(${r}, ${g}, ${b})`;
+            const x = (i % perRow) * lampSize;
+            const y = Math.floor(i / perRow) * lampSize;
+            ctx.fillRect(x, y, lampSize - lampGap, lampSize - lampGap);
+        }
+    }
+
+    function render(data) {
+        const seen = new Set();
+        data.chains.forEach(chain => {
+            seen.add(chain.id);
+            drawFrame(getCanvas(chain.id), chain.lamps);
+        });
+        // Remove chains that are no longer running
+        for (const chainId in canvases) {
+            if (!seen.has(chainId)) {
+                canvases[chainId].parentElement.remove();
+                delete canvases[chainId];
+            }
+        }
+    }
+
+    function connect() {
+        if (source) {
+            source.close();
+        }
+        source = new EventSource(`/api/frames?fps=${fpsSelect.value}`);
+        source.onopen = () => {
+            statusSpan.textContent = 'Live';
+        };
+        source.onmessage = (event) => {
+            render(JSON.parse(event.data));
+        };
+        source.onerror = () => {
+            // EventSource reconnects by itself
+            statusSpan.textContent = 'Disconnected, retrying...';
+        };
+    }
+
+    fpsSelect.addEventListener('change', connect);
+
+    connect();
+});
@@ -2,12 +2,20 @@ document.addEventListener('DOMContentLoaded', () => {
     const bpmValueSpan = document.getElementById('bpm-value');
     const bpmDownButton = document.getElementById('bpm-down');
     const bpmUpButton = document.getElementById('bpm-up');
+    const bpmTapButton = document.getElementById('bpm-tap');
+    const beatResyncButton = document.getElementById('beat-resync');
     const chainsContainer = document.getElementById('chains-container');
     const eventsContainer = document.getElementById('events-container'); // New: Get events container
+    const masterIntensityInput = document.getElementById('master-intensity');
+    const masterValueSpan = document.getElementById('master-value');
+    const blackoutButton = document.getElementById('blackout-toggle');
+    const cuesContainer = document.getElementById('cues-container');
 
     let currentBPM = 0;
     let currentChains = [];
     let currentEvents = []; // New: To track current events
+    let currentBlackout = false;
+    let currentCues = [];
 
     const renderArgs = (args) => {
         if (!args || Object.keys(args).length === 0) {
@@ -22,6 +30,65 @@ document.addEventListener('DOMContentLoaded', () => {
         `;
     };
 
+    // Effect args are editable, changes go to the running effect without a rebuild
+    const renderEffectArgs = (chain, effect) => {
+        if (!effect.Args || Object.keys(effect.Args).length === 0) {
+            return '';
+        }
+        const attrs = (key) => `data-chain="${chain.ID}" data-effect="${effect.ID}" data-param="${key}"`;
+        return `
+            <ul class="args-list">
+                ${Object.entries(effect.Args).map(([key, value]) => {
+                    let input;
+                    if (typeof value === 'number') {
+                        input = `<input class="effect-param" type="number" step="any" value="${value}" ${attrs(key)}>`;
+                    } else if (typeof value === 'boolean') {
+                        input = `<input class="effect-param" type="checkbox" ${value ? 'checked' : ''} ${attrs(key)}>`;
+                    } else if (typeof value === 'string') {
+                        input = `<input class="effect-param" type="text" value="${value}" ${attrs(key)}>`;
+                    } else {
+                        input = JSON.stringify(value);
+                    }
+                    return `<li><strong>${key}:</strong> ${input}</li>`;
+                }).join('')}
+            </ul>
+        `;
+    };
+
+    const updateEffectParam = async (input) => {
+        let value = input.value;
+        if (input.type === 'number') {
+            value = parseFloat(input.value);
+        } else if (input.type === 'checkbox') {
+            value = input.checked;
+        }
+        try {
+            const response = await fetch('/api/effect/param', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({
+                    chain_id: input.dataset.chain,
+                    effect_id: input.dataset.effect,
+                    param: input.dataset.param,
+                    value: value,
+                }),
+            });
+            if (!response.ok) {
+                console.error('Error updating effect parameter:', await response.text());
+            }
+        } catch (error) {
+            console.error('Error updating effect parameter:', error);
+        }
+    };
+
+    chainsContainer.addEventListener('change', (event) => {
+        if (event.target.classList.contains('effect-param')) {
+            updateEffectParam(event.target);
+        }
+    });
+
     const renderChains = (chains) => {
         chainsContainer.innerHTML = ''; // Clear existing chains
         chains.forEach(chain => {
@@ -42,7 +109,7 @@ document.addEventListener('DOMContentLoaded', () => {
                     ${chain.Effects.map(effect => `
                         <div class="chain-element ${effect.Enabled ? '' : 'disabled'}">
                             <h4>Effect: ${effect.Type}</h4>
-                            ${renderArgs(effect.Args)}
+                            ${renderEffectArgs(chain, effect)}
                         </div>
                     `).join('')}
 
@@ -75,6 +142,10 @@ document.addEventListener('DOMContentLoaded', () => {
         try {
             const response = await fetch('/api/chains');
             const chains = await response.json();
+            // Don't re-render while a parameter is being edited
+            if (chainsContainer.contains(document.activeElement) && document.activeElement.tagName === 'INPUT') {
+                return;
+            }
             if (JSON.stringify(currentChains) !== JSON.stringify(chains)) {
                 currentChains = chains;
                 renderChains(currentChains);
@@ -139,6 +210,73 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     };
 
+    const tapBPM = async () => {
+        try {
+            const response = await fetch('/api/tap', { method: 'POST' });
+            const data = await response.json();
+            currentBPM = data.bpm;
+            bpmValueSpan.textContent = currentBPM.toFixed(2);
+        } catch (error) {
+            console.error('Error tapping BPM:', error);
+        }
+    };
+
+    const resyncBeat = async () => {
+        try {
+            await fetch('/api/resync', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ align: 'bar' }),
+            });
+        } catch (error) {
+            console.error('Error resyncing beat:', error);
+        }
+    };
+
+    const renderMaster = (data) => {
+        // Don't fight the user while the fader is being dragged
+        if (document.activeElement !== masterIntensityInput) {
+            masterIntensityInput.value = data.intensity;
+        }
+        masterValueSpan.textContent = data.intensity;
+        currentBlackout = data.blackout;
+        blackoutButton.classList.toggle('active', currentBlackout);
+    };
+
+    const fetchMaster = async () => {
+        try {
+            const response = await fetch('/api/master');
+            renderMaster(await response.json());
+        } catch (error) {
+            console.error('Error fetching master:', error);
+        }
+    };
+
+    const updateMaster = async (update) => {
+        try {
+            const response = await fetch('/api/master', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify(update),
+            });
+            renderMaster(await response.json());
+        } catch (error) {
+            console.error('Error updating master:', error);
+        }
+    };
+
+    masterIntensityInput.addEventListener('input', () => {
+        updateMaster({ intensity: parseInt(masterIntensityInput.value, 10) });
+    });
+
+    blackoutButton.addEventListener('click', () => {
+        updateMaster({ blackout: !currentBlackout });
+    });
+
     bpmDownButton.addEventListener('click', () => {
         updateBPM(currentBPM - 5);
     });
@@ -147,11 +285,76 @@ document.addEventListener('DOMContentLoaded', () => {
         updateBPM(currentBPM + 5);
     });
 
+    // Tap on mousedown, a click only fires on release and would lag behind the beat
+    bpmTapButton.addEventListener('mousedown', () => {
+        tapBPM();
+    });
+
+    beatResyncButton.addEventListener('mousedown', () => {
+        resyncBeat();
+    });
+
+    // One row per cue list with its current cue and GO/BACK/Stop buttons
+    const renderCues = (cueLists) => {
+        if (JSON.stringify(currentCues) === JSON.stringify(cueLists)) {
+            return;
+        }
+        currentCues = cueLists;
+        cuesContainer.innerHTML = '';
+        cueLists.forEach(list => {
+            const row = document.createElement('div');
+            row.className = 'bpm-control';
+            const cueName = list.current >= 0 ? (list.cues[list.current] || '') : '';
+            row.innerHTML = `
+                <label>${list.name}:</label>
+                <span>${list.current >= 0 ? `${list.current + 1}/${list.cues.length} ${cueName}` : '-'}</span>
+            `;
+            ['back', 'go', 'stop'].forEach(command => {
+                const button = document.createElement('button');
+                button.textContent = command.toUpperCase();
+                button.addEventListener('click', () => sendCueCommand(list.name, command));
+                row.appendChild(button);
+            });
+            cuesContainer.appendChild(row);
+        });
+    };
+
+    const fetchCues = async () => {
+        try {
+            const response = await fetch('/api/cues');
+            renderCues(await response.json());
+        } catch (error) {
+            console.error('Error fetching cue lists:', error);
+        }
+    };
+
+    const sendCueCommand = async (cueList, command) => {
+        try {
+            const response = await fetch('/api/cues', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ cue_list: cueList, command: command }),
+            });
+            if (!response.ok) {
+                console.error(`Cue ${command} on '${cueList}' failed:`, await response.text());
+                return;
+            }
+            renderCues(await response.json());
+            fetchChains();
+        } catch (error) {
+            console.error(`Error sending cue ${command}:`, error);
+        }
+    };
+
     // New: Function to refresh all data
     const refreshAll = () => {
         fetchBPM();
+        fetchMaster();
         fetchChains();
         fetchEventsAndRenderButtons();
+        fetchCues();
     };
 
     // Initial fetch and poll every second